@@ -0,0 +1,182 @@
+// Package analyzer provides the import rules of baobab check as an
+// Analyzer, for go vet, golangci-lint and other drivers of
+// golang.org/x/tools/go/analysis. It checks one package at a time, so import
+// cycles, which the go tool rejects anyway, are left to baobab check.
+//
+// Flags of the Analyzer are those of baobab check, without the leading
+// dash: forbid-blank, allow-blank, forbid-dot, allow-dot, boundary and
+// check-canonical, plus include-tests. Directories are relative to the root
+// of the module, as baobab names packages.
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"strconv"
+	"strings"
+
+	"github.com/sequix/baobab/internal/rules"
+	"golang.org/x/tools/go/analysis"
+)
+
+// Analyzer reports imports breaking the rules its flags enable. Diagnostics
+// have the rule broken as their category.
+var Analyzer = &analysis.Analyzer{
+	Name:      "baobab",
+	Doc:       "report imports breaking the rules of baobab check",
+	URL:       "https://github.com/sequix/baobab#checks",
+	Run:       run,
+	FactTypes: []analysis.Fact{new(canonicalFact)},
+}
+
+// listFlag is a repeatable flag, also taking comma-separated lists.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(s string) error {
+	*l = append(*l, strings.Split(s, ",")...)
+	return nil
+}
+
+var (
+	forbidBlank    bool
+	allowBlank     listFlag
+	forbidDot      bool
+	allowDot       listFlag
+	boundary       listFlag
+	checkCanonical bool
+	includeTests   bool
+)
+
+func init() {
+	fs := &Analyzer.Flags
+	fs.BoolVar(&forbidBlank, "forbid-blank", false, "forbid blank imports not allowed by -allow-blank")
+	fs.Var(&allowBlank, "allow-blank", "`DIR_GLOB[:IMPORT_GLOB]` of packages allowed to blank import, repeatable")
+	fs.BoolVar(&forbidDot, "forbid-dot", false, "forbid dot imports not allowed by -allow-dot")
+	fs.Var(&allowDot, "allow-dot", "`DIR_GLOB[:IMPORT_GLOB]` of packages allowed to dot import, repeatable")
+	fs.Var(&boundary, "boundary", "`GLOB` of directories whose packages are only importable from below their parent, repeatable")
+	fs.BoolVar(&checkCanonical, "check-canonical", false, "forbid importing packages by another path than the one in their import comment")
+	fs.BoolVar(&includeTests, "include-tests", false, "also check the imports of _test.go files")
+}
+
+// canonicalFact is the path in the import comment of a package.
+type canonicalFact struct {
+	Path string
+}
+
+func (*canonicalFact) AFact() {}
+
+func (f *canonicalFact) String() string {
+	return "canonical " + f.Path
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	if strings.HasSuffix(pass.Pkg.Path(), ".test") {
+		// The main package go test generates.
+		return nil, nil
+	}
+	if checkCanonical {
+		if path := importComment(pass); path != "" {
+			pass.ExportPackageFact(&canonicalFact{path})
+		}
+	}
+	var module string
+	if pass.Module != nil {
+		module = pass.Module.Path
+	}
+	dir := moduleDir(module, strings.TrimSuffix(pass.Pkg.Path(), "_test"))
+	imported := map[string]*canonicalFact{}
+	for _, pkg := range pass.Pkg.Imports() {
+		fact := new(canonicalFact)
+		if pass.ImportPackageFact(pkg, fact) {
+			imported[pkg.Path()] = fact
+		}
+	}
+	for _, file := range pass.Files {
+		if !includeTests && strings.HasSuffix(pass.Fset.File(file.Pos()).Name(), "_test.go") {
+			continue
+		}
+		for _, spec := range file.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || cgoUnsafe(pass, spec) {
+				continue
+			}
+			var name string
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			switch {
+			case forbidBlank && name == "_" && !rules.ImportAllowed(allowBlank, dir, path):
+				report(pass, spec, "blank-import", "blank import of %s in %s", path, dir)
+			case forbidDot && name == "." && !rules.ImportAllowed(allowDot, dir, path):
+				report(pass, spec, "dot-import", "dot import of %s in %s", path, dir)
+			}
+			if to := moduleDir(module, path); to != path {
+				if parent, ok := rules.BoundaryParent(boundary, to); ok && !rules.Within(dir, parent) {
+					report(pass, spec, "boundary", "%s imports %s from outside %s", dir, to, parent)
+				}
+			}
+			if fact := imported[path]; fact != nil && fact.Path != path {
+				report(pass, spec, "canonical", "%s imports %s, its canonical path is %s", dir, path, fact.Path)
+			}
+		}
+	}
+	return nil, nil
+}
+
+func report(pass *analysis.Pass, spec *ast.ImportSpec, rule, format string, args ...any) {
+	pass.Report(analysis.Diagnostic{
+		Pos:      spec.Pos(),
+		End:      spec.End(),
+		Category: rule,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// cgoUnsafe reports whether spec is the blank import of unsafe cgo adds to
+// the files it processes, which drivers parse from its output. A blank
+// import of unsafe of your own in such a file is left out too.
+func cgoUnsafe(pass *analysis.Pass, spec *ast.ImportSpec) bool {
+	return spec.Name != nil && spec.Name.Name == "_" && spec.Path.Value == `"unsafe"` &&
+		pass.Fset.File(spec.Pos()).Name() != pass.Fset.Position(spec.Pos()).Filename
+}
+
+// moduleDir returns the directory of the package path in module, path
+// itself if outside it.
+func moduleDir(module, path string) string {
+	switch {
+	case module == "":
+		return path
+	case path == module:
+		return "."
+	case strings.HasPrefix(path, module+"/"):
+		return path[len(module)+1:]
+	}
+	return path
+}
+
+// importComment returns the path in the import comment of the package, as
+// in package foo // import "example.com/foo", empty if none.
+func importComment(pass *analysis.Pass) string {
+	for _, file := range pass.Files {
+		line := pass.Fset.Position(file.Name.End()).Line
+		for _, group := range file.Comments {
+			for _, c := range group.List {
+				if c.Pos() < file.Name.End() || pass.Fset.Position(c.Pos()).Line != line {
+					continue
+				}
+				text := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(c.Text, "//"), "/*"))
+				text = strings.TrimSpace(strings.TrimSuffix(text, "*/"))
+				if rest, ok := strings.CutPrefix(text, "import "); ok {
+					if path, err := strconv.Unquote(strings.TrimSpace(rest)); err == nil {
+						return path
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
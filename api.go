@@ -0,0 +1,201 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var flagMinExported int
+
+// apiFlags defines the flags of the api command.
+func apiFlags(fs *flag.FlagSet) {
+	fs.IntVar(&flagMinExported, "min-exported", 10, "only flag the packages exporting at least `N` names")
+}
+
+// apiSurface is the exported API of a package and how much of it the other
+// packages scanned use.
+type apiSurface struct {
+	pkg      string
+	name     string          // package name
+	exported map[string]byte // exported package-level names, by kind: f, t, v or c
+	used     map[string]bool // of which other packages use
+}
+
+func (a *apiSurface) count(kind byte) int {
+	n := 0
+	for _, k := range a.exported {
+		if k == kind {
+			n++
+		}
+	}
+	return n
+}
+
+func (a *apiSurface) unused() int {
+	return len(a.exported) - len(a.used)
+}
+
+func runAPI(fs *flag.FlagSet, args []string) error {
+	source, err := sourceArg(args)
+	if err != nil {
+		return err
+	}
+	p, err := newPainter()
+	if err != nil {
+		return err
+	}
+	if flagGoList != "" {
+		return fmt.Errorf("cannot read the files of go list output")
+	}
+	recordFiles = true
+	cleanup, err := prepare(fs, source)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if err := scanAll(); err != nil {
+		return err
+	}
+	surfaces, err := readAPISurfaces(graph)
+	if err != nil {
+		return err
+	}
+	var rows []*apiSurface
+	for _, a := range surfaces {
+		if a.name != "main" && len(a.exported) > 0 {
+			rows = append(rows, a)
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].unused() != rows[j].unused() {
+			return rows[i].unused() > rows[j].unused()
+		}
+		return rows[i].pkg < rows[j].pkg
+	})
+	return writeOutput(func(w io.Writer) error { return printAPISurfaces(w, p, graph, rows) })
+}
+
+// readAPISurfaces parses the files of the packages scanned, tests and
+// skipped files aside, for the names each package of g exports and those
+// of them the others use, as pkg.Name.
+func readAPISurfaces(g *Graph) (map[string]*apiSurface, error) {
+	type parsed struct {
+		dir  string
+		file *ast.File
+	}
+	var (
+		files  []parsed
+		result = map[string]*apiSurface{}
+		fset   = token.NewFileSet()
+	)
+	for _, file := range scanner.Files() {
+		if file.Skipped != "" || strings.HasSuffix(file.Path, "_test.go") || g.Node(file.Dir) == nil {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file.Path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %s", file.Path, err)
+		}
+		files = append(files, parsed{file.Dir, f})
+		a := result[file.Dir]
+		if a == nil {
+			a = &apiSurface{pkg: file.Dir, name: f.Name.Name, exported: map[string]byte{}, used: map[string]bool{}}
+			result[file.Dir] = a
+		}
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv == nil && decl.Name.IsExported() {
+					a.exported[decl.Name.Name] = 'f'
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						if spec.Name.IsExported() {
+							a.exported[spec.Name.Name] = 't'
+						}
+					case *ast.ValueSpec:
+						kind := byte('v')
+						if decl.Tok == token.CONST {
+							kind = 'c'
+						}
+						for _, name := range spec.Names {
+							if name.IsExported() {
+								a.exported[name.Name] = kind
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	byPath := map[string]string{}
+	for _, name := range g.Nodes() {
+		if n := g.Node(name); n.ImportPath != "" {
+			byPath[n.ImportPath] = name
+		}
+	}
+	for _, pf := range files {
+		// Local name of each import of a package scanned.
+		local := map[string]*apiSurface{}
+		for _, imp := range pf.file.Imports {
+			path, err := strconv.Unquote(imp.Path.Value)
+			if err != nil {
+				continue
+			}
+			a := result[byPath[path]]
+			if a == nil || a.pkg == pf.dir {
+				continue
+			}
+			name := a.name
+			if imp.Name != nil {
+				name = imp.Name.Name
+			}
+			local[name] = a
+		}
+		ast.Inspect(pf.file, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if x, ok := sel.X.(*ast.Ident); ok && local[x.Name] != nil {
+					if a := local[x.Name]; a.exported[sel.Sel.Name] != 0 {
+						a.used[sel.Sel.Name] = true
+					}
+				}
+			}
+			return true
+		})
+	}
+	return result, nil
+}
+
+// printAPISurfaces writes the exported names of each package by kind, its
+// importers and how many of its names they use, flagging the packages of
+// -min-exported names or more exporting more than they use.
+func printAPISurfaces(w io.Writer, p painter, g *Graph, rows []*apiSurface) error {
+	table := [][]cell{{{"EXPORTED", bold}, {"FUNCS", bold}, {"TYPES", bold}, {"VARS", bold}, {"CONSTS", bold}, {"IMPORTERS", bold}, {"USED", bold}, {"PACKAGE", bold}}}
+	for _, a := range rows {
+		row := []cell{
+			{fmt.Sprint(len(a.exported)), plain},
+			{fmt.Sprint(a.count('f')), dim},
+			{fmt.Sprint(a.count('t')), dim},
+			{fmt.Sprint(a.count('v')), dim},
+			{fmt.Sprint(a.count('c')), dim},
+			{fmt.Sprint(len(g.Pred(a.pkg))), plain},
+			{fmt.Sprint(len(a.used)), plain},
+			{a.pkg, cyan},
+		}
+		if len(a.exported) >= flagMinExported && a.unused() > len(a.used) {
+			row[7].style = red
+			row = append(row, cell{"oversized", red})
+		}
+		table = append(table, row)
+	}
+	return p.printTable(w, table)
+}
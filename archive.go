@@ -0,0 +1,130 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isArchive reports whether file is named like an archive extractArchive
+// knows.
+func isArchive(file string) bool {
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(file, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractArchive extracts a zip, tar or gzipped tar archive of go source
+// into a new temporary directory and returns it, or the single directory the
+// archive holds, as in the archives GitHub makes of a tag.
+func extractArchive(file string) (string, error) {
+	dir, err := ioutil.TempDir("", "baobab-")
+	if err != nil {
+		return "", err
+	}
+	if strings.HasSuffix(file, ".zip") {
+		err = extractZip(file, dir)
+	} else {
+		err = extractTar(file, dir)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("failed to extract %s: %s", file, err)
+	}
+	fis, err := ioutil.ReadDir(dir)
+	if err == nil && len(fis) == 1 && fis[0].IsDir() {
+		// Moving the single directory up keeps the whole extraction under
+		// the directory the caller removes.
+		only := filepath.Join(dir, fis[0].Name())
+		tmp := dir + ".tmp"
+		if os.Rename(only, tmp) == nil && os.Remove(dir) == nil && os.Rename(tmp, dir) == nil {
+			return dir, nil
+		}
+	}
+	return dir, nil
+}
+
+func extractZip(file, dir string) error {
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = writeExtracted(dir, f.Name, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractTar(file, dir string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if !strings.HasSuffix(file, ".tar") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			// Directories are made as needed, links are not followed.
+			continue
+		}
+		if err := writeExtracted(dir, hdr.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// writeExtracted writes the archive member name, read from r, under dir.
+func writeExtracted(dir, name string, r io.Reader) error {
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if !strings.HasPrefix(path, dir+string(os.PathSeparator)) {
+		return fmt.Errorf("%s is outside of the archive", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
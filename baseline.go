@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// baseline is the violations -baseline records, which check then accepts.
+type baseline struct {
+	Violations []baselineEntry `json:"violations"`
+}
+
+// baselineEntry is a recorded violation, known by its rule and packages
+// alone so that moving imports around a file keeps it recorded. Message is
+// there for people reading the file.
+type baselineEntry struct {
+	Rule     string   `json:"rule"`
+	Packages []string `json:"packages"`
+	Message  string   `json:"message,omitempty"`
+}
+
+// key returns what tells e from the other recorded violations.
+func (e baselineEntry) key() string {
+	pkgs := e.Packages
+	if e.Rule == "cycle" {
+		// A cycle is the same whatever package it is printed from.
+		pkgs = append([]string(nil), pkgs...)
+		sort.Strings(pkgs)
+	}
+	return e.Rule + " " + strings.Join(pkgs, " ")
+}
+
+func newBaselineEntry(v Violation) baselineEntry {
+	e := baselineEntry{Rule: v.Rule, Message: v.Message}
+	for _, pkg := range v.Packages {
+		e.Packages = append(e.Packages, filepath.ToSlash(pkg))
+	}
+	return e
+}
+
+// readBaseline reads the baseline in file, empty if there is no such file
+// yet.
+func readBaseline(file string) (*baseline, error) {
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return &baseline{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %s", err)
+	}
+	var b baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %s", file, err)
+	}
+	return &b, nil
+}
+
+// writeBaseline records violations in file, sorted so that the file only
+// changes with them.
+func writeBaseline(file string, violations []Violation) error {
+	b := baseline{Violations: []baselineEntry{}}
+	for _, v := range violations {
+		b.Violations = append(b.Violations, newBaselineEntry(v))
+	}
+	sort.SliceStable(b.Violations, func(i, j int) bool {
+		return b.Violations[i].key() < b.Violations[j].key()
+	})
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(b); err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write baseline: %s", err)
+	}
+	return nil
+}
+
+// newViolations returns the violations b does not record, each recorded
+// violation accepting as many as it is recorded times, and how many
+// recorded ones are fixed.
+func (b *baseline) newViolations(violations []Violation) (result []Violation, fixed int) {
+	known := map[string]int{}
+	for _, e := range b.Violations {
+		known[e.key()]++
+	}
+	for _, v := range violations {
+		key := newBaselineEntry(v).key()
+		if known[key] > 0 {
+			known[key]--
+			continue
+		}
+		result = append(result, v)
+	}
+	for _, n := range known {
+		fixed += n
+	}
+	return result, fixed
+}
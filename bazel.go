@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sequix/baobab/internal/rules"
+)
+
+// writeBazel writes the imports of each package of g as Bazel labels in a
+// Starlark file, named as Gazelle names targets and repositories: GO_DEPS
+// maps the label of each package to the labels of its deps, GO_TEST_DEPS to
+// those only its tests import. The standard library is left out, and so are
+// imports of external packages unless -include-external made module nodes
+// for them.
+func writeBazel(w io.Writer, g *Graph) error {
+	deps := map[string]map[string]bool{}
+	testDeps := map[string]map[string]bool{}
+	for _, name := range g.Nodes() {
+		n := g.Node(name)
+		if n.External || n.Std || n.Marker || n.Proto {
+			continue
+		}
+		label := bazelLabel(n)
+		deps[label] = map[string]bool{}
+		for _, imp := range n.Imports {
+			dep := bazelDep(g, name, imp)
+			if dep == "" || dep == label {
+				continue
+			}
+			if imp.Test {
+				if testDeps[label] == nil {
+					testDeps[label] = map[string]bool{}
+				}
+				testDeps[label][dep] = true
+			} else {
+				deps[label][dep] = true
+			}
+		}
+	}
+	for label, tests := range testDeps {
+		for dep := range tests {
+			if deps[label][dep] {
+				delete(tests, dep)
+			}
+		}
+		if len(tests) == 0 {
+			delete(testDeps, label)
+		}
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# Code generated by baobab. DO NOT EDIT.")
+	writeStarlarkDict(bw, "GO_DEPS", deps)
+	writeStarlarkDict(bw, "GO_TEST_DEPS", testDeps)
+	return bw.Flush()
+}
+
+func writeStarlarkDict(w io.Writer, name string, dict map[string]map[string]bool) {
+	if len(dict) == 0 {
+		fmt.Fprintf(w, "\n%s = {}\n", name)
+		return
+	}
+	fmt.Fprintf(w, "\n%s = {\n", name)
+	for _, key := range sortedSet(dict) {
+		fmt.Fprintf(w, "    %q: [", key)
+		values := make([]string, 0, len(dict[key]))
+		for v := range dict[key] {
+			values = append(values, v)
+		}
+		if len(values) == 0 {
+			fmt.Fprintln(w, "],")
+			continue
+		}
+		sort.Strings(values)
+		fmt.Fprintln(w)
+		for _, v := range values {
+			fmt.Fprintf(w, "        %q,\n", v)
+		}
+		fmt.Fprintln(w, "    ],")
+	}
+	fmt.Fprintln(w, "}")
+}
+
+func sortedSet(m map[string]map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// bazelDep returns the label of the package imp imports, empty if not in the
+// graph or in the standard library.
+func bazelDep(g *Graph, from string, imp Import) string {
+	if imp.Dir != "" {
+		if to := g.Node(imp.Dir); to != nil && g.Edge(from, imp.Dir) != nil {
+			return bazelLabel(to)
+		}
+		return ""
+	}
+	for _, to := range g.Succ(from) {
+		n := g.Node(to)
+		if !n.External || !rules.Within(imp.Path, nodePath(to)) {
+			continue
+		}
+		pkg := strings.TrimPrefix(strings.TrimPrefix(imp.Path, nodePath(to)), "/")
+		return fmt.Sprintf("@%s//%s", bazelRepo(nodePath(to)), bazelPackage(pkg, imp.Path))
+	}
+	return ""
+}
+
+// bazelLabel returns the label of the go_library Gazelle generates for the
+// package n, in its directory and named after it.
+func bazelLabel(n *Node) string {
+	dir := filepath.ToSlash(n.Name)
+	if dir == "." {
+		dir = ""
+	}
+	importPath := n.ImportPath
+	if importPath == "" {
+		importPath = dir
+	}
+	return "//" + bazelPackage(dir, importPath)
+}
+
+// bazelPackage returns the label of the go_library of importPath in dir of
+// its repository, without the repository: dir alone when the target is
+// named after it. Targets are named after the last element of the import
+// path, but for a major version suffix like v2.
+func bazelPackage(dir, importPath string) string {
+	name := path.Base(importPath)
+	if parent := path.Dir(importPath); parent != "." && isMajorVersion(name) {
+		name = path.Base(parent)
+	}
+	if dir == "" {
+		return ":" + name
+	}
+	if path.Base(dir) == name {
+		return dir
+	}
+	return dir + ":" + name
+}
+
+// isMajorVersion reports whether elem is a major version suffix of a module
+// path, like v2.
+func isMajorVersion(elem string) bool {
+	if len(elem) < 2 || elem[0] != 'v' {
+		return false
+	}
+	for _, r := range elem[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// bazelRepo returns the name Gazelle gives the repository of module, its
+// host reversed then its path, as in com_github_sequix_baobab.
+func bazelRepo(module string) string {
+	elems := strings.Split(strings.ToLower(module), "/")
+	host := strings.Split(elems[0], ".")
+	for i, j := 0, len(host)-1; i < j; i, j = i+1, j-1 {
+		host[i], host[j] = host[j], host[i]
+	}
+	name := strings.Join(append(host, elems[1:]...), "_")
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	graphs "github.com/sequix/baobab/graph"
+)
+
+var (
+	flagMain   string
+	flagBinary string
+	flagTop    int
+)
+
+// binSizeFlags defines the flags of the binsize command.
+func binSizeFlags(fs *flag.FlagSet) {
+	fs.StringVar(&flagMain, "main", ".", "main `PACKAGE` to build and weigh, by directory or import path")
+	fs.StringVar(&flagBinary, "binary", "", "weigh the executable `FILE`, built from -main, instead of building it")
+	fs.IntVar(&flagTop, "top", 20, "list the `N` heaviest packages and imports, 0 for all")
+}
+
+// importCost is what an import adds to a binary: the packages it alone
+// brings in.
+type importCost struct {
+	edge     *Edge
+	packages int
+	size     int64
+}
+
+func runBinSize(fs *flag.FlagSet, args []string) error {
+	source, err := sourceArg(args)
+	if err != nil {
+		return err
+	}
+	p, err := newPainter()
+	if err != nil {
+		return err
+	}
+	if flagGoList != "" {
+		return fmt.Errorf("cannot build go list output")
+	}
+	if flagBinary != "" {
+		// Relative to where baobab runs, not the root it changes to.
+		if flagBinary, err = filepath.Abs(flagBinary); err != nil {
+			return err
+		}
+	}
+	cleanup, err := prepare(fs, source)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if err := scanAll(); err != nil {
+		return err
+	}
+	entry := scanner.Resolve(flagMain)
+	if graph.Node(entry) == nil {
+		return fmt.Errorf("no package %s in the graph", flagMain)
+	}
+	binary := flagBinary
+	if binary == "" {
+		dir, err := os.MkdirTemp("", "baobab-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		binary = filepath.Join(dir, "main")
+		if err := scanner.BuildBinary(context.Background(), entry, binary); err != nil {
+			return err
+		}
+	}
+	symbols, err := readSymbolSizes(binary)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(binary)
+	if err != nil {
+		return err
+	}
+	sizes, unattributed := packageSizes(graph, entry, symbols)
+	return writeOutput(func(w io.Writer) error {
+		return printBinSizes(w, p, info.Size(), unattributed, sizes, importCosts(graph, entry, sizes))
+	})
+}
+
+// readSymbolSizes returns the size of the code and data of the symbols of
+// the executable file by package, as go tool nm lists them.
+func readSymbolSizes(file string) (map[string]int64, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("go", "tool", "nm", "-size", file)
+	logger.Debug("listing symbols", "cmd", cmd.Args)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to list the symbols of %s: %s: %s", file, err, strings.TrimSpace(stderr.String()))
+	}
+	result := map[string]int64{}
+	sc := bufio.NewScanner(&stdout)
+	for sc.Scan() {
+		// address size type name, the address missing for undefined ones.
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[2] {
+		case "T", "t", "R", "r", "D", "d":
+			result[symbolPackage(strings.Join(fields[3:], " "))] += size
+		}
+	}
+	return result, sc.Err()
+}
+
+// symbolPackage returns the import path of the package of the symbol name,
+// like github.com/acme/app/pkg.(*T).Method, empty if none.
+func symbolPackage(name string) string {
+	for _, prefix := range []string{"go:itab.", "type:.eq.", "type:.hash.", "type:", "go:"} {
+		if strings.HasPrefix(name, prefix) {
+			name = strings.TrimPrefix(name, prefix)
+			break
+		}
+	}
+	name = strings.TrimLeft(name, "*")
+	if end := strings.IndexAny(name, "[(,"); end >= 0 {
+		name = name[:end]
+	}
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return ""
+	}
+	// The last element of import paths has its dots escaped.
+	return strings.ReplaceAll(name[:slash+1+dot], "%2e", ".")
+}
+
+// packageSizes returns the size each package of g adds to the binary of
+// entry, per the sizes of the symbols by package, and the size of those not
+// in g. External and standard packages weigh the packages below them, as
+// they stand for whole modules or trees.
+func packageSizes(g *Graph, entry string, symbols map[string]int64) (map[string]int64, int64) {
+	nodeOf := packageNodes(g)
+	result := map[string]int64{}
+	var unattributed int64
+	for pkg, size := range symbols {
+		name := nodeOf(pkg)
+		if pkg == "main" {
+			name = entry
+		}
+		if name == "" {
+			unattributed += size
+			continue
+		}
+		result[name] += size
+	}
+	return result, unattributed
+}
+
+// importCosts returns what each import of the packages entry imports costs:
+// the packages entry would no longer import without it, and their size, the
+// costliest first.
+func importCosts(g *Graph, entry string, sizes map[string]int64) []importCost {
+	all := func(*Node) bool { return true }
+	g = g.Filter(all, func(e *Edge) bool { return !e.Test })
+	var (
+		result []importCost
+		reach  = graphs.Reachable(g, []string{entry})
+	)
+	for _, e := range g.Edges() {
+		if !reach[e.From] {
+			continue
+		}
+		without := graphs.Reachable(g.Filter(all, func(other *Edge) bool { return other != e }), []string{entry})
+		c := importCost{edge: e}
+		for name := range reach {
+			if !without[name] {
+				c.packages++
+				c.size += sizes[name]
+			}
+		}
+		if c.size > 0 {
+			result = append(result, c)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].size > result[j].size })
+	return result
+}
+
+// printBinSizes writes the size of the binary, the -top heaviest packages in
+// it and the -top imports bringing in the most.
+func printBinSizes(w io.Writer, p painter, total, unattributed int64, sizes map[string]int64, costs []importCost) error {
+	names := make([]string, 0, len(sizes))
+	for name := range sizes {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if sizes[names[i]] != sizes[names[j]] {
+			return sizes[names[i]] > sizes[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if flagTop > 0 && len(names) > flagTop {
+		names = names[:flagTop]
+	}
+	if flagTop > 0 && len(costs) > flagTop {
+		costs = costs[:flagTop]
+	}
+	fmt.Fprintf(w, "binary: %s, symbols of no package in the graph: %s\n\n", formatBytes(total), formatBytes(unattributed))
+	table := [][]cell{{{"SIZE", bold}, {"SHARE", bold}, {"PACKAGE", bold}}}
+	for _, name := range names {
+		table = append(table, []cell{
+			{formatBytes(sizes[name]), plain},
+			{fmt.Sprintf("%.1f%%", 100*float64(sizes[name])/float64(total)), dim},
+			{name, cyan},
+		})
+	}
+	if err := p.printTable(w, table); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	table = [][]cell{{{"COST", bold}, {"PACKAGES", bold}, {"IMPORT", bold}}}
+	for _, c := range costs {
+		table = append(table, []cell{
+			{formatBytes(c.size), plain},
+			{fmt.Sprint(c.packages), plain},
+			{c.edge.From + " -> " + c.edge.To, cyan},
+		})
+	}
+	return p.printTable(w, table)
+}
+
+// formatBytes returns n bytes in the largest unit it makes at least one of.
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fKiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", n)
+}
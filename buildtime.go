@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	flagActionGraph string
+	flagBottlenecks int
+)
+
+// buildTimeFlags defines the flags of the buildtime command.
+func buildTimeFlags(fs *flag.FlagSet) {
+	fs.StringVar(&flagActionGraph, "actiongraph", "", "read the compile times from the action graph `FILE` go build -debug-actiongraph wrote instead of building every package from scratch")
+	fs.IntVar(&flagBottlenecks, "bottlenecks", 5, "highlight the `N` packages whose change costs the most compile time")
+}
+
+// buildCost is the compile time a package costs.
+type buildCost struct {
+	pkg       string
+	compile   time.Duration // compiling the package itself
+	importers int           // packages importing it, directly or not
+	rebuild   time.Duration // compiling it and its importers, which a change to it takes
+}
+
+func runBuildTime(fs *flag.FlagSet, args []string) error {
+	source, err := sourceArg(args)
+	if err != nil {
+		return err
+	}
+	p, err := newPainter()
+	if err != nil {
+		return err
+	}
+	if flagGoList != "" {
+		return fmt.Errorf("cannot build go list output")
+	}
+	cleanup, err := prepare(fs, source)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if err := scanAll(); err != nil {
+		return err
+	}
+	times, err := scanner.CompileTimes(context.Background(), flagActionGraph)
+	if err != nil {
+		return err
+	}
+	return writeOutput(func(w io.Writer) error { return printBuildCosts(w, p, buildCosts(graph, times)) })
+}
+
+// buildCosts returns the compile time each package of g costs, per the
+// times by import path, the costliest to change first. External and
+// standard packages cost the time of the packages below them, as they
+// stand for whole modules or trees.
+func buildCosts(g *Graph, times map[string]time.Duration) []buildCost {
+	compile := map[string]time.Duration{}
+	for _, name := range g.Nodes() {
+		n := g.Node(name)
+		if n.ImportPath != "" {
+			compile[name] = times[n.ImportPath]
+			continue
+		}
+		if n.External || n.Std {
+			for pkg, t := range times {
+				if path := nodePath(name); pkg == path || strings.HasPrefix(pkg, path+"/") {
+					compile[name] += t
+				}
+			}
+		}
+	}
+	var result []buildCost
+	for _, name := range g.Nodes() {
+		if n := g.Node(name); n.Marker || n.Proto || n.ImportPath == "" && !n.External && !n.Std {
+			continue
+		}
+		c := buildCost{pkg: name, compile: compile[name]}
+		c.rebuild = c.compile
+		for _, importer := range neighbors(name, g.Pred, true) {
+			c.importers++
+			c.rebuild += compile[importer]
+		}
+		result = append(result, c)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].rebuild != result[j].rebuild {
+			return result[i].rebuild > result[j].rebuild
+		}
+		return result[i].pkg < result[j].pkg
+	})
+	return result
+}
+
+// printBuildCosts writes the compile time of each package and of the
+// packages importing it, the -bottlenecks costliest marked.
+func printBuildCosts(w io.Writer, p painter, costs []buildCost) error {
+	table := [][]cell{{{"REBUILD", bold}, {"COMPILE", bold}, {"IMPORTERS", bold}, {"PACKAGE", bold}}}
+	for i, c := range costs {
+		row := []cell{
+			{c.rebuild.Round(time.Millisecond).String(), plain},
+			{c.compile.Round(time.Millisecond).String(), plain},
+			{fmt.Sprint(c.importers), plain},
+			{c.pkg, cyan},
+		}
+		if i < flagBottlenecks && c.rebuild > 0 {
+			row[3].style = red
+			row = append(row, cell{"bottleneck", red})
+		}
+		table = append(table, row)
+	}
+	return p.printTable(w, table)
+}
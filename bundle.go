@@ -0,0 +1,26 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"io"
+)
+
+//go:embed bundle.html
+var bundleHTML []byte
+
+// writeBundle writes g as a standalone HTML page drawing the packages on a
+// circle, in the order of their directories, and the imports as curves
+// bundled along the directory tree, which stays readable with thousands of
+// them.
+func writeBundle(w io.Writer, g *Graph) error {
+	// json.Marshal escapes <, > and &, so the document cannot end the
+	// script it is in.
+	doc, err := json.Marshal(newJSONGraph(g))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(themeHTML(bytes.Replace(bundleHTML, []byte("/*GRAPH*/null"), doc, 1)))
+	return err
+}
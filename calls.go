@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	graphs "github.com/sequix/baobab/graph"
+)
+
+var (
+	flagCallFrom    string
+	flagDynamic     bool
+	flagSamePackage bool
+)
+
+// callFlags defines the flags of the calls command.
+func callFlags(fs *flag.FlagSet) {
+	fs.StringVar(&flagCallFrom, "from", "", "only keep the calls made, directly or not, from `FUNC`, like cmd/app.main or pkg/log.(*Logger).Print")
+	fs.BoolVar(&flagDynamic, "dynamic", false, "follow calls through interfaces and function values too, to every function of a matching type")
+}
+
+// samePackageFlags defines the flags of the commands graphing functions or
+// types.
+func samePackageFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&flagSamePackage, "same-package", false, "keep the edges between functions or types of the same package")
+}
+
+func runCalls(fs *flag.FlagSet, args []string) error {
+	source, err := sourceArg(args)
+	if err != nil {
+		return err
+	}
+	if err := typeGraphFormat("calls"); err != nil {
+		return err
+	}
+	if flagGoList != "" {
+		return fmt.Errorf("cannot build a call graph from go list output")
+	}
+	write, err := graphWriter()
+	if err != nil {
+		return err
+	}
+	cleanup, err := prepare(fs, source)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	g, err := scanner.CallGraph(context.Background(), flagDynamic)
+	if err != nil {
+		return err
+	}
+	if flagCallFrom != "" {
+		entry := funcNode(g, flagCallFrom)
+		if entry == "" {
+			return fmt.Errorf("no function %s in the call graph", flagCallFrom)
+		}
+		reached := graphs.Reachable(g, []string{entry})
+		g = g.Filter(func(n *Node) bool { return reached[n.Name] }, func(*Edge) bool { return true })
+	}
+	if !flagSamePackage {
+		g = crossPackage(g)
+	}
+	return writeOutput(func(w io.Writer) error { return write(w, g) })
+}
+
+// crossPackage returns g, a graph of functions or types, without the edges
+// between those of the same package, nor those left without edges.
+func crossPackage(g *Graph) *Graph {
+	edges := g.Filter(func(*Node) bool { return true }, func(e *Edge) bool { return g.Node(e.From).Group != g.Node(e.To).Group })
+	return edges.Filter(func(n *Node) bool { return len(edges.Succ(n.Name)) > 0 || len(edges.Pred(n.Name)) > 0 }, func(*Edge) bool { return true })
+}
+
+// typeGraphFormat checks -format for the commands graphing functions or
+// types, which only write DOT and JSON.
+func typeGraphFormat(command string) error {
+	if flagFormat != "dot" && flagFormat != "json" {
+		return fmt.Errorf("%s writes dot or json, not %q", command, flagFormat)
+	}
+	return nil
+}
+
+// funcNode returns the node of the call graph g the function arg names, by
+// the directory or import path of its package and its name, empty if none.
+func funcNode(g *Graph, arg string) string {
+	if g.Node(arg) != nil {
+		return arg
+	}
+	// The package ends at a dot after its last slash, not necessarily the
+	// first, as in gopkg.in/yaml.v3.Marshal.
+	slash := strings.LastIndex(arg, "/")
+	for i := slash + 1; i < len(arg); i++ {
+		if arg[i] != '.' {
+			continue
+		}
+		pkg, fn := arg[:i], arg[i+1:]
+		for _, dir := range []string{scanner.Resolve(pkg), pkg} {
+			name := dir + "." + fn
+			if dir == "." {
+				// Functions of the root package go by their name alone.
+				name = fn
+			}
+			if g.Node(name) != nil {
+				return name
+			}
+		}
+	}
+	return ""
+}
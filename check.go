@@ -0,0 +1,446 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	graphs "github.com/sequix/baobab/graph"
+	"github.com/sequix/baobab/internal/rules"
+)
+
+// Violation is an import breaking one of the rules enabled for -check.
+type Violation struct {
+	Rule    string // name of the broken rule
+	File    string // file of the offending import, if known
+	Line    int    // line of the offending import in File, 0 if unknown
+	Message string
+	Warning bool // reported, but never failing the check
+
+	// Packages are the packages involved, the importing one first.
+	Packages []string
+}
+
+func (v Violation) String() string {
+	if v.File == "" {
+		return fmt.Sprintf("%s [%s]", v.Message, v.tag())
+	}
+	return fmt.Sprintf("%s: %s [%s]", v.File, v.Message, v.tag())
+}
+
+// tag returns the rule of v, and whether it is a warning.
+func (v Violation) tag() string {
+	if v.Warning {
+		return v.Rule + ", warning"
+	}
+	return v.Rule
+}
+
+// fails reports whether v makes check fail.
+func (v Violation) fails() bool {
+	return !v.Warning && failsOn(v.Rule)
+}
+
+// checkFunc looks for violations of a rule in the graph.
+type checkFunc func(g *Graph) []Violation
+
+// enabledChecks returns the checks turned on by flags.
+func enabledChecks() []checkFunc {
+	var result []checkFunc
+	if flagForbidBlank {
+		result = append(result, checkBlankImports)
+	}
+	if flagForbidDot {
+		result = append(result, checkDotImports)
+	}
+	if flagCheckInternal {
+		result = append(result, checkInternal)
+	}
+	if flagCanonical {
+		result = append(result, checkCanonical)
+	}
+	if flagForbidCycles {
+		result = append(result, checkCycles)
+	}
+	if len(importRules) > 0 {
+		result = append(result, checkImportRules)
+	}
+	if flagForbidDeprecated {
+		result = append(result, checkDeprecated)
+	}
+	return result
+}
+
+// ruleGroups names sets of rules for -fail-on.
+var ruleGroups = map[string][]string{
+	"forbidden-imports": {"blank-import", "dot-import", "internal", "boundary", "canonical", "import-rule", "deprecated"},
+	"cycles":            {"cycle"},
+}
+
+// knownRule reports whether some check reports violations of rule.
+func knownRule(rule string) bool {
+	switch rule {
+	case "all", "blank-import", "dot-import", "internal", "boundary", "canonical", "cycle", "policy", "import-rule", "deprecated":
+		return true
+	}
+	return false
+}
+
+// failsOn reports whether a violation of rule makes check fail, per -fail-on.
+func failsOn(rule string) bool {
+	if len(flagFailOn) == 0 {
+		return true
+	}
+	for _, name := range flagFailOn {
+		if name == rule || name == "all" {
+			return true
+		}
+		for _, r := range ruleGroups[name] {
+			if r == rule {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// runChecks runs every check and returns the violations found.
+func runChecks(g *Graph, checks []checkFunc) []Violation {
+	var result []Violation
+	for _, check := range checks {
+		result = append(result, check(g)...)
+	}
+	return result
+}
+
+// printViolations writes violations to w, one per line, in columns of file,
+// message and rule for terminals.
+func printViolations(w io.Writer, p painter, violations []Violation) error {
+	if !p {
+		for _, v := range violations {
+			if _, err := fmt.Fprintln(w, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var rows [][]cell
+	for _, v := range violations {
+		file := v.File
+		if file != "" {
+			file += ":"
+		}
+		color := red
+		if v.Warning {
+			color = yellow
+		}
+		rows = append(rows, []cell{{file, dim}, {v.Message, plain}, {"[" + v.tag() + "]", color}})
+	}
+	return p.printTable(w, rows)
+}
+
+// printAnnotations writes violations as GitHub Actions workflow commands,
+// errors if they fail the check per -fail-on and warnings otherwise, which
+// GitHub shows on the lines of the pull request making the imports.
+func printAnnotations(w io.Writer, violations []Violation) error {
+	for _, v := range violations {
+		level := "warning"
+		if v.fails() {
+			level = "error"
+		}
+		props := []string{"title=" + escapeProperty("baobab "+v.Rule)}
+		if v.File != "" {
+			props = append(props, "file="+escapeProperty(workspacePath(v.File)))
+		}
+		if v.Line > 0 {
+			props = append(props, fmt.Sprintf("line=%d", v.Line))
+		}
+		if _, err := fmt.Fprintf(w, "::%s %s::%s\n", level, strings.Join(props, ","), escapeData(v.Message)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// workspacePath returns file, relative to the root of the scan, relative to
+// the GitHub Actions workspace instead if there is one, as annotations want,
+// for modules outside the root of their repository.
+func workspacePath(file string) string {
+	if ws := os.Getenv("GITHUB_WORKSPACE"); ws != "" {
+		if abs, err := filepath.Abs(file); err == nil {
+			if rel, err := filepath.Rel(ws, abs); err == nil && !strings.HasPrefix(rel, "..") {
+				file = rel
+			}
+		}
+	}
+	return filepath.ToSlash(file)
+}
+
+// escapeData escapes the message of a workflow command.
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a property value of a workflow command.
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// checkCycles reports import cycles, each strongly connected set of
+// packages once. Edges only test files make are left out, as the go tool
+// allows cycles through external test packages.
+func checkCycles(g *Graph) []Violation {
+	var result []Violation
+	for _, scc := range graphs.StronglyConnected(g, func(e *Edge) bool { return !e.Test }) {
+		if len(scc) < 2 {
+			continue
+		}
+		cycle := cycleThrough(g, scc)
+		file, line := importPos(g, g.Edge(cycle[0], cycle[1]))
+		result = append(result, Violation{
+			Rule:     "cycle",
+			File:     file,
+			Line:     line,
+			Message:  fmt.Sprintf("import cycle: %s", strings.Join(cycle, " -> ")),
+			Packages: scc,
+		})
+	}
+	return result
+}
+
+// cycleThrough returns a shortest cycle through the first node of scc, a
+// strongly connected component, ending with that node again.
+func cycleThrough(g *Graph, scc []string) []string {
+	in := map[string]bool{}
+	for _, n := range scc {
+		in[n] = true
+	}
+	start := scc[0]
+	prev := map[string]string{}
+	queue := []string{start}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, next := range g.Succ(n) {
+			if !in[next] || g.Edge(n, next).Test {
+				continue
+			}
+			if next == start {
+				cycle := []string{start}
+				for ; n != start; n = prev[n] {
+					cycle = append([]string{n}, cycle...)
+				}
+				return append([]string{start}, cycle...)
+			}
+			if _, seen := prev[next]; !seen {
+				prev[next] = n
+				queue = append(queue, next)
+			}
+		}
+	}
+	return scc
+}
+
+// checkBlankImports reports blank imports, as in _ "pkg", not allowed by
+// any -allow-blank pattern.
+func checkBlankImports(g *Graph) []Violation {
+	return checkNamedImports(g, "_", "blank-import", "blank import", flagAllowBlank)
+}
+
+// checkDotImports reports dot imports, as in . "pkg", not allowed by any
+// -allow-dot pattern.
+func checkDotImports(g *Graph) []Violation {
+	return checkNamedImports(g, ".", "dot-import", "dot import", flagAllowDot)
+}
+
+// checkNamedImports reports imports named name not allowed by any of allows.
+func checkNamedImports(g *Graph, name, rule, what string, allows []string) []Violation {
+	var result []Violation
+	for _, dir := range g.Nodes() {
+		for _, imp := range g.Node(dir).Imports {
+			if imp.Name != name || rules.ImportAllowed(allows, dir, imp.Path) {
+				continue
+			}
+			result = append(result, Violation{
+				Rule:     rule,
+				File:     imp.File,
+				Line:     imp.Line,
+				Message:  fmt.Sprintf("%s of %s in %s", what, imp.Path, dir),
+				Packages: importPackages(dir, imp),
+			})
+		}
+	}
+	return result
+}
+
+// checkInternal reports imports of internal packages from outside the tree
+// rooted at the parent of their internal directory, as the go tool forbids,
+// and likewise for packages behind -boundary directories.
+func checkInternal(g *Graph) []Violation {
+	var result []Violation
+	for _, e := range g.Edges() {
+		from, to := g.Node(e.From), g.Node(e.To)
+		if to.External || to.Std || to.Marker || to.Proto {
+			continue
+		}
+		var (
+			fromPath   = importPath(from)
+			toPath     = importPath(to)
+			file, line = importPos(g, e)
+		)
+		if parent, ok := rules.InternalParent(toPath); ok && !rules.Within(fromPath, parent) {
+			result = append(result, Violation{
+				Rule:     "internal",
+				File:     file,
+				Line:     line,
+				Message:  fmt.Sprintf("%s imports internal package %s", e.From, e.To),
+				Packages: []string{e.From, e.To},
+			})
+		} else if parent, ok := rules.BoundaryParent(flagBoundary, filepath.ToSlash(e.To)); ok && !rules.Within(filepath.ToSlash(e.From), parent) {
+			result = append(result, Violation{
+				Rule:     "boundary",
+				File:     file,
+				Line:     line,
+				Message:  fmt.Sprintf("%s imports %s from outside %s", e.From, e.To, parent),
+				Packages: []string{e.From, e.To},
+			})
+		}
+	}
+	return result
+}
+
+// checkCanonical reports imports of packages by another path than the one
+// in their import comment, as in package foo // import "canonical/path".
+func checkCanonical(g *Graph) []Violation {
+	var result []Violation
+	for _, dir := range g.Nodes() {
+		for _, imp := range g.Node(dir).Imports {
+			if imp.Dir == "" || g.Node(imp.Dir) == nil {
+				continue
+			}
+			canonical := g.Node(imp.Dir).Canonical
+			if canonical == "" || imp.Path == canonical {
+				continue
+			}
+			result = append(result, Violation{
+				Rule:     "canonical",
+				File:     imp.File,
+				Line:     imp.Line,
+				Message:  fmt.Sprintf("%s imports %s, its canonical path is %s", dir, imp.Path, canonical),
+				Packages: importPackages(dir, imp),
+			})
+		}
+	}
+	return result
+}
+
+// importPath returns the import path of the package n stands for, its
+// canonical one if it has an import comment.
+func importPath(n *Node) string {
+	if n.External || n.Std || n.Marker || n.Proto {
+		return nodePath(n.Name)
+	}
+	if n.Canonical != "" {
+		return n.Canonical
+	}
+	if n.ImportPath != "" {
+		return n.ImportPath
+	}
+	return filepath.ToSlash(n.Name)
+}
+
+// importPackages returns dir and the package imp imports, if in the scanned
+// modules.
+func importPackages(dir string, imp Import) []string {
+	if imp.Dir == "" {
+		return []string{dir}
+	}
+	return []string{dir, imp.Dir}
+}
+
+// importPos returns a file of e.From importing e.To and the line of the
+// import, empty if unknown.
+func importPos(g *Graph, e *Edge) (file string, line int) {
+	if imps := edgeImports(g, e); len(imps) > 0 {
+		return imps[0].File, imps[0].Line
+	}
+	return "", 0
+}
+
+// edgeImports returns the import declarations making e, one per file of
+// e.From importing e.To, none if unknown, as for the edges of collapsed
+// nodes.
+func edgeImports(g *Graph, e *Edge) []Import {
+	var result []Import
+	for _, imp := range g.Node(e.From).Imports {
+		if imp.Dir == e.To || imp.Node == e.To {
+			result = append(result, imp)
+		}
+	}
+	return result
+}
+
+// importPositions returns the file:line of each import making e.
+func importPositions(g *Graph, e *Edge) []string {
+	var result []string
+	for _, imp := range edgeImports(g, e) {
+		if imp.Line > 0 {
+			result = append(result, fmt.Sprintf("%s:%d", imp.File, imp.Line))
+		} else {
+			result = append(result, imp.File)
+		}
+	}
+	return result
+}
+
+// importRules are the rules of -rules, read by loadImportRules.
+var importRules []rules.Rule
+
+// loadImportRules reads the rules file of -rules, if any.
+func loadImportRules() error {
+	if flagRules == "" {
+		return nil
+	}
+	var err error
+	if importRules, err = rules.ParseFile(flagRules); err != nil {
+		return fmt.Errorf("failed to read rules: %s", err)
+	}
+	return nil
+}
+
+// checkImportRules reports the imports the first rule of -rules about them
+// denies, or warns against. Packages are known by their directory or import
+// path.
+func checkImportRules(g *Graph) []Violation {
+	var result []Violation
+	for _, e := range g.Edges() {
+		from, to := g.Node(e.From), g.Node(e.To)
+		if from.Marker || to.Marker {
+			continue
+		}
+		r := rules.First(importRules, filepath.ToSlash(e.From), filepath.ToSlash(e.To), importPath(to))
+		if r == nil || r.Verb == "allow" {
+			continue
+		}
+		why := r.Message
+		switch {
+		case why != "":
+		case r.Warning():
+			why = "warned against by " + r.Pos()
+		default:
+			why = "denied by " + r.Pos()
+		}
+		file, line := importPos(g, e)
+		result = append(result, Violation{
+			Rule:     "import-rule",
+			File:     file,
+			Line:     line,
+			Message:  fmt.Sprintf("%s imports %s: %s", e.From, e.To, why),
+			Warning:  r.Warning(),
+			Packages: []string{e.From, e.To},
+		})
+	}
+	return result
+}
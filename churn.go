@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var flagChurn string
+
+// applyChurn sets the number of commits since -churn changing the go files
+// of each package of g, from the history of the git repository of the
+// current directory, the root scanned.
+func applyChurn(g *Graph) error {
+	if flagChurn == "" {
+		return nil
+	}
+	// %x00 starts each commit, its files relative to here following.
+	out, err := git("log", "--since="+flagChurn, "--format=%x00", "--name-only", "--relative", "--", "*.go")
+	if err != nil {
+		return err
+	}
+	commits := map[string]int{}
+	for _, commit := range strings.Split(out, "\x00") {
+		dirs := map[string]bool{}
+		for _, file := range strings.Split(commit, "\n") {
+			if file = strings.TrimSpace(file); file != "" {
+				dirs[filepath.FromSlash(path.Dir(file))] = true
+			}
+		}
+		for dir := range dirs {
+			commits[dir]++
+		}
+	}
+	for _, name := range g.Nodes() {
+		if n := g.Node(name); !n.External && !n.Std && !n.Marker && !n.Proto {
+			n.Commits = commits[name]
+		}
+	}
+	return nil
+}
+
+// dotChurnAttrs returns the DOT attributes outlining the nodes of g changed
+// since -churn, redder and thicker the more commits changed them.
+func dotChurnAttrs(g *Graph) map[string]string {
+	top := 0
+	for _, name := range g.Nodes() {
+		top = max(top, g.Node(name).Commits)
+	}
+	if top == 0 {
+		return nil
+	}
+	result := map[string]string{}
+	for _, name := range g.Nodes() {
+		if c := g.Node(name).Commits; c > 0 {
+			share := math.Sqrt(float64(c) / float64(top))
+			result[name] = fmt.Sprintf("color=\"0.000 %.3f 0.900\", penwidth=%.1f, tooltip=\"commits: %d\"", share, 1+3*share, c)
+		}
+	}
+	return result
+}
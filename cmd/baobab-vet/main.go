@@ -0,0 +1,15 @@
+// Command baobab-vet checks the import rules of baobab check on packages,
+// alone or as a go vet tool:
+//
+//	baobab-vet -forbid-blank -allow-blank 'cmd/**' ./...
+//	go vet -vettool=$(which baobab-vet) -forbid-blank ./...
+package main
+
+import (
+	"github.com/sequix/baobab/analyzer"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(analyzer.Analyzer)
+}
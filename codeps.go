@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+)
+
+var (
+	flagMinShared     int
+	flagMinSimilarity float64
+)
+
+// codepsFlags defines the flags of the codeps command.
+func codepsFlags(fs *flag.FlagSet) {
+	fs.IntVar(&flagMinShared, "min-shared", 2, "only report the pairs of packages imported together by at least `N` packages")
+	fs.Float64Var(&flagMinSimilarity, "min-similarity", 0.8, "only report the pairs of packages whose importers overlap by at least this `RATIO`, from 0 to 1: the importers of both over those of either")
+}
+
+// codep is a pair of packages imported by the same packages.
+type codep struct {
+	a, b       string
+	shared     int // importers of both
+	importers  int // importers of either
+	similarity float64
+}
+
+func runCodeps(fs *flag.FlagSet, args []string) error {
+	source, err := sourceArg(args)
+	if err != nil {
+		return err
+	}
+	if flagMinSimilarity < 0 || flagMinSimilarity > 1 {
+		return fmt.Errorf("want a -min-similarity from 0 to 1, got %g", flagMinSimilarity)
+	}
+	p, err := newPainter()
+	if err != nil {
+		return err
+	}
+	if err := scanGraph(fs, source); err != nil {
+		return err
+	}
+	return writeOutput(func(w io.Writer) error { return printCodeps(w, p, codeps(graph)) })
+}
+
+// codeps returns the pairs of packages of g imported by at least -min-shared
+// packages in common, of which the share of the importers of either
+// importing both is at least -min-similarity, the most similar first.
+// Markers are left out.
+func codeps(g *Graph) []codep {
+	var pkgs []string
+	for _, name := range g.Nodes() {
+		if !g.Node(name).Marker && len(g.Pred(name)) >= flagMinShared {
+			pkgs = append(pkgs, name)
+		}
+	}
+	importers := map[string]map[string]bool{}
+	for _, name := range pkgs {
+		importers[name] = map[string]bool{}
+		for _, from := range g.Pred(name) {
+			importers[name][from] = true
+		}
+	}
+	var result []codep
+	for i, a := range pkgs {
+		for _, b := range pkgs[i+1:] {
+			shared := 0
+			for from := range importers[a] {
+				if importers[b][from] {
+					shared++
+				}
+			}
+			either := len(importers[a]) + len(importers[b]) - shared
+			similarity := float64(shared) / float64(either)
+			if shared >= flagMinShared && similarity >= flagMinSimilarity {
+				result = append(result, codep{a, b, shared, either, similarity})
+			}
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].similarity != result[j].similarity {
+			return result[i].similarity > result[j].similarity
+		}
+		return result[i].shared > result[j].shared
+	})
+	return result
+}
+
+// printCodeps writes the pairs of packages imported together, with how
+// many packages import both and either.
+func printCodeps(w io.Writer, p painter, pairs []codep) error {
+	table := [][]cell{{{"SIMILARITY", bold}, {"BOTH", bold}, {"EITHER", bold}, {"PACKAGES", bold}}}
+	for _, c := range pairs {
+		table = append(table, []cell{
+			{fmt.Sprintf("%.0f%%", 100*c.similarity), plain},
+			{fmt.Sprint(c.shared), plain},
+			{fmt.Sprint(c.importers), dim},
+			{c.a + " + " + c.b, cyan},
+		})
+	}
+	if err := p.printTable(w, table); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%d pairs of packages imported together\n", len(pairs))
+	return err
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+
+	graphs "github.com/sequix/baobab/graph"
+)
+
+var flagCollapseDepth int
+
+// collapse returns g with the packages below depth directories merged into
+// one node per subtree, named by its directory at that depth.
+func collapse(g *Graph, depth int) *Graph {
+	return merge(g, func(n *Node) string {
+		if n.External || n.Std || n.Marker || n.Proto {
+			return n.Name
+		}
+		return collapsedName(n.Name, depth)
+	})
+}
+
+// merge returns g with the nodes merged into the one named by into. Imports
+// between merged nodes are merged too, weighing how many they are, and
+// those within a merged node are left out.
+func merge(g *Graph, into func(*Node) string) *Graph {
+	result := graphs.New()
+	names := map[string]string{}
+	for _, name := range g.Nodes() {
+		n := g.Node(name)
+		merged := into(n)
+		names[name] = merged
+		m := result.Node(merged)
+		if m == nil {
+			m = result.AddNode(merged)
+			m.Module, m.External, m.Std, m.Marker, m.Proto = n.Module, n.External, n.Std, n.Marker, n.Proto
+			if merged == name {
+				m.Canonical, m.Group = n.Canonical, n.Group
+			}
+			m.Owners = n.Owners
+		} else if !slices.Equal(m.Owners, n.Owners) {
+			// Owned by several, so by none of them alone.
+			m.Owners = nil
+		}
+		m.Cgo = m.Cgo || n.Cgo
+		m.Packages += max(n.Packages, 1)
+		m.Files += n.Files
+		m.TestFiles += n.TestFiles
+		m.Lines += n.Lines
+		m.Commits += n.Commits
+		for _, v := range n.Vulns {
+			if !slices.Contains(m.Vulns, v) {
+				m.Vulns = append(m.Vulns, v)
+			}
+		}
+		slices.Sort(m.Vulns)
+		m.Statements += n.Statements
+		m.Covered += n.Covered
+		m.Imports = append(m.Imports, n.Imports...)
+	}
+	for _, e := range g.Edges() {
+		from, to := names[e.From], names[e.To]
+		if from == to {
+			continue
+		}
+		m := result.Edge(from, to)
+		if m == nil {
+			m = result.AddEdge(from, to)
+			m.Test = e.Test
+		}
+		m.Test = m.Test && e.Test
+		m.Blank = m.Blank || e.Blank
+		m.Dot = m.Dot || e.Dot
+		m.Vulnerable = m.Vulnerable || e.Vulnerable
+		m.Weight += max(e.Weight, 1)
+	}
+	return result
+}
+
+// collapsedName returns the first depth directories of name.
+func collapsedName(name string, depth int) string {
+	elems := strings.Split(name, string(filepath.Separator))
+	if len(elems) <= depth {
+		return name
+	}
+	return filepath.Join(elems[:depth]...)
+}
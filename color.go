@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var flagColor string
+
+// colorFlags defines the flags of the commands writing reports for humans.
+func colorFlags(fs *flag.FlagSet) {
+	fs.StringVar(&flagColor, "color", "auto", "when to color and align the report: auto, when writing to a terminal, always or never")
+}
+
+// style is an ANSI SGR code.
+type style string
+
+const (
+	plain   style = ""
+	bold    style = "1"
+	dim     style = "2"
+	reverse style = "7"
+	red     style = "31"
+	yellow  style = "33"
+	cyan    style = "36"
+)
+
+// painter writes reports for terminals when true, plain text otherwise.
+type painter bool
+
+// newPainter returns the painter -color asks for. When auto, reports are
+// colored on terminals, unless NO_COLOR is set or TERM is dumb.
+func newPainter() (painter, error) {
+	switch flagColor {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		return painter(flagOut == "" && os.Getenv("NO_COLOR") == "" &&
+			os.Getenv("TERM") != "dumb" && isTerminal(os.Stdout)), nil
+	}
+	return false, fmt.Errorf("unknown -color %q, want auto, always or never", flagColor)
+}
+
+// paint returns s in style st.
+func (p painter) paint(s string, st style) string {
+	if !p || st == plain {
+		return s
+	}
+	return "\x1b[" + string(st) + "m" + s + "\x1b[0m"
+}
+
+// cell is some text of a table and its style.
+type cell struct {
+	text  string
+	style style
+}
+
+// printTable writes rows to w with their columns aligned two spaces apart,
+// like a tabwriter would, but measuring text without its colors.
+func (p painter) printTable(w io.Writer, rows [][]cell) error {
+	var widths []int
+	for _, row := range rows {
+		for i, c := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], len(c.text))
+		}
+	}
+	for _, row := range rows {
+		var b strings.Builder
+		for i, c := range row {
+			b.WriteString(p.paint(c.text, c.style))
+			if i < len(row)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-len(c.text)+2))
+			}
+		}
+		if _, err := fmt.Fprintln(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
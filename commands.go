@@ -0,0 +1,580 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	graphs "github.com/sequix/baobab/graph"
+)
+
+// command is a subcommand of baobab.
+type command struct {
+	name    string
+	args    string // positional arguments, for usage
+	summary string
+	flags   []func(fs *flag.FlagSet)
+	run     func(fs *flag.FlagSet, args []string) error
+}
+
+// errViolations is returned by commands which found problems, to exit 1
+// without printing more.
+var errViolations = errors.New("violations found")
+
+// errDryRun is returned by scanGraph with -dry-run, for commands to stop
+// after the scan.
+var errDryRun = errors.New("dry run")
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{"graph", "[SOURCE]", "print the dependency graph, the default command",
+			[]func(*flag.FlagSet){scanFlags, formatFlags, outputFlags}, runGraph},
+		{"check", "[SOURCE]", "report imports breaking the enabled rules, exit 1 if any per -fail-on",
+			[]func(*flag.FlagSet){scanFlags, ruleFlags, checkFlags, outputFlags, colorFlags}, runCheck},
+		{"stats", "[SOURCE]", "rank packages by PageRank and betweenness",
+			[]func(*flag.FlagSet){scanFlags, outputFlags, colorFlags}, runStats},
+		{"initorder", "[SOURCE]", "list the packages in the order they initialize, with the work their init functions and variables do",
+			[]func(*flag.FlagSet){scanFlags, initFlags, outputFlags, colorFlags}, runInitOrder},
+		{"buildtime", "[SOURCE]", "attribute compile time to packages and rank them by what a change to them costs to rebuild",
+			[]func(*flag.FlagSet){scanFlags, buildTimeFlags, outputFlags, colorFlags}, runBuildTime},
+		{"binsize", "[SOURCE]", "attribute the size of a binary to the packages in it and the imports bringing them in",
+			[]func(*flag.FlagSet){scanFlags, binSizeFlags, outputFlags, colorFlags}, runBinSize},
+		{"consolidate", "[SOURCE]", "suggest merging the packages a single other package imports into it",
+			[]func(*flag.FlagSet){scanFlags, consolidateFlags, outputFlags, colorFlags}, runConsolidate},
+		{"split", "[SOURCE]", "suggest splitting the packages whose files form groups referring to no other",
+			[]func(*flag.FlagSet){scanFlags, splitFlags, outputFlags, colorFlags}, runSplit},
+		{"codeps", "[SOURCE]", "report the pairs of packages imported together by the same packages, to merge or put behind one facade",
+			[]func(*flag.FlagSet){scanFlags, codepsFlags, outputFlags, colorFlags}, runCodeps},
+		{"api", "[SOURCE]", "count the names each package exports and how many of them the others use",
+			[]func(*flag.FlagSet){scanFlags, apiFlags, outputFlags, colorFlags}, runAPI},
+		{"simulate", "[SOURCE]", "report what deleting the -remove packages would break, exit 1 if anything",
+			[]func(*flag.FlagSet){scanFlags, simulateFlags, outputFlags, colorFlags}, runSimulate},
+		{"owners", "[SOURCE]", "list the imports between packages of different owners per CODEOWNERS",
+			[]func(*flag.FlagSet){scanFlags, codeOwnersFlags, outputFlags, colorFlags}, runOwners},
+		{"why", "FROM TO", "print the shortest chain of imports from package FROM to TO",
+			[]func(*flag.FlagSet){scanFlags, outputFlags, colorFlags}, runWhy},
+		{"calls", "[SOURCE]", "print the graph of calls between functions of different packages",
+			[]func(*flag.FlagSet){scanFlags, formatFlags, callFlags, samePackageFlags, outputFlags}, runCalls},
+		{"implements", "[SOURCE]", "print the graph of types implementing interfaces of other packages",
+			[]func(*flag.FlagSet){scanFlags, formatFlags, samePackageFlags, outputFlags}, runImplements},
+		{"types", "[SOURCE]", "print the graph of exported types made of those of other packages",
+			[]func(*flag.FlagSet){scanFlags, formatFlags, samePackageFlags, outputFlags}, runTypes},
+		{"unused", "[SOURCE]", "list the exported names no other package of the modules uses, type checking them",
+			[]func(*flag.FlagSet){scanFlags, outputFlags, colorFlags}, runUnused},
+		{"diff", "OLD.json NEW.json", "compare two graphs written by graph -format json",
+			[]func(*flag.FlagSet){diffFlags, outputFlags}, runDiff},
+		{"tui", "[SOURCE]", "browse the packages and their imports in the terminal",
+			[]func(*flag.FlagSet){scanFlags}, runTUI},
+		{"repl", "[SOURCE]", "scan once, then answer queries about the graph read from stdin",
+			[]func(*flag.FlagSet){scanFlags, colorFlags}, runREPL},
+		{"watch", "", "print the graph again whenever go files change",
+			[]func(*flag.FlagSet){scanFlags, formatFlags, outputFlags, watchFlags}, runWatch},
+		{"serve", "", "serve a web UI and REST API on the graph, scanning again on demand",
+			[]func(*flag.FlagSet){scanFlags, formatFlags, ruleFlags, serveFlags, watchFlags}, runServe},
+		{"daemon", "", "scan, then answer the queries of baobab query on a unix socket, scanning again when go files change",
+			[]func(*flag.FlagSet){scanFlags, watchFlags, socketFlags}, runDaemon},
+		{"query", "QUERY [ARGS]", "answer a query of repl from the graph of the running daemon",
+			[]func(*flag.FlagSet){socketFlags, outputFlags, colorFlags}, runQuery},
+		{"version", "", "print the version of baobab and the go it was built with",
+			[]func(*flag.FlagSet){outputFlags}, runVersion},
+		{"completion", "bash|zsh|fish", "print a shell completion script",
+			[]func(*flag.FlagSet){outputFlags}, runCompletion},
+		{"__packages", "", "list the packages, for completion scripts",
+			[]func(*flag.FlagSet){scanFlags, outputFlags}, runPackages},
+		{"help", "[COMMAND]", "describe the commands, or the flags of one", nil, runHelp},
+	}
+}
+
+// findCommand returns the command called name, nil if none. scan is the old
+// name of graph.
+func findCommand(name string) *command {
+	if name == "scan" {
+		name = "graph"
+	}
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// flagSet returns a new flag set with the flags of c, setting them to their
+// defaults.
+func (c *command) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ExitOnError)
+	logFlags(fs)
+	for _, f := range c.flags {
+		f(fs)
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: baobab %s [flags] %s\n\n%s.\n", c.name, c.args, c.summary)
+		fmt.Fprintln(fs.Output(), "\nflags:")
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// flagSets holds the flags of every command, by name, see defineFlags.
+var flagSets = map[string]*flag.FlagSet{}
+
+// defineFlags fills flagSets. As a side effect every flag is set to its
+// default, so commands can read the flags they don't define.
+func defineFlags() {
+	for _, c := range commands {
+		flagSets[c.name] = c.flagSet()
+	}
+}
+
+// flagNames returns the names of the flags of every command.
+func flagNames() map[string]bool {
+	names := map[string]bool{}
+	for _, fs := range flagSets {
+		fs.VisitAll(func(f *flag.Flag) { names[f.Name] = true })
+	}
+	return names
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: baobab [COMMAND] [flags] [args]\n\ncommands:")
+	width := 0
+	for _, c := range visibleCommands() {
+		width = max(width, len(c.name))
+	}
+	for _, c := range visibleCommands() {
+		fmt.Fprintf(os.Stderr, "  %-*s %s\n", width, c.name, c.summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun baobab help COMMAND for the flags of a command.")
+}
+
+func runHelp(fs *flag.FlagSet, args []string) error {
+	switch {
+	case len(args) == 0:
+		usage()
+	case len(args) == 1 && findCommand(args[0]) != nil:
+		flagSets[findCommand(args[0]).name].Usage()
+	default:
+		return fmt.Errorf("unknown command %q", strings.Join(args, " "))
+	}
+	return nil
+}
+
+// sourceArg returns the optional SOURCE argument of scanning commands.
+func sourceArg(args []string) (string, error) {
+	switch len(args) {
+	case 0:
+		return "", nil
+	case 1:
+		return args[0], nil
+	}
+	return "", fmt.Errorf("unexpected arguments %q", args[1:])
+}
+
+// graphWriter returns the function writing the graph in -format, as
+// outputGraph makes it.
+func graphWriter() (func(io.Writer, *Graph) error, error) {
+	var write func(io.Writer, *Graph) error
+	switch flagFormat {
+	case "dot":
+		write = writeDOT
+	case "json":
+		write = writeJSON
+	case "cypher":
+		write = writeCypher
+	case "sqlite":
+		write = writeSQLite
+	case "bazel":
+		write = writeBazel
+	case "bundle":
+		write = writeBundle
+	case "treemap":
+		write = writeTreemap
+	case "sankey":
+		if flagGroups == "" {
+			return nil, fmt.Errorf("-format sankey draws the imports between -groups, and none were given")
+		}
+		write = writeSankey
+	default:
+		return nil, fmt.Errorf("unknown format %q", flagFormat)
+	}
+	if err := checkSizeBy(); err != nil {
+		return nil, err
+	}
+	if err := checkLayers(); err != nil {
+		return nil, err
+	}
+	if err := loadTheme(); err != nil {
+		return nil, err
+	}
+	output, err := outputGraph()
+	if err != nil {
+		return nil, err
+	}
+	return func(w io.Writer, g *Graph) error { return write(w, output(g)) }, nil
+}
+
+// outputGraph returns the function making the graph to output from the one
+// scanned: with the owners of -codeowners and the coverage of -coverage,
+// without what -filter-node and -filter-edge drop, grouped per -groups or
+// -group-owners, collapsed to -collapse-depth and labeled per -relabel.
+func outputGraph() (func(*Graph) *Graph, error) {
+	if flagCollapseDepth < 0 {
+		return nil, fmt.Errorf("-collapse-depth must not be negative, got %d", flagCollapseDepth)
+	}
+	filter, err := outputFilter()
+	if err != nil {
+		return nil, err
+	}
+	var groups []group
+	if flagGroups != "" {
+		if groups, err = parseGroups(flagGroups); err != nil {
+			return nil, err
+		}
+	}
+	var rules []relabelRule
+	if flagRelabel != "" {
+		if rules, err = parseRelabel(flagRelabel); err != nil {
+			return nil, err
+		}
+	}
+	if flagGroupOwners && (flagCodeOwners == "" || flagGroups != "") {
+		return nil, fmt.Errorf("-group-owners takes -codeowners and no -groups")
+	}
+	var owners *codeOwners
+	if flagCodeOwners != "" {
+		if owners, err = readCodeOwners(flagCodeOwners); err != nil {
+			return nil, err
+		}
+	}
+	var profile map[string]blockCounts
+	if flagCoverage != "" {
+		if profile, err = readCoverProfile(flagCoverage); err != nil {
+			return nil, err
+		}
+	}
+	return func(g *Graph) *Graph {
+		if owners != nil {
+			applyOwners(g, owners)
+		}
+		if profile != nil {
+			applyCoverage(g, profile)
+		}
+		if filter != nil {
+			g = filter(g)
+		}
+		if groups != nil {
+			g = applyGroups(g, func(n *Node) string { return groupOf(groups, n.Name) }, flagClusters)
+		}
+		if flagGroupOwners {
+			g = applyGroups(g, ownerGroup, flagClusters)
+		}
+		if flagCollapseDepth > 0 {
+			g = collapse(g, flagCollapseDepth)
+		}
+		if rules != nil {
+			relabel(g, rules)
+		}
+		return g
+	}, nil
+}
+
+func runGraph(fs *flag.FlagSet, args []string) error {
+	source, err := sourceArg(args)
+	if err != nil {
+		return err
+	}
+	write, err := graphWriter()
+	if err != nil {
+		return err
+	}
+	if err := scanGraph(fs, source); err != nil {
+		return err
+	}
+	return writeOutput(func(w io.Writer) error { return write(w, graph) })
+}
+
+func runCheck(fs *flag.FlagSet, args []string) error {
+	source, err := sourceArg(args)
+	if err != nil {
+		return err
+	}
+	for _, name := range flagFailOn {
+		if _, ok := ruleGroups[name]; !ok && !knownRule(name) && !knownPlugin(name) {
+			return fmt.Errorf("unknown rule %q for -fail-on", name)
+		}
+	}
+	if flagReport != "text" && flagReport != "github" {
+		return fmt.Errorf("unknown report %q", flagReport)
+	}
+	if err := loadImportRules(); err != nil {
+		return err
+	}
+	if flagUpdateBase {
+		if flagBaseline == "" {
+			return fmt.Errorf("-update-baseline needs a -baseline file")
+		}
+		if flagStaged {
+			return fmt.Errorf("cannot update the baseline from staged files only")
+		}
+	}
+	var staged []string
+	if flagStaged {
+		if source != "" || flagRepo != "" {
+			return fmt.Errorf("cannot check staged files of another source")
+		}
+		if len(flagEntries) > 0 && !configFlags["entry"] {
+			return fmt.Errorf("cannot use both -staged and -entry")
+		}
+		if staged, err = stagedDirs(); err != nil {
+			return err
+		}
+		if len(staged) == 0 {
+			return nil
+		}
+		flagEntries = staged
+	}
+	p, err := newPainter()
+	if err != nil {
+		return err
+	}
+	if err := scanGraph(fs, source); err != nil {
+		return err
+	}
+	violations := runChecks(graph, enabledChecks())
+	external, err := checkExternal(graph)
+	if err != nil {
+		return err
+	}
+	violations = append(violations, external...)
+	if flagStaged {
+		violations = involving(violations, staged)
+	}
+	if flagUpdateBase {
+		if err := writeBaseline(flagBaseline, violations); err != nil {
+			return err
+		}
+		logger.Info("updated baseline", "file", flagBaseline, "violations", len(violations))
+		return nil
+	}
+	if flagBaseline != "" {
+		b, err := readBaseline(flagBaseline)
+		if err != nil {
+			return err
+		}
+		all := len(violations)
+		var fixed int
+		violations, fixed = b.newViolations(violations)
+		logger.Debug("left out violations in the baseline", "known", all-len(violations))
+		if fixed > 0 && !flagStaged {
+			logger.Info("violations in the baseline are fixed, run with -update-baseline to record that", "fixed", fixed)
+		}
+	}
+	err = writeOutput(func(w io.Writer) error {
+		if flagReport == "github" {
+			return printAnnotations(w, violations)
+		}
+		return printViolations(w, p, violations)
+	})
+	if err != nil {
+		return err
+	}
+	for _, v := range violations {
+		if v.fails() {
+			return errViolations
+		}
+	}
+	return nil
+}
+
+func runStats(fs *flag.FlagSet, args []string) error {
+	source, err := sourceArg(args)
+	if err != nil {
+		return err
+	}
+	p, err := newPainter()
+	if err != nil {
+		return err
+	}
+	if err := scanGraph(fs, source); err != nil {
+		return err
+	}
+	return writeOutput(func(w io.Writer) error { return printRanks(w, p, Ranks(graph)) })
+}
+
+func runWatch(fs *flag.FlagSet, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments %q", args)
+	}
+	if flagRepo != "" {
+		return fmt.Errorf("cannot watch a -repo")
+	}
+	if flagGoList != "" {
+		return fmt.Errorf("cannot watch go list output")
+	}
+	write, err := graphWriter()
+	if err != nil {
+		return err
+	}
+	if _, err := prepare(fs, ""); err != nil {
+		return err
+	}
+	if err := scanAll(); err != nil {
+		return err
+	}
+	return watch(func(w io.Writer) error { return write(w, graph) })
+}
+
+func runWhy(fs *flag.FlagSet, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("want FROM and TO packages, got %q", args)
+	}
+	p, err := newPainter()
+	if err != nil {
+		return err
+	}
+	if len(flagEntries) == 0 {
+		flagEntries = listFlag{args[0]}
+	}
+	if err := scanGraph(fs, ""); err != nil {
+		return err
+	}
+	var from, to string
+	for i, n := range []*string{&from, &to} {
+		if *n = graphNode(args[i]); *n == "" {
+			return fmt.Errorf("no package %s in the graph", args[i])
+		}
+	}
+	path := graphs.ShortestPath(graph, from, to)
+	if path == nil {
+		return fmt.Errorf("%s does not import %s", from, to)
+	}
+	return writeOutput(func(w io.Writer) error { return printPath(w, p, path) })
+}
+
+// printPath writes a chain of imports, each with the file:line of every
+// import making it, aligned on terminals.
+func printPath(w io.Writer, p painter, path []string) error {
+	if !p {
+		fmt.Fprintln(w, path[0])
+		for i := 1; i < len(path); i++ {
+			if evidence := importPositions(graph, graph.Edge(path[i-1], path[i])); len(evidence) > 0 {
+				fmt.Fprintf(w, "  -> %s (%s)\n", path[i], strings.Join(evidence, ", "))
+			} else {
+				fmt.Fprintf(w, "  -> %s\n", path[i])
+			}
+		}
+		return nil
+	}
+	rows := [][]cell{{{path[0], bold}}}
+	for i := 1; i < len(path); i++ {
+		row := []cell{{"  -> " + path[i], cyan}}
+		if evidence := importPositions(graph, graph.Edge(path[i-1], path[i])); len(evidence) > 0 {
+			row = append(row, cell{"(" + strings.Join(evidence, ", ") + ")", dim})
+		}
+		rows = append(rows, row)
+	}
+	return p.printTable(w, rows)
+}
+
+// graphNode returns the node of the graph arg, a directory or an import
+// path, stands for, empty if none.
+func graphNode(arg string) string {
+	return nodeIn(graph, arg)
+}
+
+// nodeIn returns the node of g arg stands for, like graphNode.
+func nodeIn(g *Graph, arg string) string {
+	for _, n := range []string{scanner.Resolve(arg), arg, graphs.StdPrefix + arg, graphs.ExternalPrefix + arg, graphs.MarkerPrefix + arg} {
+		if g.Node(n) != nil {
+			return n
+		}
+	}
+	return ""
+}
+
+func runDiff(fs *flag.FlagSet, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("want OLD.json and NEW.json, got %q", args)
+	}
+	var docs [2]jsonGraph
+	for i, file := range args {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &docs[i]); err != nil {
+			return fmt.Errorf("failed to decode %s: %s", file, err)
+		}
+	}
+	if err := loadTheme(); err != nil {
+		return err
+	}
+	switch flagRender {
+	case "text":
+	case "dot":
+		return writeOutput(func(w io.Writer) error { return writeDiffDOT(w, overlay(docs[0], docs[1])) })
+	case "html":
+		return writeOutput(func(w io.Writer) error { return writeDiffHTML(w, overlay(docs[0], docs[1])) })
+	default:
+		return fmt.Errorf("unknown -render %q, want text, dot or html", flagRender)
+	}
+	return writeOutput(func(w io.Writer) error {
+		for _, line := range diffGraphs(docs[0], docs[1]) {
+			fmt.Fprintln(w, line)
+		}
+		return nil
+	})
+}
+
+// diffGraphs returns the nodes and edges added, +, removed, -, or whose kind
+// changed, ~, from old to new, sorted.
+func diffGraphs(old, new jsonGraph) []string {
+	var (
+		lines    []string
+		oldNodes = map[string]bool{}
+		newNodes = map[string]bool{}
+		oldEdges = map[[2]string]string{}
+		newEdges = map[[2]string]string{}
+	)
+	for _, n := range old.Nodes {
+		oldNodes[n.Name] = true
+	}
+	for _, n := range new.Nodes {
+		newNodes[n.Name] = true
+		if !oldNodes[n.Name] {
+			lines = append(lines, "+ "+n.Name)
+		}
+	}
+	for n := range oldNodes {
+		if !newNodes[n] {
+			lines = append(lines, "- "+n)
+		}
+	}
+	for _, e := range old.Edges {
+		oldEdges[[2]string{e.From, e.To}] = e.Kind
+	}
+	for _, e := range new.Edges {
+		key := [2]string{e.From, e.To}
+		newEdges[key] = e.Kind
+		switch kind, ok := oldEdges[key]; {
+		case !ok:
+			lines = append(lines, fmt.Sprintf("+ %s -> %s", e.From, e.To))
+		case kind != e.Kind:
+			lines = append(lines, fmt.Sprintf("~ %s -> %s: %s, was %s", e.From, e.To, e.Kind, kind))
+		}
+	}
+	for key := range oldEdges {
+		if _, ok := newEdges[key]; !ok {
+			lines = append(lines, fmt.Sprintf("- %s -> %s", key[0], key[1]))
+		}
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i][2:] < lines[j][2:] })
+	return lines
+}
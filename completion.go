@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// packageCommands take packages as arguments, completed from a scan.
+var packageCommands = []string{"why"}
+
+// packageFlags take a package, completed from a scan.
+var packageFlags = []string{"entry"}
+
+func runCompletion(fs *flag.FlagSet, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("want one shell, bash, zsh or fish, got %q", args)
+	}
+	var write func(io.Writer)
+	switch args[0] {
+	case "bash":
+		write = writeBashCompletion
+	case "zsh":
+		write = func(w io.Writer) {
+			fmt.Fprintln(w, "autoload -U +X bashcompinit && bashcompinit")
+			writeBashCompletion(w)
+		}
+	case "fish":
+		write = writeFishCompletion
+	default:
+		return fmt.Errorf("unknown shell %q, want bash, zsh or fish", args[0])
+	}
+	return writeOutput(func(w io.Writer) error {
+		write(w)
+		return nil
+	})
+}
+
+// runPackages lists the packages of the graph, for completion scripts. With
+// the parse cache, the scan only parses files changed since the last one.
+func runPackages(fs *flag.FlagSet, args []string) error {
+	if err := scanGraph(fs, ""); err != nil {
+		return err
+	}
+	return writeOutput(func(w io.Writer) error {
+		for _, n := range graph.Nodes() {
+			if node := graph.Node(n); !node.Marker {
+				fmt.Fprintln(w, n)
+			}
+		}
+		return nil
+	})
+}
+
+// commandFlags returns the flags of c, with their leading dash.
+func commandFlags(c *command) []string {
+	var result []string
+	flagSets[c.name].VisitAll(func(f *flag.Flag) { result = append(result, "-"+f.Name) })
+	sort.Strings(result)
+	return result
+}
+
+func visibleCommands() []*command {
+	var result []*command
+	for _, c := range commands {
+		if !strings.HasPrefix(c.name, "__") {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func writeBashCompletion(w io.Writer) {
+	var names []string
+	for _, c := range visibleCommands() {
+		names = append(names, c.name)
+	}
+	fmt.Fprintf(w, `_baobab() {
+	local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+	local cmd="${COMP_WORDS[1]}" opts
+	if [ "$COMP_CWORD" -eq 1 ] && [[ "$cur" != -* ]]; then
+		COMPREPLY=($(compgen -W %q -- "$cur"))
+		return
+	fi
+	case "$cmd" in
+	-*) cmd=graph ;;
+	esac
+	case "$prev" in
+	-%s)
+		COMPREPLY=($(compgen -W "$(baobab __packages -q 2>/dev/null)" -- "$cur"))
+		return ;;
+	esac
+	if [[ "$cur" == -* ]]; then
+		case "$cmd" in
+`, strings.Join(names, " "), strings.Join(packageFlags, "|-"))
+	for _, c := range visibleCommands() {
+		fmt.Fprintf(w, "\t\t%s) opts=%q ;;\n", c.name, strings.Join(commandFlags(c), " "))
+	}
+	fmt.Fprintf(w, `		esac
+		COMPREPLY=($(compgen -W "$opts" -- "$cur"))
+		return
+	fi
+	case "$cmd" in
+	%s)
+		COMPREPLY=($(compgen -W "$(baobab __packages -q 2>/dev/null)" -- "$cur")) ;;
+	*)
+		COMPREPLY=($(compgen -f -- "$cur")) ;;
+	esac
+}
+complete -F _baobab baobab
+`, strings.Join(packageCommands, "|"))
+}
+
+func writeFishCompletion(w io.Writer) {
+	const packages = "(baobab __packages -q 2>/dev/null)"
+	fmt.Fprintln(w, "complete -c baobab -f")
+	for _, c := range visibleCommands() {
+		fmt.Fprintf(w, "complete -c baobab -n __fish_use_subcommand -a %s -d %q\n", c.name, c.summary)
+	}
+	for _, c := range visibleCommands() {
+		cond := fmt.Sprintf("'__fish_seen_subcommand_from %s'", c.name)
+		flagSets[c.name].VisitAll(func(f *flag.Flag) {
+			usage := strings.SplitN(f.Usage, ",", 2)[0]
+			args := ""
+			for _, p := range packageFlags {
+				if f.Name == p {
+					args = fmt.Sprintf(" -x -a %q", packages)
+				}
+			}
+			fmt.Fprintf(w, "complete -c baobab -n %s -o %s -d %q%s\n", cond, f.Name, usage, args)
+		})
+		for _, p := range packageCommands {
+			if c.name == p {
+				fmt.Fprintf(w, "complete -c baobab -n %s -a %q\n", cond, packages)
+			}
+		}
+	}
+}
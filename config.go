@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sequix/baobab/scan"
+)
+
+// configFile is looked for in the directory baobab runs in and above it,
+// unless -config says otherwise.
+const configFile = ".baobab.yaml"
+
+// configValue is a key of a config file and its value, a list of one value
+// for keys with a scalar.
+type configValue struct {
+	key    string
+	values []string
+	list   bool
+	line   int
+}
+
+var (
+	// configDir is the directory of the config file read, if any.
+	configDir string
+	// configFlags holds the flags set by the config file.
+	configFlags = map[string]bool{}
+)
+
+// loadConfig sets the flags of fs not given on the command line, or in the
+// environment, to the values in file, the closest .baobab.yaml if empty. Keys
+// may be the flags of any command, in known, those fs lacks are left alone.
+func loadConfig(fs *flag.FlagSet, file string, known map[string]bool) error {
+	if file == "off" {
+		return nil
+	}
+	if file == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		dir, ok := scan.FindUp(cwd, configFile)
+		if !ok {
+			return nil
+		}
+		file = filepath.Join(dir, configFile)
+	}
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	values, err := parseConfig(file)
+	if err != nil {
+		return err
+	}
+	cmdline := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { cmdline[f.Name] = true })
+	for _, v := range values {
+		if !known[v.key] || v.key == "config" {
+			return fmt.Errorf("%s:%d: unknown key %q, keys are flag names", file, v.line, v.key)
+		}
+		f := fs.Lookup(v.key)
+		if f == nil || cmdline[v.key] {
+			continue
+		}
+		if _, ok := f.Value.(*listFlag); v.list && !ok {
+			return fmt.Errorf("%s:%d: %s takes a single value", file, v.line, v.key)
+		}
+		for _, value := range v.values {
+			if err := fs.Set(v.key, value); err != nil {
+				return fmt.Errorf("%s:%d: bad value %q for %s: %s", file, v.line, value, v.key, err)
+			}
+		}
+		configFlags[v.key] = true
+	}
+	configDir = filepath.Dir(file)
+	return nil
+}
+
+// envPrefix starts the names of the environment variables setting flags,
+// like BAOBAB_INCLUDE_TESTS for -include-tests.
+const envPrefix = "BAOBAB_"
+
+// envName returns the environment variable setting flag name.
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// loadEnv sets the flags of fs not given on the command line from their
+// environment variables, if set. Those of repeatable flags take
+// comma-separated values.
+func loadEnv(fs *flag.FlagSet) error {
+	cmdline := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { cmdline[f.Name] = true })
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok || cmdline[f.Name] || err != nil {
+			return
+		}
+		if serr := fs.Set(f.Name, value); serr != nil {
+			err = fmt.Errorf("bad value %q for %s: %s", value, envName(f.Name), serr)
+		}
+	})
+	return err
+}
+
+// parseConfig reads the subset of YAML config files are written in: keys
+// with a scalar, a [flow, list] or a block list of - items as value.
+func parseConfig(file string) ([]configValue, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var (
+		result []configValue
+		scan   = bufio.NewScanner(f)
+		// block is whether the last key starts a block list, items
+		// following it, indented or not.
+		block bool
+	)
+	for line := 1; scan.Scan(); line++ {
+		text := strings.TrimRight(stripComment(scan.Text()), " \t")
+		trimmed := strings.TrimSpace(text)
+		switch {
+		case trimmed == "" || trimmed == "---":
+			continue
+		case strings.HasPrefix(trimmed, "- "), trimmed == "-":
+			last := len(result) - 1
+			if !block {
+				return nil, fmt.Errorf("%s:%d: list item without a key", file, line)
+			}
+			result[last].values = append(result[last].values, unquoteYAML(strings.TrimSpace(trimmed[1:])))
+			continue
+		case text != trimmed:
+			return nil, fmt.Errorf("%s:%d: unexpected indentation", file, line)
+		}
+		i := strings.Index(text, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("%s:%d: expected key: value", file, line)
+		}
+		v := configValue{key: strings.TrimSpace(text[:i]), line: line}
+		block = false
+		switch value := strings.TrimSpace(text[i+1:]); {
+		case value == "":
+			v.list, block = true, true
+		case strings.HasPrefix(value, "["):
+			if !strings.HasSuffix(value, "]") {
+				return nil, fmt.Errorf("%s:%d: unterminated list", file, line)
+			}
+			v.list = true
+			for _, item := range strings.Split(value[1:len(value)-1], ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					v.values = append(v.values, unquoteYAML(item))
+				}
+			}
+		default:
+			v.values = []string{unquoteYAML(value)}
+		}
+		result = append(result, v)
+	}
+	return result, scan.Err()
+}
+
+// stripComment removes a # comment from line, unless it is quoted.
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// unquoteYAML returns value without its quotes, if it has any.
+func unquoteYAML(value string) string {
+	switch {
+	case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+		if s, err := strconv.Unquote(value); err == nil {
+			return s
+		}
+	case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+	}
+	return value
+}
+
+// relToCwd returns dir relative to the current directory.
+func relToCwd(dir string) (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(cwd, dir)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes content to a config file in a temporary directory and
+// returns its path.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), configFile)
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestParseConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name, content string
+		want          []configValue
+	}{
+		{"scalars", "---\nbackend: parser # fast enough\ninclude-tests: true\nformat: \"dot\"\nlabel: 'it''s'\n", []configValue{
+			{"backend", []string{"parser"}, false, 2},
+			{"include-tests", []string{"true"}, false, 3},
+			{"format", []string{"dot"}, false, 4},
+			{"label", []string{"it's"}, false, 5},
+		}},
+		{"flow list", "exclude: [vendor, \"test data\" , 'testdata']\nempty: []\n", []configValue{
+			{"exclude", []string{"vendor", "test data", "testdata"}, true, 1},
+			{"empty", nil, true, 2},
+		}},
+		{"indented block list", "exclude:\n  - vendor\n\n  # comment\n  - \"#not a comment\"\nbackend: scanner\n", []configValue{
+			{"exclude", []string{"vendor", "#not a comment"}, true, 1},
+			{"backend", []string{"scanner"}, false, 6},
+		}},
+		{"unindented block list", "exclude:\n- vendor\n- testdata\ninclude:\n- '**'\n", []configValue{
+			{"exclude", []string{"vendor", "testdata"}, true, 1},
+			{"include", []string{"**"}, true, 4},
+		}},
+		{"empty item", "exclude:\n-\n", []configValue{
+			{"exclude", []string{""}, true, 1},
+		}},
+	} {
+		got, err := parseConfig(writeConfig(t, tc.content))
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %+v, %v, want %+v", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	for _, tc := range []struct {
+		content, want string
+	}{
+		{"- vendor\n", ":1: list item without a key"},
+		{"backend: parser\n- vendor\n", ":2: list item without a key"},
+		{"exclude: [vendor]\n- testdata\n", ":2: list item without a key"},
+		{"exclude:\n- vendor\nbackend: parser\n- testdata\n", ":4: list item without a key"},
+		{"backend: parser\n  format: dot\n", ":2: unexpected indentation"},
+		{"just a value\n", ":1: expected key: value"},
+		{": value\n", ":1: expected key: value"},
+		{"exclude: [vendor\n", ":1: unterminated list"},
+	} {
+		_, err := parseConfig(writeConfig(t, tc.content))
+		if err == nil || !strings.HasSuffix(err.Error(), tc.want) {
+			t.Errorf("parseConfig(%q) error %v, want one ending in %s", tc.content, err, tc.want)
+		}
+	}
+}
+
+func TestStripComment(t *testing.T) {
+	for _, tc := range []struct {
+		line, want string
+	}{
+		{"a: b # c", "a: b "},
+		{"# c", ""},
+		{"a: b#c", "a: b#c"},
+		{"a: \"b # c\" # d", "a: \"b # c\" "},
+		{"a: 'b # c'\t# d", "a: 'b # c'\t"},
+		{"a: \"it's\" # d", "a: \"it's\" "},
+	} {
+		if got := stripComment(tc.line); got != tc.want {
+			t.Errorf("stripComment(%q) = %q, want %q", tc.line, got, tc.want)
+		}
+	}
+}
+
+func TestUnquoteYAML(t *testing.T) {
+	for _, tc := range []struct {
+		value, want string
+	}{
+		{"plain", "plain"},
+		{`"a\tb"`, "a\tb"},
+		{`"bad \q"`, `"bad \q"`},
+		{"'it''s'", "it's"},
+		{`'\t'`, `\t`},
+		{`"`, `"`},
+		{"'a\"", "'a\""},
+	} {
+		if got := unquoteYAML(tc.value); got != tc.want {
+			t.Errorf("unquoteYAML(%s) = %q, want %q", tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	var (
+		fromFlag, fromEnv, fromConfig, unset string
+		list                                 listFlag
+	)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.StringVar(&fromFlag, "from-flag", "", "")
+	fs.StringVar(&fromEnv, "from-env", "", "")
+	fs.StringVar(&fromConfig, "from-config", "default", "")
+	fs.StringVar(&unset, "unset", "default", "")
+	fs.Var(&list, "list", "")
+	if err := fs.Parse([]string{"-from-flag", "flag"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { configDir, configFlags = "", map[string]bool{} })
+	t.Setenv("BAOBAB_FROM_FLAG", "env")
+	t.Setenv("BAOBAB_FROM_ENV", "env")
+	if err := loadEnv(fs); err != nil {
+		t.Fatal(err)
+	}
+	file := writeConfig(t, "from-flag: config\nfrom-env: config\nfrom-config: config\nlist:\n- a\n- b,c\nother: x\n")
+	known := map[string]bool{"from-flag": true, "from-env": true, "from-config": true, "unset": true, "list": true, "other": true}
+	if err := loadConfig(fs, file, known); err != nil {
+		t.Fatal(err)
+	}
+	if fromFlag != "flag" || fromEnv != "env" || fromConfig != "config" || unset != "default" {
+		t.Errorf("from-flag %q, from-env %q, from-config %q, unset %q, want flag, env, config and default", fromFlag, fromEnv, fromConfig, unset)
+	}
+	if want := (listFlag{"a", "b", "c"}); !reflect.DeepEqual(list, want) {
+		t.Errorf("list %q, want %q", list, want)
+	}
+	if configDir != filepath.Dir(file) || !configFlags["from-config"] || configFlags["from-flag"] {
+		t.Errorf("configDir %s, configFlags %v", configDir, configFlags)
+	}
+
+	for _, tc := range []struct {
+		content, want string
+	}{
+		{"nope: x\n", `:1: unknown key "nope", keys are flag names`},
+		{"config: other.yaml\n", `:1: unknown key "config", keys are flag names`},
+		{"unset: [a, b]\n", ":1: unset takes a single value"},
+	} {
+		err := loadConfig(fs, writeConfig(t, tc.content), known)
+		if err == nil || !strings.HasSuffix(err.Error(), tc.want) {
+			t.Errorf("loadConfig(%q) error %v, want one ending in %s", tc.content, err, tc.want)
+		}
+	}
+	if err := loadConfig(fs, "off", known); err != nil {
+		t.Errorf("loadConfig(off): %s", err)
+	}
+}
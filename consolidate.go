@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+)
+
+var flagMaxLines int
+
+// consolidateFlags defines the flags of the consolidate command.
+func consolidateFlags(fs *flag.FlagSet) {
+	fs.IntVar(&flagMaxLines, "max-lines", 0, "only suggest packages of at most `N` lines of go code, 0 for any")
+}
+
+// mergeCandidate is a package only one other package of the scanned modules
+// imports, which could as well be part of it.
+type mergeCandidate struct {
+	pkg, into *Node
+}
+
+func runConsolidate(fs *flag.FlagSet, args []string) error {
+	source, err := sourceArg(args)
+	if err != nil {
+		return err
+	}
+	p, err := newPainter()
+	if err != nil {
+		return err
+	}
+	if err := scanGraph(fs, source); err != nil {
+		return err
+	}
+	return writeOutput(func(w io.Writer) error { return printMergeCandidates(w, p, mergeCandidates(graph)) })
+}
+
+// mergeCandidates returns the packages of the scanned modules of g with a
+// single importer, of the scanned modules too, and at most -max-lines lines,
+// the smallest first.
+func mergeCandidates(g *Graph) []mergeCandidate {
+	internal := func(n *Node) bool {
+		return !n.External && !n.Std && !n.Marker && !n.Proto
+	}
+	var result []mergeCandidate
+	for _, name := range g.Nodes() {
+		n := g.Node(name)
+		if !internal(n) || flagMaxLines > 0 && n.Lines > flagMaxLines {
+			continue
+		}
+		importers := g.Pred(name)
+		if len(importers) != 1 || !internal(g.Node(importers[0])) {
+			continue
+		}
+		result = append(result, mergeCandidate{n, g.Node(importers[0])})
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].pkg.Lines < result[j].pkg.Lines })
+	return result
+}
+
+// printMergeCandidates writes the packages to merge into their importer,
+// with their size.
+func printMergeCandidates(w io.Writer, p painter, candidates []mergeCandidate) error {
+	table := [][]cell{{{"LINES", bold}, {"FILES", bold}, {"PACKAGE", bold}, {"IMPORTER", bold}, {"IMPORTER LINES", bold}}}
+	for _, c := range candidates {
+		table = append(table, []cell{
+			{fmt.Sprint(c.pkg.Lines), plain},
+			{fmt.Sprint(c.pkg.Files), plain},
+			{c.pkg.Name, cyan},
+			{c.into.Name, plain},
+			{fmt.Sprint(c.into.Lines), dim},
+		})
+	}
+	if err := p.printTable(w, table); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%d packages with a single importer\n", len(candidates))
+	return err
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+)
+
+var flagCoupling bool
+
+// applyCoupling weights the imports of g between packages of the scanned
+// modules by the number of exported names of the imported package the
+// importer uses, if -coupling.
+func applyCoupling(g *Graph) error {
+	if !flagCoupling {
+		return nil
+	}
+	used, err := scanner.Coupling(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, e := range g.Edges() {
+		if n, ok := used[[2]string{e.From, e.To}]; ok {
+			e.Weight = n
+		}
+	}
+	return nil
+}
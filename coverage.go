@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+var flagCoverage string
+
+// blockCounts is the number of statements of a package in a coverage
+// profile, and of those covered.
+type blockCounts struct {
+	statements int
+	covered    int
+}
+
+// readCoverProfile reads the coverage profile file, as go test
+// -coverprofile writes it, and returns the counts of each package by import
+// path. Blocks found several times, as in profiles concatenated from
+// several runs, count once, covered if any run covered them.
+func readCoverProfile(file string) (map[string]blockCounts, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open coverage profile: %s", err)
+	}
+	defer f.Close()
+	type block struct {
+		statements int
+		covered    bool
+	}
+	blocks := map[string]*block{}
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "mode:") {
+			continue
+		}
+		// name.go:line.column,line.column statements count
+		fields := strings.Fields(text)
+		if len(fields) != 3 || !strings.Contains(fields[0], ":") {
+			return nil, fmt.Errorf("%s:%d: bad coverage block %q", file, line, text)
+		}
+		statements, err1 := strconv.Atoi(fields[1])
+		count, err2 := strconv.Atoi(fields[2])
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("%s:%d: bad coverage block %q", file, line, text)
+		}
+		b := blocks[fields[0]]
+		if b == nil {
+			b = &block{statements: statements}
+			blocks[fields[0]] = b
+		}
+		b.covered = b.covered || count > 0
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read coverage profile: %s", err)
+	}
+	result := map[string]blockCounts{}
+	for key, b := range blocks {
+		pkg := path.Dir(key[:strings.LastIndex(key, ":")])
+		c := result[pkg]
+		c.statements += b.statements
+		if b.covered {
+			c.covered += b.statements
+		}
+		result[pkg] = c
+	}
+	return result, nil
+}
+
+// applyCoverage sets the statements of the packages of g found in profile,
+// and how many are covered.
+func applyCoverage(g *Graph, profile map[string]blockCounts) {
+	for _, name := range g.Nodes() {
+		n := g.Node(name)
+		if c, ok := profile[n.ImportPath]; ok && !n.Marker && !n.Proto {
+			n.Statements, n.Covered = c.statements, c.covered
+		}
+	}
+}
+
+// coverage returns the percentage of the statements of n covered, ok false
+// if the coverage profile has none of n.
+func coverage(n *Node) (percent float64, ok bool) {
+	if n.Statements == 0 {
+		return 0, false
+	}
+	return 100 * float64(n.Covered) / float64(n.Statements), true
+}
+
+// dotCoverageAttrs returns the DOT attributes coloring n by its coverage,
+// from red for none to green for all, empty if unknown.
+func dotCoverageAttrs(n *Node) string {
+	c, ok := coverage(n)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("style=filled, fillcolor=\"%.3f 0.45 1.000\", xlabel=\"%.0f%%\"", c/100/3, c)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// writeCypher writes g as Cypher statements creating a :Package node per
+// package, with its properties and rank scores, and an :IMPORTS
+// relationship per edge, for cypher-shell to load into Neo4j.
+func writeCypher(w io.Writer, g *Graph) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "CREATE INDEX package_name IF NOT EXISTS FOR (p:Package) ON (p.name);")
+	pr, bc := PageRank(g), Betweenness(g)
+	for _, name := range g.Nodes() {
+		n := g.Node(name)
+		labels := ":Package"
+		switch {
+		case n.Marker:
+			labels += ":Marker"
+		case n.Proto:
+			labels += ":Proto"
+		case n.Std:
+			labels += ":Std"
+		case n.External:
+			labels += ":External"
+		}
+		props := []string{"name: " + cypherString(name)}
+		for _, p := range []struct{ key, value string }{
+			{"importPath", n.ImportPath},
+			{"module", n.Module},
+			{"canonical", n.Canonical},
+			{"label", n.Label},
+			{"group", n.Group},
+		} {
+			if p.value != "" {
+				props = append(props, p.key+": "+cypherString(p.value))
+			}
+		}
+		for _, p := range []struct {
+			key    string
+			values []string
+		}{
+			{"owners", n.Owners},
+			{"vulns", n.Vulns},
+		} {
+			if len(p.values) > 0 {
+				values := make([]string, len(p.values))
+				for i, v := range p.values {
+					values[i] = cypherString(v)
+				}
+				props = append(props, p.key+": ["+strings.Join(values, ", ")+"]")
+			}
+		}
+		if n.Cgo {
+			props = append(props, "cgo: true")
+		}
+		for _, p := range []struct {
+			key   string
+			value int
+		}{
+			{"packages", n.Packages},
+			{"files", n.Files},
+			{"testFiles", n.TestFiles},
+			{"lines", n.Lines},
+			{"commits", n.Commits},
+		} {
+			if p.value > 0 {
+				props = append(props, fmt.Sprintf("%s: %d", p.key, p.value))
+			}
+		}
+		props = append(props,
+			fmt.Sprintf("imports: %d", len(g.Succ(name))),
+			fmt.Sprintf("importers: %d", len(g.Pred(name))),
+			"pageRank: "+strconv.FormatFloat(pr[name], 'g', -1, 64),
+			"betweenness: "+strconv.FormatFloat(bc[name], 'g', -1, 64))
+		fmt.Fprintf(bw, "CREATE (%s {%s});\n", labels, strings.Join(props, ", "))
+	}
+	for _, e := range g.Edges() {
+		props := "kind: " + cypherString(e.Kind())
+		if e.Weight > 0 {
+			props += fmt.Sprintf(", weight: %d", e.Weight)
+		}
+		if e.Vulnerable {
+			props += ", vulnerable: true"
+		}
+		fmt.Fprintf(bw, "MATCH (a:Package {name: %s}), (b:Package {name: %s}) CREATE (a)-[:IMPORTS {%s}]->(b);\n",
+			cypherString(e.From), cypherString(e.To), props)
+	}
+	return bw.Flush()
+}
+
+// cypherString quotes s as a Cypher string literal.
+func cypherString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(s) + "'"
+}
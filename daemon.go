@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sequix/baobab/scan"
+)
+
+var flagSocket string
+
+// socketFlags defines where the daemon and its clients meet.
+func socketFlags(fs *flag.FlagSet) {
+	fs.StringVar(&flagSocket, "socket", "", "unix socket `FILE` the daemon listens on, one in the temporary directory named after the root of the project by default")
+}
+
+// socketPath returns the socket of -socket, or the one of the project
+// baobab runs in.
+func socketPath() (string, error) {
+	if flagSocket != "" {
+		// Absolute, as the daemon changes to the root of the project.
+		return filepath.Abs(flagSocket)
+	}
+	root, err := scan.FindRoot(".", true)
+	if err != nil {
+		return "", err
+	}
+	if root, err = filepath.Abs(root); err != nil {
+		return "", err
+	}
+	// Named after a hash of the root, as socket paths are short.
+	sum := sha256.Sum256([]byte(root))
+	return filepath.Join(os.TempDir(), fmt.Sprintf("baobab-%x.sock", sum[:8])), nil
+}
+
+// runDaemon scans once, then answers the queries of baobab query on a unix
+// socket until interrupted, scanning again whenever go files change, so
+// they don't wait for a scan each.
+func runDaemon(fs *flag.FlagSet, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments %q", args)
+	}
+	if flagRepo != "" {
+		return fmt.Errorf("cannot run a daemon on a -repo")
+	}
+	if flagGoList != "" {
+		return fmt.Errorf("cannot run a daemon on go list output")
+	}
+	path, err := socketPath()
+	if err != nil {
+		return err
+	}
+	if _, err := prepare(fs, ""); err != nil {
+		return err
+	}
+	start := time.Now()
+	if err := scanAll(); err != nil {
+		return err
+	}
+	s := &server{view: graph, health: newHealth(graph, time.Since(start)), output: func(g *Graph) *Graph { return g }}
+	l, err := listenSocket(path)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/query", s.handleQuery)
+	mux.HandleFunc("POST /baobab.v1.Baobab/{method}", s.handleGRPC)
+	// gRPC clients speak HTTP/2 without TLS.
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	srv := &http.Server{Handler: mux, Protocols: &protocols}
+	go func() {
+		<-ctx.Done()
+		srv.Shutdown(context.Background())
+	}()
+	go s.watch(ctx)
+	logger.Info("listening", "socket", path)
+	if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// listenSocket listens on the unix socket path, removing it first if left
+// behind by a daemon which did not stop cleanly, but not if one answers.
+func listenSocket(path string) (net.Listener, error) {
+	if _, err := os.Stat(path); err == nil {
+		if c, err := net.Dial("unix", path); err == nil {
+			c.Close()
+			return nil, fmt.Errorf("a daemon already listens on %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %s", err)
+		}
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %s", err)
+	}
+	return l, nil
+}
+
+// watch scans again whenever go files change, until ctx is done.
+func (s *server) watch(ctx context.Context) {
+	w, err := newWatcher()
+	if err != nil {
+		warn("watch", "", "failed to look for changed files", err)
+		return
+	}
+	defer w.close()
+	for {
+		changed, err := w.next(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				warn("watch", "", "failed to look for changed files", err)
+			}
+			return
+		}
+		logger.Info("files changed", "dirs", changed)
+		if err := s.rescan(ctx); err != nil {
+			warn("watch", "", "failed to scan", err)
+		}
+	}
+}
+
+// runQuery asks the daemon of the project a query of baobab repl, printing
+// its answer.
+func runQuery(fs *flag.FlagSet, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("want a query, like path cmd/app pkg/log, see help")
+	}
+	p, err := newPainter()
+	if err != nil {
+		return err
+	}
+	path, err := socketPath()
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+	q := url.Values{"q": {strings.Join(args, " ")}}
+	if p {
+		q.Set("color", "1")
+	}
+	resp, err := client.Get("http://baobab/api/query?" + q.Encode())
+	if ue, ok := err.(*url.Error); ok {
+		err = ue.Err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to reach the daemon on %s, start one with baobab daemon: %s", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return errors.New(strings.TrimSpace(string(msg)))
+	}
+	return writeOutput(func(w io.Writer) error {
+		_, err := io.Copy(w, resp.Body)
+		return err
+	})
+}
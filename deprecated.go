@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var flagForbidDeprecated bool
+
+// deprecatedUse is a use of a deprecated name of another package.
+type deprecatedUse struct {
+	from, to string // packages
+	name     string
+	message  string // what the deprecation notice says
+	file     string
+	line     int
+}
+
+var (
+	// deprecatedPkgs are the deprecation notices of the packages scanned
+	// marked deprecated, read by readDeprecations.
+	deprecatedPkgs map[string]string
+	// deprecatedUses are the uses of deprecated names by packages scanned
+	// not deprecated themselves, the first of each name by each package.
+	deprecatedUses []deprecatedUse
+)
+
+// readDeprecations parses the files of the packages of g scanned, tests and
+// skipped files aside, for the packages and package-level names with a
+// "Deprecated:" paragraph in their doc comment, and the uses of those.
+func readDeprecations(g *Graph) error {
+	type parsed struct {
+		dir  string
+		file *ast.File
+	}
+	var (
+		files []parsed
+		names = map[string]map[string]string{} // notices by name by package
+		pkgs  = map[string]string{}            // package names by directory
+		fset  = token.NewFileSet()
+	)
+	deprecatedPkgs, deprecatedUses = map[string]string{}, nil
+	for _, file := range scanner.Files() {
+		if file.Skipped != "" || strings.HasSuffix(file.Path, "_test.go") || g.Node(file.Dir) == nil {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file.Path, nil, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %s", file.Path, err)
+		}
+		files = append(files, parsed{file.Dir, f})
+		pkgs[file.Dir] = f.Name.Name
+		if notice, ok := deprecation(f.Doc); ok {
+			deprecatedPkgs[file.Dir] = notice
+		}
+		if names[file.Dir] == nil {
+			names[file.Dir] = map[string]string{}
+		}
+		mark := func(name *ast.Ident, docs ...*ast.CommentGroup) {
+			for _, doc := range docs {
+				if notice, ok := deprecation(doc); ok && name.IsExported() {
+					names[file.Dir][name.Name] = notice
+					return
+				}
+			}
+		}
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv == nil {
+					mark(decl.Name, decl.Doc)
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						mark(spec.Name, spec.Doc, decl.Doc)
+					case *ast.ValueSpec:
+						for _, name := range spec.Names {
+							mark(name, spec.Doc, decl.Doc)
+						}
+					}
+				}
+			}
+		}
+	}
+	byPath := map[string]string{}
+	for _, name := range g.Nodes() {
+		if n := g.Node(name); n.ImportPath != "" {
+			byPath[n.ImportPath] = name
+		}
+	}
+	seen := map[deprecatedUse]bool{}
+	for _, pf := range files {
+		if _, ok := deprecatedPkgs[pf.dir]; ok {
+			continue
+		}
+		local := map[string]string{} // package of each import name
+		for _, imp := range pf.file.Imports {
+			path, err := strconv.Unquote(imp.Path.Value)
+			if err != nil {
+				continue
+			}
+			dir := byPath[path]
+			if dir == "" || dir == pf.dir || names[dir] == nil {
+				continue
+			}
+			name := pkgs[dir]
+			if imp.Name != nil {
+				name = imp.Name.Name
+			}
+			local[name] = dir
+		}
+		ast.Inspect(pf.file, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			x, ok := sel.X.(*ast.Ident)
+			if !ok || local[x.Name] == "" {
+				return true
+			}
+			to := local[x.Name]
+			notice, ok := names[to][sel.Sel.Name]
+			key := deprecatedUse{from: pf.dir, to: to, name: sel.Sel.Name}
+			if !ok || seen[key] {
+				return true
+			}
+			seen[key] = true
+			pos := fset.Position(sel.Sel.Pos())
+			key.message, key.file, key.line = notice, pos.Filename, pos.Line
+			deprecatedUses = append(deprecatedUses, key)
+			return true
+		})
+	}
+	sort.SliceStable(deprecatedUses, func(i, j int) bool {
+		a, b := deprecatedUses[i], deprecatedUses[j]
+		if a.file != b.file {
+			return a.file < b.file
+		}
+		return a.line < b.line
+	})
+	return nil
+}
+
+// deprecation returns what the "Deprecated:" paragraph of doc says, ok
+// false if it has none.
+func deprecation(doc *ast.CommentGroup) (notice string, ok bool) {
+	if doc == nil {
+		return "", false
+	}
+	for _, para := range strings.Split(doc.Text(), "\n\n") {
+		if rest, found := strings.CutPrefix(para, "Deprecated:"); found {
+			return strings.Join(strings.Fields(rest), " "), true
+		}
+	}
+	return "", false
+}
+
+// checkDeprecated reports the imports of deprecated packages and the uses
+// of deprecated names of other packages, from packages not deprecated.
+func checkDeprecated(g *Graph) []Violation {
+	var result []Violation
+	for _, e := range g.Edges() {
+		notice, ok := deprecatedPkgs[e.To]
+		if _, dead := deprecatedPkgs[e.From]; !ok || dead {
+			continue
+		}
+		file, line := importPos(g, e)
+		result = append(result, Violation{
+			Rule:     "deprecated",
+			File:     file,
+			Line:     line,
+			Message:  fmt.Sprintf("%s imports deprecated %s: %s", e.From, e.To, notice),
+			Packages: []string{e.From, e.To},
+		})
+	}
+	for _, use := range deprecatedUses {
+		if g.Node(use.from) == nil {
+			continue
+		}
+		result = append(result, Violation{
+			Rule:     "deprecated",
+			File:     use.file,
+			Line:     use.line,
+			Message:  fmt.Sprintf("%s uses deprecated %s.%s: %s", use.from, use.to, use.name, use.message),
+			Packages: []string{use.from, use.to},
+		})
+	}
+	return result
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var flagDiagnostics string
+
+// Diagnostic is a problem met by a command, written to -diagnostics for
+// wrapper tools.
+type Diagnostic struct {
+	Level   string `json:"level"` // warning or error
+	Kind    string `json:"kind"`  // what went wrong, like parse-error or missing-dir
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+var (
+	diagnosticsMu sync.Mutex
+	diagnostics   = []Diagnostic{}
+)
+
+// warn logs msg as a warning of kind about path, if any, caused by err, if
+// any, and records it for -diagnostics.
+func warn(kind, path, msg string, err error) {
+	var args []any
+	d := Diagnostic{Level: "warning", Kind: kind, Path: path, Message: msg}
+	if path != "" {
+		args = append(args, "path", path)
+	}
+	if err != nil {
+		args = append(args, "err", err)
+		d.Message += ": " + err.Error()
+	}
+	logger.Warn(msg, args...)
+	diagnosticsMu.Lock()
+	diagnostics = append(diagnostics, d)
+	diagnosticsMu.Unlock()
+}
+
+// writeDiagnostics writes the diagnostics recorded, and err, the error the
+// command failed with if not nil, to -diagnostics as a JSON document.
+func writeDiagnostics(err error) {
+	if flagDiagnostics == "" {
+		return
+	}
+	diagnosticsMu.Lock()
+	defer diagnosticsMu.Unlock()
+	doc := struct {
+		Diagnostics []Diagnostic `json:"diagnostics"`
+	}{diagnostics}
+	if err != nil {
+		doc.Diagnostics = append(doc.Diagnostics, Diagnostic{Level: "error", Kind: "error", Message: err.Error()})
+	}
+	data, merr := json.MarshalIndent(doc, "", "  ")
+	if merr == nil {
+		merr = os.WriteFile(flagDiagnostics, append(data, '\n'), 0o644)
+	}
+	if merr != nil {
+		logger.Error("failed to write diagnostics", "err", merr)
+	}
+}
+
+// setupDiagnostics makes -diagnostics absolute, as scans change directory.
+func setupDiagnostics() {
+	if flagDiagnostics == "" {
+		return
+	}
+	if abs, err := filepath.Abs(flagDiagnostics); err == nil {
+		flagDiagnostics = abs
+	}
+}
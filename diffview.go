@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+var flagRender string
+
+// diffFlags defines the flags of the diff command.
+func diffFlags(fs *flag.FlagSet) {
+	fs.StringVar(&flagRender, "render", "text", "how to write the differences: text, a line per change, dot, the graphs overlaid in graphviz code, or html, a page drawing them like -format bundle")
+	themeFlags(fs)
+}
+
+// diffView is two graphs overlaid, each node and edge marked with how it
+// changed from the old one to the new one: added, removed, changed, for
+// edges of another kind, or empty.
+type diffView struct {
+	Nodes []diffNode `json:"nodes"`
+	Edges []diffEdge `json:"edges"`
+}
+
+type diffNode struct {
+	Name   string `json:"name"`
+	Label  string `json:"label,omitempty"`
+	Change string `json:"change,omitempty"`
+}
+
+type diffEdge struct {
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Kind     string   `json:"kind"`
+	Was      string   `json:"was,omitempty"` // kind in the old graph, if changed
+	Change   string   `json:"change,omitempty"`
+	Evidence []string `json:"evidence,omitempty"`
+}
+
+// overlay returns old and new overlaid, sorted.
+func overlay(old, new jsonGraph) diffView {
+	var v diffView
+	oldNodes := map[string]bool{}
+	for _, n := range old.Nodes {
+		oldNodes[n.Name] = true
+	}
+	newNodes := map[string]bool{}
+	for _, n := range new.Nodes {
+		newNodes[n.Name] = true
+		dn := diffNode{Name: n.Name, Label: n.Label}
+		if !oldNodes[n.Name] {
+			dn.Change = "added"
+		}
+		v.Nodes = append(v.Nodes, dn)
+	}
+	for _, n := range old.Nodes {
+		if !newNodes[n.Name] {
+			v.Nodes = append(v.Nodes, diffNode{Name: n.Name, Label: n.Label, Change: "removed"})
+		}
+	}
+	oldEdges := map[[2]string]string{}
+	for _, e := range old.Edges {
+		oldEdges[[2]string{e.From, e.To}] = e.Kind
+	}
+	newEdges := map[[2]string]bool{}
+	for _, e := range new.Edges {
+		key := [2]string{e.From, e.To}
+		newEdges[key] = true
+		de := diffEdge{From: e.From, To: e.To, Kind: e.Kind, Evidence: e.Evidence}
+		switch kind, ok := oldEdges[key]; {
+		case !ok:
+			de.Change = "added"
+		case kind != e.Kind:
+			de.Change, de.Was = "changed", kind
+		}
+		v.Edges = append(v.Edges, de)
+	}
+	for _, e := range old.Edges {
+		if !newEdges[[2]string{e.From, e.To}] {
+			v.Edges = append(v.Edges, diffEdge{From: e.From, To: e.To, Kind: e.Kind, Change: "removed", Evidence: e.Evidence})
+		}
+	}
+	sort.Slice(v.Nodes, func(i, j int) bool { return v.Nodes[i].Name < v.Nodes[j].Name })
+	sort.Slice(v.Edges, func(i, j int) bool {
+		if v.Edges[i].From != v.Edges[j].From {
+			return v.Edges[i].From < v.Edges[j].From
+		}
+		return v.Edges[i].To < v.Edges[j].To
+	})
+	return v
+}
+
+// writeDiffDOT writes v as graphviz code: what was added in green, what
+// was removed in red and dashed, imports of another kind in orange and the
+// rest in gray.
+func writeDiffDOT(w io.Writer, v diffView) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph G {")
+	fmt.Fprint(bw, dotThemeAttrs())
+	fmt.Fprintf(bw, "edge [color=%q]\n", palette.muted)
+	for _, n := range v.Nodes {
+		var attrs []string
+		if n.Label != "" {
+			attrs = append(attrs, fmt.Sprintf("label=%q", n.Label))
+		}
+		switch n.Change {
+		case "added":
+			attrs = append(attrs, fmt.Sprintf("color=%q, fontcolor=%q, penwidth=2", palette.good, palette.good))
+		case "removed":
+			attrs = append(attrs, fmt.Sprintf("color=%q, fontcolor=%q, style=dashed", palette.bad, palette.bad))
+		}
+		if len(attrs) > 0 {
+			fmt.Fprintf(bw, "%s [%s]\n", dotID(n.Name), strings.Join(attrs, ", "))
+		} else {
+			fmt.Fprintln(bw, dotID(n.Name))
+		}
+	}
+	for _, e := range v.Edges {
+		switch e.Change {
+		case "added":
+			fmt.Fprintf(bw, "%s -> %s [color=%q, penwidth=2]\n", dotID(e.From), dotID(e.To), palette.good)
+		case "removed":
+			fmt.Fprintf(bw, "%s -> %s [color=%q, style=dashed]\n", dotID(e.From), dotID(e.To), palette.bad)
+		case "changed":
+			fmt.Fprintf(bw, "%s -> %s [color=%q, label=%q]\n", dotID(e.From), dotID(e.To), palette.warn, e.Was+" -> "+e.Kind)
+		default:
+			fmt.Fprintf(bw, "%s -> %s\n", dotID(e.From), dotID(e.To))
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// writeDiffHTML writes v as a page drawing it like -format bundle, the
+// changes colored as by writeDiffDOT.
+func writeDiffHTML(w io.Writer, v diffView) error {
+	doc, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(themeHTML(bytes.Replace(bundleHTML, []byte("/*GRAPH*/null"), doc, 1)))
+	return err
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// writeDOT writes g as graphviz code.
+func writeDOT(w io.Writer, g *Graph) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph G {")
+	fmt.Fprint(bw, dotThemeAttrs())
+	sizes, churn := nodeSizes(g), dotChurnAttrs(g)
+	layers, err := nodeLayers(g)
+	if err != nil {
+		return err
+	}
+	nodeAttrs := func(n *Node) string {
+		var attrs []string
+		for _, a := range []string{dotNodeAttrs(n), sizes[n.Name], dotCoverageAttrs(n), layers.nodeAttrs(n.Name, n), churn[n.Name], dotVulnAttrs(n)} {
+			if a != "" {
+				attrs = append(attrs, a)
+			}
+		}
+		return strings.Join(attrs, ", ")
+	}
+	clusters := map[string][]string{}
+	for _, name := range g.Nodes() {
+		n := g.Node(name)
+		if n.Group != "" {
+			clusters[n.Group] = append(clusters[n.Group], name)
+			continue
+		}
+		if attrs := nodeAttrs(n); attrs != "" {
+			fmt.Fprintf(bw, "%s [%s]\n", dotID(name), attrs)
+		}
+	}
+	groups := make([]string, 0, len(clusters))
+	for group := range clusters {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		fmt.Fprintf(bw, "subgraph %s {\nlabel=%q\n", dotID("cluster_"+group), group)
+		fmt.Fprint(bw, layers.clusterAttrs(group, clusters[group]))
+		for _, name := range clusters[group] {
+			if attrs := nodeAttrs(g.Node(name)); attrs != "" {
+				fmt.Fprintf(bw, "%s [%s]\n", dotID(name), attrs)
+			} else {
+				fmt.Fprintln(bw, dotID(name))
+			}
+		}
+		fmt.Fprintln(bw, "}")
+	}
+	for _, e := range g.Edges() {
+		// Of the colors the edge could get, the later ones take precedence:
+		// problems over the target over the kind of import.
+		var (
+			attrs, styles []string
+			color         string
+		)
+		style := func(st string) {
+			if !slices.Contains(styles, st) {
+				styles = append(styles, st)
+			}
+		}
+		kinds := []string{e.Kind()}
+		if e.Test && (e.Dot || e.Blank) {
+			kinds = append(kinds, "test")
+		}
+		if g.Node(e.To).External {
+			kinds = append(kinds, "external")
+		}
+		for _, kind := range kinds {
+			c, st := palette.edgeKind(kind)
+			if c != "" {
+				color = c
+			}
+			if st != "" {
+				style(st)
+			}
+		}
+		if to := g.Node(e.To); g.Node(e.From).Module != to.Module && !to.External && !to.Std && !to.Marker && !to.Proto {
+			color = palette.crossModule
+			style("bold")
+		}
+		if g.Node(e.To).Proto {
+			color = palette.proto
+		}
+		if g.Node(e.To).Interface {
+			// Implements, as drawn in UML.
+			attrs = append(attrs, "arrowhead=empty")
+			style("dashed")
+		}
+		switch {
+		case e.Dot:
+			attrs = append(attrs, "arrowhead=dot")
+		case e.Blank:
+			attrs = append(attrs, "arrowhead=odot")
+		}
+		if layers.breaks(e) || e.Vulnerable {
+			color = palette.bad
+			style("bold")
+		}
+		if color != "" {
+			attrs = append(attrs, "color="+strconv.Quote(color))
+		}
+		if e.Weight > 1 {
+			attrs = append(attrs, fmt.Sprintf("label=%d, penwidth=%.1f", e.Weight, 1+math.Log2(float64(e.Weight))))
+		}
+		if len(styles) > 0 {
+			attrs = append(attrs, fmt.Sprintf("style=%q", strings.Join(styles, ",")))
+		}
+		if evidence := importPositions(g, e); len(evidence) > 0 {
+			attrs = append(attrs, fmt.Sprintf("tooltip=%q", strings.Join(evidence, "\n")))
+		}
+		if len(attrs) > 0 {
+			fmt.Fprintf(bw, "%s -> %s [%s]\n", dotID(e.From), dotID(e.To), strings.Join(attrs, ", "))
+			continue
+		}
+		fmt.Fprintf(bw, "%s -> %s\n", dotID(e.From), dotID(e.To))
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// dotNodeAttrs returns the attributes of n in graphviz code, empty for
+// plain packages.
+func dotNodeAttrs(n *Node) string {
+	switch {
+	case n.External:
+		return fmt.Sprintf("label=%q, shape=box, style=dashed", nodeLabel(n))
+	case n.Std:
+		return fmt.Sprintf("label=%q, shape=box, color=%q, fontcolor=%q", nodeLabel(n), palette.muted, palette.muted)
+	case n.Marker:
+		return fmt.Sprintf("label=%q, shape=octagon, style=filled, fillcolor=%q", nodeLabel(n), palette.marker)
+	case n.Proto:
+		return fmt.Sprintf("label=%q, shape=note, color=%q", nodeLabel(n), palette.proto)
+	case n.Interface:
+		return fmt.Sprintf("label=%q, shape=ellipse, color=%q, fontcolor=%q", nodeLabel(n), palette.iface, palette.iface)
+	case n.Packages > 1:
+		return fmt.Sprintf("label=%q", fmt.Sprintf("%s (%d)", nodeLabel(n), n.Packages))
+	case n.Label != "" || n.Canonical != "":
+		return fmt.Sprintf("label=%q", nodeLabel(n))
+	}
+	return ""
+}
+
+// dotID turns a directory into a valid graphviz identifier.
+func dotID(dir string) string {
+	dir = strings.ReplaceAll(dir, string(os.PathSeparator), "_")
+	dir = strings.ReplaceAll(dir, "/", "_")
+	dir = strings.ReplaceAll(dir, "-", "_")
+	dir = strings.ReplaceAll(dir, ".", "_")
+	for _, r := range dir {
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			// Like the functions of baobab calls, (*T).M.
+			return strconv.Quote(dir)
+		}
+	}
+	return dir
+}
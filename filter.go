@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var (
+	flagFilterNode string
+	flagFilterEdge string
+)
+
+// outputFilter returns the function dropping the nodes matching -filter-node
+// and the edges matching -filter-edge from a graph about to be written, nil
+// if neither is given. Edges are matched as "FROM -> TO".
+func outputFilter() (func(*Graph) *Graph, error) {
+	if flagFilterNode == "" && flagFilterEdge == "" {
+		return nil, nil
+	}
+	var nodeRe, edgeRe *regexp.Regexp
+	for _, f := range []struct {
+		re   **regexp.Regexp
+		name string
+		expr string
+	}{{&nodeRe, "-filter-node", flagFilterNode}, {&edgeRe, "-filter-edge", flagFilterEdge}} {
+		if f.expr == "" {
+			continue
+		}
+		re, err := regexp.Compile(f.expr)
+		if err != nil {
+			return nil, fmt.Errorf("bad %s: %s", f.name, err)
+		}
+		*f.re = re
+	}
+	keepNode := func(n *Node) bool {
+		return nodeRe == nil || !nodeRe.MatchString(nodePath(n.Name))
+	}
+	keepEdge := func(e *Edge) bool {
+		return edgeRe == nil || !edgeRe.MatchString(nodePath(e.From)+" -> "+nodePath(e.To))
+	}
+	return func(g *Graph) *Graph { return g.Filter(keepNode, keepEdge) }, nil
+}
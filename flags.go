@@ -0,0 +1,22 @@
+package main
+
+import (
+	"strings"
+)
+
+// listFlag is a flag that can be given more than once, each time with one
+// or more comma-separated values.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+// Package graph holds the import graph of go packages baobab builds, and
+// ways to walk it.
+package graph
+
+import (
+	"sort"
+	"strings"
+)
+
+// Graph is a directed graph of package directories, an edge from a to b
+// meaning a imports b.
+type Graph struct {
+	nodes map[string]*Node
+	out   map[string]map[string]*Edge
+	in    map[string]map[string]*Edge
+}
+
+// The nodes of packages outside the scanned modules, and markers, are named
+// by their import path, module path or marker name behind the prefix of
+// their kind, which no directory starts with, so a directory named like a
+// standard package, log say, keeps a node of its own.
+const (
+	StdPrefix      = "std:"
+	ExternalPrefix = "external:"
+	MarkerPrefix   = "marker:"
+)
+
+// Outside returns the import path, module path or marker name node name
+// stands for, and whether it is one of those rather than a directory.
+func Outside(name string) (string, bool) {
+	for _, prefix := range []string{StdPrefix, ExternalPrefix, MarkerPrefix} {
+		if rest, ok := strings.CutPrefix(name, prefix); ok {
+			return rest, true
+		}
+	}
+	return name, false
+}
+
+// Node is a package in the graph, named by its directory.
+type Node struct {
+	Name       string
+	ImportPath string   // import path, the name of packages outside the scanned modules
+	Module     string   // path of the module the package belongs to
+	External   bool     // not part of the scanned modules, named by ExternalPrefix and import path
+	Std        bool     // standard library, or a node standing for part or all of it, named by StdPrefix and import path
+	Cgo        bool     // has files importing "C"
+	Marker     bool     // not a package but a marker, like the cgo node, named by MarkerPrefix
+	Proto      bool     // not a package but the .proto files of a directory
+	Interface  bool     // not a package but an interface type, in graphs of types
+	Canonical  string   // canonical import path, from an import comment
+	Label      string   // shown instead of the name, if set
+	Packages   int      // number of packages merged into this one, if any
+	Group      string   // group the package is drawn in, if any
+	Owners     []string // owners of the package per CODEOWNERS, if read
+	Files      int      // go files of the package, tests aside
+	TestFiles  int      // _test.go files of the package
+	Lines      int      // lines of the go files of the package, tests aside
+	Statements int      // statements of the package in a coverage profile, if any
+	Covered    int      // of which covered by tests
+	Commits    int      // recent commits changing its go files, if counted
+	Vulns      []string // vulnerabilities it is on a call path to, if checked
+	Imports    []Import
+}
+
+// Edge is an import of one package by another.
+type Edge struct {
+	From  string
+	To    string
+	Test  bool // only imported by _test.go files
+	Blank bool // imported for side effects by some file, as in _ "pkg"
+	Dot   bool // imported into the file block by some file, as in . "pkg"
+
+	// Vulnerable is set if the import is on a call path to a vulnerable
+	// function, if checked.
+	Vulnerable bool
+
+	// Weight is the number of imports merged into this one, if any.
+	Weight int
+}
+
+// Import is an import declaration found in a go file.
+type Import struct {
+	File string // file declaring the import
+	Line int    // line of the import path in File, 0 if unknown
+	Name string // "_" for blank, "." for dot imports, the alias or empty otherwise
+	Path string
+	Test bool   // File is a _test.go file
+	Dir  string // directory of the imported package if in the scanned modules
+	Node string // std, external or marker node standing for the package otherwise, if drawn
+}
+
+// New creates and returns an empty graph.
+func New() *Graph {
+	return &Graph{
+		nodes: map[string]*Node{},
+		out:   map[string]map[string]*Edge{},
+		in:    map[string]map[string]*Edge{},
+	}
+}
+
+// AddNode adds node n to the graph, if not already present, and returns it.
+func (g *Graph) AddNode(n string) *Node {
+	node, ok := g.nodes[n]
+	if !ok {
+		node = &Node{Name: n}
+		g.nodes[n] = node
+	}
+	return node
+}
+
+// Node returns node n, nil if not in the graph.
+func (g *Graph) Node(n string) *Node {
+	return g.nodes[n]
+}
+
+// AddEdge adds an edge from -> to, adding both nodes if necessary, and
+// returns it.
+func (g *Graph) AddEdge(from, to string) *Edge {
+	g.AddNode(from)
+	g.AddNode(to)
+	if e, ok := g.out[from][to]; ok {
+		return e
+	}
+	e := &Edge{From: from, To: to}
+	if g.out[from] == nil {
+		g.out[from] = map[string]*Edge{}
+	}
+	g.out[from][to] = e
+	if g.in[to] == nil {
+		g.in[to] = map[string]*Edge{}
+	}
+	g.in[to][from] = e
+	return e
+}
+
+// Edge returns the edge from -> to, nil if not in the graph.
+func (g *Graph) Edge(from, to string) *Edge {
+	return g.out[from][to]
+}
+
+// RemoveOut removes the edges from n.
+func (g *Graph) RemoveOut(n string) {
+	for to := range g.out[n] {
+		delete(g.in[to], n)
+	}
+	delete(g.out, n)
+}
+
+// RemoveNode removes node n and its edges.
+func (g *Graph) RemoveNode(n string) {
+	g.RemoveOut(n)
+	for from := range g.in[n] {
+		delete(g.out[from], n)
+	}
+	delete(g.in, n)
+	delete(g.nodes, n)
+}
+
+// Filter returns a graph sharing the nodes and edges of g keepNode and
+// keepEdge accept, leaving out the edges of nodes left out.
+func (g *Graph) Filter(keepNode func(*Node) bool, keepEdge func(*Edge) bool) *Graph {
+	result := New()
+	for name, n := range g.nodes {
+		if keepNode(n) {
+			result.nodes[name] = n
+		}
+	}
+	for from, out := range g.out {
+		for to, e := range out {
+			if result.nodes[from] == nil || result.nodes[to] == nil || !keepEdge(e) {
+				continue
+			}
+			if result.out[from] == nil {
+				result.out[from] = map[string]*Edge{}
+			}
+			result.out[from][to] = e
+			if result.in[to] == nil {
+				result.in[to] = map[string]*Edge{}
+			}
+			result.in[to][from] = e
+		}
+	}
+	return result
+}
+
+// AddImport adds an edge like AddEdge for an import found in a file, the
+// edge stays marked test-only as long as all such files are tests.
+func (g *Graph) AddImport(from, to string, imp Import) *Edge {
+	e, ok := g.out[from][to]
+	if !ok {
+		e = g.AddEdge(from, to)
+		e.Test = imp.Test
+	} else if !imp.Test {
+		e.Test = false
+	}
+	switch imp.Name {
+	case "_":
+		e.Blank = true
+	case ".":
+		e.Dot = true
+	}
+	return e
+}
+
+// Kind returns the kind of dependency e stands for: "dot", "blank", "test"
+// or "normal".
+func (e *Edge) Kind() string {
+	switch {
+	case e.Dot:
+		return "dot"
+	case e.Blank:
+		return "blank"
+	case e.Test:
+		return "test"
+	}
+	return "normal"
+}
+
+// Nodes returns the names of all nodes in sorted order.
+func (g *Graph) Nodes() []string {
+	return sortedKeys(g.nodes)
+}
+
+// Succ returns the nodes n imports, in sorted order.
+func (g *Graph) Succ(n string) []string {
+	return sortedKeys(g.out[n])
+}
+
+// Pred returns the nodes importing n, in sorted order.
+func (g *Graph) Pred(n string) []string {
+	return sortedKeys(g.in[n])
+}
+
+// Edges returns all edges, sorted by from then to.
+func (g *Graph) Edges() []*Edge {
+	var result []*Edge
+	for _, from := range g.Nodes() {
+		for _, to := range g.Succ(from) {
+			result = append(result, g.out[from][to])
+		}
+	}
+	return result
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,20 @@
+package graph
+
+import "testing"
+
+func TestOutside(t *testing.T) {
+	for _, tc := range []struct {
+		name, path string
+		outside    bool
+	}{
+		{"log", "log", false},
+		{"pkg/std:x", "pkg/std:x", false},
+		{StdPrefix + "net/http", "net/http", true},
+		{ExternalPrefix + "github.com/a/b", "github.com/a/b", true},
+		{MarkerPrefix + "cgo", "cgo", true},
+	} {
+		if path, outside := Outside(tc.name); path != tc.path || outside != tc.outside {
+			t.Errorf("Outside(%q) = %q, %v, want %q, %v", tc.name, path, outside, tc.path, tc.outside)
+		}
+	}
+}
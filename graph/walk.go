@@ -0,0 +1,108 @@
+package graph
+
+import (
+	"sort"
+)
+
+// ShortestPath returns the nodes from from to to along the fewest imports,
+// nil if to is not reachable.
+func ShortestPath(g *Graph, from, to string) []string {
+	prev := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if n == to {
+			var path []string
+			for ; n != ""; n = prev[n] {
+				path = append([]string{n}, path...)
+			}
+			return path
+		}
+		for _, next := range g.Succ(n) {
+			if _, seen := prev[next]; !seen {
+				prev[next] = n
+				queue = append(queue, next)
+			}
+		}
+	}
+	return nil
+}
+
+// StronglyConnected returns the strongly connected components of g, only
+// following the edges keep accepts, with Tarjan's algorithm. Components are
+// sorted, and listed by their first node.
+func StronglyConnected(g *Graph, keep func(*Edge) bool) [][]string {
+	var (
+		index   = map[string]int{}
+		low     = map[string]int{}
+		onStack = map[string]bool{}
+		stack   []string
+		result  [][]string
+		visit   func(n string)
+	)
+	visit = func(n string) {
+		index[n] = len(index)
+		low[n] = index[n]
+		stack = append(stack, n)
+		onStack[n] = true
+		for _, next := range g.Succ(n) {
+			if !keep(g.Edge(n, next)) {
+				continue
+			}
+			if _, seen := index[next]; !seen {
+				visit(next)
+				low[n] = min(low[n], low[next])
+			} else if onStack[next] {
+				low[n] = min(low[n], index[next])
+			}
+		}
+		if low[n] != index[n] {
+			return
+		}
+		var scc []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			scc = append(scc, top)
+			if top == n {
+				break
+			}
+		}
+		sort.Strings(scc)
+		result = append(result, scc)
+	}
+	for _, n := range g.Nodes() {
+		if _, seen := index[n]; !seen {
+			visit(n)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i][0] < result[j][0] })
+	return result
+}
+
+// Reachable returns the nodes reachable from roots, roots included if in g.
+func Reachable(g *Graph, roots []string) map[string]bool {
+	var (
+		seen  = map[string]bool{}
+		queue []string
+	)
+	for _, root := range roots {
+		if g.Node(root) != nil && !seen[root] {
+			seen[root] = true
+			queue = append(queue, root)
+		}
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, next := range g.Succ(n) {
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return seen
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	graphs "github.com/sequix/baobab/graph"
+	"github.com/sequix/baobab/internal/glob"
+	"github.com/sequix/baobab/internal/graphql"
+)
+
+// graphqlRequest is the body of a POST to /graphql.
+type graphqlRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// gqlSchema is the schema of the graph served, for introspection.
+var gqlSchema = graphql.MustParseSchema(`
+type Query {
+  "Packages whose name matches the glob match, all without."
+  nodes(match: String): [Node!]!
+  "The package of a directory or import path, null if not in the graph."
+  node(name: String!): Node
+  "Imports, only those of kind if given."
+  edges(kind: String): [Edge!]!
+  "The shortest chain of imports from a package to another, empty if none."
+  path(from: String!, to: String!): [Node!]
+  "The health of the graph scanned, as /metrics serves it."
+  metrics: Metrics!
+}
+
+"A package, or a node standing for packages outside the scanned modules."
+type Node {
+  name: String!
+  importPath: String
+  module: String
+  external: Boolean!
+  std: Boolean!
+  cgo: Boolean!
+  marker: Boolean!
+  proto: Boolean!
+  canonical: String
+  label: String!
+  packages: Int!
+  group: String
+  owners: [String!]!
+  vulns: [String!]!
+  files: Int!
+  testFiles: Int!
+  lines: Int!
+  commits: Int!
+  coverage: Float
+  "What the package imports, directly, or at all if transitive."
+  imports(transitive: Boolean = false): [Node!]!
+  "What imports the package, directly, or at all if transitive."
+  importers(transitive: Boolean = false): [Node!]!
+  importEdges: [Edge!]!
+  importerEdges: [Edge!]!
+  pageRank: Float!
+  betweenness: Float!
+}
+
+"An import of a package by another."
+type Edge {
+  from: Node!
+  to: Node!
+  "normal, test, blank or dot."
+  kind: String!
+  weight: Int!
+  vulnerable: Boolean!
+}
+
+type Metrics {
+  packages: Int!
+  edges: Int!
+  cycles: Int!
+  maxDepth: Int!
+  violations: [RuleCount!]!
+  "When the last scan ended, in RFC 3339."
+  scanned: String!
+  scanSeconds: Float!
+}
+
+type RuleCount {
+  rule: String!
+  count: Int!
+}
+`)
+
+// handleGraphQL answers GraphQL queries about the graph served, per
+// gqlSchema, given in a POST body or in the URL of a GET.
+func (s *server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req graphqlRequest
+	if r.Method == http.MethodGet {
+		q := r.URL.Query()
+		req.Query, req.OperationName = q.Get("query"), q.Get("operationName")
+		if vars := q.Get("variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				graphqlError(w, fmt.Sprintf("failed to decode variables: %s", err))
+				return
+			}
+		}
+	} else if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+		graphqlError(w, fmt.Sprintf("failed to decode request: %s", err))
+		return
+	}
+	doc, err := graphql.Parse(req.Query)
+	if err != nil {
+		graphqlError(w, err.Error())
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	resp, err := graphql.Execute(doc, req.OperationName, req.Variables, gqlSchema, &gqlQuery{s: s})
+	if err != nil {
+		graphqlError(w, err.Error())
+		return
+	}
+	serveJSON(w, resp)
+}
+
+// graphqlError answers a request failing as a whole.
+func graphqlError(w http.ResponseWriter, msg string) {
+	w.WriteHeader(http.StatusBadRequest)
+	serveJSON(w, graphql.Response{Errors: []graphql.Error{{Message: msg}}})
+}
+
+// gqlQuery is the root of GraphQL queries, holding what the fields of a
+// query share.
+type gqlQuery struct {
+	s     *server
+	ranks map[string][2]float64 // page rank and betweenness, computed once
+}
+
+func (q *gqlQuery) TypeName() string { return "Query" }
+
+func (q *gqlQuery) Resolve(field string, args map[string]any) (any, error) {
+	g := q.s.view
+	switch field {
+	case "nodes":
+		match, _ := args["match"].(string)
+		var result []graphql.Object
+		for _, name := range g.Nodes() {
+			if match == "" || glob.Match(match, name) {
+				result = append(result, q.node(name))
+			}
+		}
+		return result, nil
+	case "node":
+		name, err := q.nodeArg(args, "name")
+		if err != nil || name == "" {
+			return nil, err
+		}
+		return q.node(name), nil
+	case "edges":
+		kind, _ := args["kind"].(string)
+		var result []graphql.Object
+		for _, e := range g.Edges() {
+			if kind == "" || e.Kind() == kind {
+				result = append(result, &gqlEdge{q, e})
+			}
+		}
+		return result, nil
+	case "path":
+		from, err := q.nodeArg(args, "from")
+		if err != nil {
+			return nil, err
+		}
+		to, err := q.nodeArg(args, "to")
+		if err != nil {
+			return nil, err
+		}
+		if from == "" || to == "" {
+			return nil, nil
+		}
+		var result []graphql.Object
+		for _, name := range graphs.ShortestPath(g, from, to) {
+			result = append(result, q.node(name))
+		}
+		return result, nil
+	case "metrics":
+		return &gqlMetrics{q.s.health}, nil
+	}
+	return nil, fmt.Errorf("unknown field %s on Query", field)
+}
+
+// nodeArg returns the package argument arg names in the graph served, by
+// directory or import path, empty if not in it.
+func (q *gqlQuery) nodeArg(args map[string]any, arg string) (string, error) {
+	name, ok := args[arg].(string)
+	if !ok {
+		return "", fmt.Errorf("missing argument %s", arg)
+	}
+	for _, n := range []string{name, scanner.Resolve(name)} {
+		if q.s.view.Node(n) != nil {
+			return n, nil
+		}
+	}
+	return "", nil
+}
+
+func (q *gqlQuery) node(name string) *gqlNode {
+	return &gqlNode{q, q.s.view.Node(name)}
+}
+
+// rank returns the page rank and betweenness of the package name.
+func (q *gqlQuery) rank(name string) [2]float64 {
+	if q.ranks == nil {
+		q.ranks = map[string][2]float64{}
+		pr, bc := PageRank(q.s.view), Betweenness(q.s.view)
+		for _, n := range q.s.view.Nodes() {
+			q.ranks[n] = [2]float64{pr[n], bc[n]}
+		}
+	}
+	return q.ranks[name]
+}
+
+// gqlNode is a package of the graph served.
+type gqlNode struct {
+	q *gqlQuery
+	n *Node
+}
+
+func (n *gqlNode) TypeName() string { return "Node" }
+
+func (n *gqlNode) Resolve(field string, args map[string]any) (any, error) {
+	switch field {
+	case "name":
+		return n.n.Name, nil
+	case "importPath":
+		return null(n.n.ImportPath), nil
+	case "module":
+		return null(n.n.Module), nil
+	case "external":
+		return n.n.External, nil
+	case "std":
+		return n.n.Std, nil
+	case "cgo":
+		return n.n.Cgo, nil
+	case "marker":
+		return n.n.Marker, nil
+	case "proto":
+		return n.n.Proto, nil
+	case "canonical":
+		return null(n.n.Canonical), nil
+	case "label":
+		return nodeLabel(n.n), nil
+	case "packages":
+		return n.n.Packages, nil
+	case "group":
+		return null(n.n.Group), nil
+	case "owners", "vulns":
+		values := n.n.Owners
+		if field == "vulns" {
+			values = n.n.Vulns
+		}
+		result := []any{}
+		for _, v := range values {
+			result = append(result, v)
+		}
+		return result, nil
+	case "files":
+		return n.n.Files, nil
+	case "testFiles":
+		return n.n.TestFiles, nil
+	case "lines":
+		return n.n.Lines, nil
+	case "commits":
+		return n.n.Commits, nil
+	case "coverage":
+		if c, ok := coverage(n.n); ok {
+			return c, nil
+		}
+		return nil, nil
+	case "imports", "importers":
+		next := n.q.s.view.Succ
+		if field == "importers" {
+			next = n.q.s.view.Pred
+		}
+		transitive, _ := args["transitive"].(bool)
+		var result []graphql.Object
+		for _, name := range neighbors(n.n.Name, next, transitive) {
+			result = append(result, n.q.node(name))
+		}
+		return result, nil
+	case "importEdges", "importerEdges":
+		var result []graphql.Object
+		for _, e := range n.q.s.view.Edges() {
+			if field == "importEdges" && e.From == n.n.Name || field == "importerEdges" && e.To == n.n.Name {
+				result = append(result, &gqlEdge{n.q, e})
+			}
+		}
+		return result, nil
+	case "pageRank":
+		return n.q.rank(n.n.Name)[0], nil
+	case "betweenness":
+		return n.q.rank(n.n.Name)[1], nil
+	}
+	return nil, fmt.Errorf("unknown field %s on Node", field)
+}
+
+// neighbors returns the packages next returns for pkg, and those it returns
+// for them in turn if transitive, in the order found.
+func neighbors(pkg string, next func(string) []string, transitive bool) []string {
+	var result []string
+	seen := map[string]bool{pkg: true}
+	queue := []string{pkg}
+	for len(queue) > 0 {
+		for _, n := range next(queue[0]) {
+			if !seen[n] {
+				seen[n] = true
+				result = append(result, n)
+				if transitive {
+					queue = append(queue, n)
+				}
+			}
+		}
+		queue = queue[1:]
+	}
+	return result
+}
+
+// gqlEdge is an import of the graph served.
+type gqlEdge struct {
+	q *gqlQuery
+	e *Edge
+}
+
+func (e *gqlEdge) TypeName() string { return "Edge" }
+
+func (e *gqlEdge) Resolve(field string, args map[string]any) (any, error) {
+	switch field {
+	case "from":
+		return e.q.node(e.e.From), nil
+	case "to":
+		return e.q.node(e.e.To), nil
+	case "kind":
+		return e.e.Kind(), nil
+	case "weight":
+		return max(e.e.Weight, 1), nil
+	case "vulnerable":
+		return e.e.Vulnerable, nil
+	}
+	return nil, fmt.Errorf("unknown field %s on Edge", field)
+}
+
+// gqlMetrics is the health of the graph scanned, as /metrics serves it.
+type gqlMetrics struct {
+	h health
+}
+
+func (m *gqlMetrics) TypeName() string { return "Metrics" }
+
+func (m *gqlMetrics) Resolve(field string, args map[string]any) (any, error) {
+	switch field {
+	case "packages":
+		return m.h.packages, nil
+	case "edges":
+		return m.h.edges, nil
+	case "cycles":
+		return m.h.cycles, nil
+	case "maxDepth":
+		return m.h.depth, nil
+	case "violations":
+		rules := make([]string, 0, len(m.h.violations))
+		for rule := range m.h.violations {
+			rules = append(rules, rule)
+		}
+		sort.Strings(rules)
+		var result []graphql.Object
+		for _, rule := range rules {
+			result = append(result, &gqlRuleCount{rule, m.h.violations[rule]})
+		}
+		return result, nil
+	case "scanned":
+		return m.h.scanned.UTC().Format(time.RFC3339), nil
+	case "scanSeconds":
+		return m.h.duration.Seconds(), nil
+	}
+	return nil, fmt.Errorf("unknown field %s on Metrics", field)
+}
+
+// gqlRuleCount is the number of violations of a rule.
+type gqlRuleCount struct {
+	rule  string
+	count int
+}
+
+func (c *gqlRuleCount) TypeName() string { return "RuleCount" }
+
+func (c *gqlRuleCount) Resolve(field string, args map[string]any) (any, error) {
+	switch field {
+	case "rule":
+		return c.rule, nil
+	case "count":
+		return c.count, nil
+	}
+	return nil, fmt.Errorf("unknown field %s on RuleCount", field)
+}
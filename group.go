@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/sequix/baobab/internal/glob"
+)
+
+var (
+	flagGroups   string
+	flagClusters bool
+)
+
+// group is a set of packages shown together, like a bounded context.
+type group struct {
+	name  string
+	globs []string
+}
+
+// parseGroups reads the groups in file, a YAML mapping of group names to
+// lists of globs of the directories in the group, as in:
+//
+//	payments: [pkg/payment/**, internal/billing/**]
+func parseGroups(file string) ([]group, error) {
+	values, err := parseConfig(file)
+	if err != nil {
+		return nil, err
+	}
+	var result []group
+	for _, v := range values {
+		if !v.list {
+			return nil, fmt.Errorf("%s:%d: want a list of globs for group %s", file, v.line, v.key)
+		}
+		result = append(result, group{v.key, v.values})
+	}
+	return result, nil
+}
+
+// groupOf returns the name of the first of groups dir belongs to, empty if
+// none.
+func groupOf(groups []group, dir string) string {
+	for _, gr := range groups {
+		if glob.MatchAny(gr.globs, dir) {
+			return gr.name
+		}
+	}
+	return ""
+}
+
+// applyGroups returns g with the packages of each group groupName names merged
+// into a node named after it or, if clusters, with the group of each package
+// set for the DOT output to draw them in clusters.
+func applyGroups(g *Graph, groupName func(*Node) string, clusters bool) *Graph {
+	of := func(n *Node) string {
+		if n.External || n.Std || n.Marker || n.Proto {
+			return ""
+		}
+		return groupName(n)
+	}
+	if clusters {
+		for _, name := range g.Nodes() {
+			n := g.Node(name)
+			n.Group = of(n)
+		}
+		return g
+	}
+	return merge(g, func(n *Node) string {
+		if name := of(n); name != "" {
+			return name
+		}
+		return n.Name
+	})
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	graphs "github.com/sequix/baobab/graph"
+	"github.com/sequix/baobab/internal/pb"
+)
+
+// gRPC status codes.
+const (
+	grpcInvalidArgument = 3
+	grpcNotFound        = 5
+	grpcUnimplemented   = 12
+	grpcInternal        = 13
+)
+
+const grpcMaxMessage = 4 << 20
+
+// grpcError is an error with a gRPC status code.
+type grpcError struct {
+	code int
+	msg  string
+}
+
+func (e *grpcError) Error() string {
+	return e.msg
+}
+
+// grpcMethod answers a call to a method of baobab.proto, given the fields of
+// the request.
+type grpcMethod func(s *server, ctx context.Context, req []pb.Field) (*pb.Buffer, error)
+
+var grpcMethods = map[string]grpcMethod{
+	"Scan":     (*server).grpcScan,
+	"GetGraph": (*server).grpcGetGraph,
+	"Path":     (*server).grpcPath,
+	"Deps": func(s *server, ctx context.Context, req []pb.Field) (*pb.Buffer, error) {
+		return s.grpcDeps(req, false)
+	},
+	"Rdeps": func(s *server, ctx context.Context, req []pb.Field) (*pb.Buffer, error) {
+		return s.grpcDeps(req, true)
+	},
+}
+
+// handleGRPC answers unary calls to the baobab.v1.Baobab service, reading
+// the request and writing the reply in gRPC's length-prefixed framing.
+// Compressed messages are not supported, which clients only send if told.
+func (s *server) handleGRPC(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	reply, err := s.grpcCall(r)
+	if err == nil {
+		frame := make([]byte, 5, 5+len(reply))
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(reply)))
+		if _, err := w.Write(append(frame, reply...)); err != nil {
+			logger.Error("failed to write response", "err", err)
+		}
+	}
+	code, msg := 0, ""
+	if err != nil {
+		code, msg = grpcInternal, err.Error()
+		if e, ok := err.(*grpcError); ok {
+			code = e.code
+		}
+	}
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	w.Header().Set("Grpc-Message", grpcEscape(msg))
+}
+
+// grpcEscape percent-encodes msg for the grpc-message trailer, which only
+// takes printable ASCII.
+func grpcEscape(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		if c := msg[i]; c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func (s *server) grpcCall(r *http.Request) ([]byte, error) {
+	method := grpcMethods[r.PathValue("method")]
+	if method == nil {
+		return nil, &grpcError{grpcUnimplemented, fmt.Sprintf("unknown method %s", r.URL.Path)}
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, grpcMaxMessage+5))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) < 5 || int(binary.BigEndian.Uint32(body[1:])) != len(body)-5 {
+		return nil, &grpcError{grpcInvalidArgument, "want a single request message"}
+	}
+	if body[0] != 0 {
+		return nil, &grpcError{grpcUnimplemented, "compressed messages are not supported"}
+	}
+	req, err := pb.Parse(body[5:])
+	if err != nil {
+		return nil, &grpcError{grpcInvalidArgument, fmt.Sprintf("failed to decode request: %s", err)}
+	}
+	reply, err := method(s, r.Context(), req)
+	if err != nil {
+		return nil, err
+	}
+	return reply.Bytes(), nil
+}
+
+func (s *server) grpcScan(ctx context.Context, req []pb.Field) (*pb.Buffer, error) {
+	if err := s.rescan(ctx); err != nil {
+		warn("serve", "", "failed to scan", err)
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var m pb.Buffer
+	m.Int(1, int64(len(s.view.Nodes())))
+	m.Int(2, int64(len(s.view.Edges())))
+	m.Int(3, s.health.scanned.Unix())
+	return &m, nil
+}
+
+func (s *server) grpcGetGraph(ctx context.Context, req []pb.Field) (*pb.Buffer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var m pb.Buffer
+	for _, name := range s.view.Nodes() {
+		n := s.view.Node(name)
+		var node pb.Buffer
+		node.String(1, n.Name)
+		node.String(2, n.ImportPath)
+		node.String(3, n.Module)
+		node.Bool(4, n.External)
+		node.Bool(5, n.Std)
+		node.Bool(6, n.Cgo)
+		node.Bool(7, n.Marker)
+		node.String(8, n.Canonical)
+		node.String(9, n.Label)
+		node.Int(10, int64(n.Packages))
+		node.String(11, n.Group)
+		node.Bool(12, n.Proto)
+		m.Message(1, &node)
+	}
+	for _, e := range s.view.Edges() {
+		var edge pb.Buffer
+		edge.String(1, e.From)
+		edge.String(2, e.To)
+		edge.String(3, e.Kind())
+		edge.Int(4, int64(e.Weight))
+		m.Message(2, &edge)
+	}
+	return &m, nil
+}
+
+// grpcPath answers from the graph served, like the other methods.
+func (s *server) grpcPath(ctx context.Context, req []pb.Field) (*pb.Buffer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var from, to string
+	for _, f := range req {
+		switch f.Num {
+		case 1:
+			from = f.String()
+		case 2:
+			to = f.String()
+		}
+	}
+	pkgs, err := grpcPackages(s.view, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var m pb.Buffer
+	m.Strings(1, graphs.ShortestPath(s.view, pkgs[0], pkgs[1]))
+	return &m, nil
+}
+
+// grpcDeps answers Deps, or Rdeps following imports in reverse, from the
+// graph served.
+func (s *server) grpcDeps(req []pb.Field, reverse bool) (*pb.Buffer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var (
+		arg        string
+		transitive bool
+	)
+	for _, f := range req {
+		switch f.Num {
+		case 1:
+			arg = f.String()
+		case 2:
+			transitive = f.Bool()
+		}
+	}
+	pkgs, err := grpcPackages(s.view, arg)
+	if err != nil {
+		return nil, err
+	}
+	next := s.view.Succ
+	if reverse {
+		next = s.view.Pred
+	}
+	var m pb.Buffer
+	m.Strings(1, neighbors(pkgs[0], next, transitive))
+	return &m, nil
+}
+
+// grpcPackages returns the packages of g args name.
+func grpcPackages(g *Graph, args ...string) ([]string, error) {
+	var pkgs []string
+	for _, arg := range args {
+		if arg == "" {
+			return nil, &grpcError{grpcInvalidArgument, "missing package"}
+		}
+		pkg := nodeIn(g, arg)
+		if pkg == "" {
+			return nil, &grpcError{grpcNotFound, fmt.Sprintf("no package %s in the graph", arg)}
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs, nil
+}
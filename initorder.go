@@ -0,0 +1,214 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"sort"
+	"strings"
+)
+
+var flagHeavy int
+
+// initFlags defines the flags of the initorder command.
+func initFlags(fs *flag.FlagSet) {
+	fs.IntVar(&flagHeavy, "heavy", 20, "highlight packages whose init functions and package-level initializers add up to `N` statements and calls or more")
+}
+
+// initWork is what initializing a package runs.
+type initWork struct {
+	pkg    string
+	funcs  int // init functions
+	stmts  int // statements of the init functions
+	vars   int // package-level variables with an initializer
+	calls  int // calls made by the initializers
+	parsed bool
+}
+
+func (w initWork) cost() int {
+	return w.stmts + w.calls
+}
+
+func runInitOrder(fs *flag.FlagSet, args []string) error {
+	source, err := sourceArg(args)
+	if err != nil {
+		return err
+	}
+	p, err := newPainter()
+	if err != nil {
+		return err
+	}
+	if flagGoList != "" {
+		return fmt.Errorf("cannot read the files of go list output")
+	}
+	recordFiles = true
+	cleanup, err := prepare(fs, source)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if err := scanAll(); err != nil {
+		return err
+	}
+	work, err := readInitWork()
+	if err != nil {
+		return err
+	}
+	var rows []initWork
+	for _, pkg := range initOrder(graph) {
+		w := work[pkg]
+		w.pkg = pkg
+		rows = append(rows, w)
+	}
+	return writeOutput(func(w io.Writer) error { return printInitOrder(w, p, rows) })
+}
+
+// initOrder returns the packages of g in the order a program importing them
+// all initializes them: the first by import path whose imports are done,
+// over and over, as go does since 1.21. Test-only imports are left out, and
+// packages of import cycles come last.
+func initOrder(g *Graph) []string {
+	key := func(name string) string {
+		if n := g.Node(name); n.ImportPath != "" {
+			return n.ImportPath
+		}
+		return name
+	}
+	var (
+		result  []string
+		pending = map[string]int{} // imports not initialized yet
+		ready   []string
+	)
+	for _, name := range g.Nodes() {
+		if n := g.Node(name); n.Marker || n.Proto {
+			continue
+		}
+		for _, to := range g.Succ(name) {
+			if n := g.Node(to); !g.Edge(name, to).Test && !n.Marker && !n.Proto {
+				pending[name]++
+			}
+		}
+		if pending[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+	done := map[string]bool{}
+	for len(ready) > 0 {
+		sort.Slice(ready, func(i, j int) bool { return key(ready[i]) < key(ready[j]) })
+		pkg := ready[0]
+		ready = ready[1:]
+		result = append(result, pkg)
+		done[pkg] = true
+		for _, from := range g.Pred(pkg) {
+			if g.Edge(from, pkg).Test {
+				continue
+			}
+			if pending[from]--; pending[from] == 0 {
+				ready = append(ready, from)
+			}
+		}
+	}
+	var cycles []string
+	for _, name := range g.Nodes() {
+		if n := g.Node(name); !done[name] && !n.Marker && !n.Proto {
+			cycles = append(cycles, name)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool { return key(cycles[i]) < key(cycles[j]) })
+	return append(result, cycles...)
+}
+
+// readInitWork parses the files of the packages scanned, tests aside, for
+// what initializing them runs.
+func readInitWork() (map[string]initWork, error) {
+	result := map[string]initWork{}
+	fset := token.NewFileSet()
+	for _, file := range scanner.Files() {
+		if file.Skipped != "" && file.Skipped != "generated" || strings.HasSuffix(file.Path, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file.Path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %s", file.Path, err)
+		}
+		w := result[file.Dir]
+		w.parsed = true
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Name.Name == "init" && decl.Recv == nil && decl.Body != nil {
+					w.funcs++
+					w.stmts += countNodes(decl.Body, func(n ast.Node) bool {
+						_, ok := n.(ast.Stmt)
+						_, block := n.(*ast.BlockStmt)
+						return ok && !block
+					})
+				}
+			case *ast.GenDecl:
+				if decl.Tok != token.VAR {
+					continue
+				}
+				for _, spec := range decl.Specs {
+					spec := spec.(*ast.ValueSpec)
+					if len(spec.Values) == 0 {
+						continue
+					}
+					w.vars += len(spec.Names)
+					for _, v := range spec.Values {
+						w.calls += countNodes(v, func(n ast.Node) bool {
+							_, ok := n.(*ast.CallExpr)
+							return ok
+						})
+					}
+				}
+			}
+		}
+		result[file.Dir] = w
+	}
+	return result, nil
+}
+
+// countNodes returns the number of nodes below root count accepts, not
+// looking into function literals, which run when called.
+func countNodes(root ast.Node, count func(ast.Node) bool) int {
+	n := 0
+	ast.Inspect(root, func(node ast.Node) bool {
+		if _, ok := node.(*ast.FuncLit); ok {
+			return false
+		}
+		if node != nil && count(node) {
+			n++
+		}
+		return true
+	})
+	return n
+}
+
+// printInitOrder writes the packages in the order they initialize, with
+// what that runs, those with at least -heavy statements and calls marked.
+func printInitOrder(w io.Writer, p painter, rows []initWork) error {
+	table := [][]cell{{{"ORDER", bold}, {"INITS", bold}, {"STMTS", bold}, {"VARS", bold}, {"CALLS", bold}, {"PACKAGE", bold}}}
+	for i, r := range rows {
+		if !r.parsed {
+			table = append(table, []cell{{fmt.Sprint(i + 1), dim}, {"-", dim}, {"-", dim}, {"-", dim}, {"-", dim}, {r.pkg, dim}})
+			continue
+		}
+		row := []cell{
+			{fmt.Sprint(i + 1), dim},
+			{fmt.Sprint(r.funcs), plain},
+			{fmt.Sprint(r.stmts), plain},
+			{fmt.Sprint(r.vars), plain},
+			{fmt.Sprint(r.calls), plain},
+			{r.pkg, cyan},
+		}
+		if r.cost() >= flagHeavy {
+			row[5].style = red
+			row = append(row, cell{"heavy", red})
+		}
+		table = append(table, row)
+	}
+	return p.printTable(w, table)
+}
@@ -0,0 +1,74 @@
+// Package glob matches slash-separated paths against patterns where ** stands
+// for any number of directories.
+package glob
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Match reports whether name, a slash or OS separated path, matches
+// pattern. Patterns are path.Match patterns, except an element "**" matches
+// any number of path elements, including none.
+func Match(pattern, name string) bool {
+	return matchElems(strings.Split(pattern, "/"), strings.Split(filepath.ToSlash(name), "/"))
+}
+
+func matchElems(pattern, elems []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(elems); i++ {
+				if matchElems(pattern[1:], elems[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elems) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], elems[0]); !ok {
+			return false
+		}
+		pattern, elems = pattern[1:], elems[1:]
+	}
+	return len(elems) == 0
+}
+
+// MatchPrefix reports whether dir, or any path below it, may match
+// pattern.
+func MatchPrefix(pattern, dir string) bool {
+	var (
+		elems = strings.Split(pattern, "/")
+		names = strings.Split(filepath.ToSlash(dir), "/")
+	)
+	if dir == "." {
+		return true
+	}
+	for i, name := range names {
+		if i == len(elems) {
+			return false
+		}
+		if elems[i] == "**" {
+			return true
+		}
+		if ok, _ := path.Match(elems[i], name); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchAny reports whether name matches any of patterns.
+func MatchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if Match(p, name) {
+			return true
+		}
+	}
+	return false
+}
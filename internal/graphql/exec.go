@@ -0,0 +1,286 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Object is a value of an object type, resolving its fields.
+type Object interface {
+	// TypeName returns the name of the type of the object, for __typename
+	// and fragment type conditions.
+	TypeName() string
+	// Resolve returns the value of a field given its arguments: nil, a
+	// string, bool, int, float64, Object, or a slice of them.
+	Resolve(field string, args map[string]any) (any, error)
+}
+
+// Response is the result of a request, as served in JSON.
+type Response struct {
+	Data   *Map    `json:"data"`
+	Errors []Error `json:"errors,omitempty"`
+}
+
+// Error is an error executing a request, at the field of Path if any.
+type Error struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+// Map is a JSON object keeping the order its keys were set in, as results
+// follow the order of the query.
+type Map struct {
+	keys   []string
+	values map[string]any
+}
+
+func (m *Map) set(key string, value any) {
+	if m.values == nil {
+		m.values = map[string]any{}
+	}
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// Get returns the value of key, nil if none.
+func (m *Map) Get(key string) any {
+	return m.values[key]
+}
+
+func (m *Map) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// Execute runs the operation of doc named operation, the only one if
+// empty, with variables, resolving its fields from root, of the Query type
+// of schema. Errors of the request as a whole come back as error, those of
+// fields in the response.
+func Execute(doc *Document, operation string, variables map[string]any, schema *Schema, root Object) (*Response, error) {
+	var op *Operation
+	for _, o := range doc.Operations {
+		if o.Name == operation || operation == "" && len(doc.Operations) == 1 {
+			op = o
+		}
+	}
+	switch {
+	case op == nil && operation == "":
+		return nil, fmt.Errorf("several operations, name the one to run")
+	case op == nil:
+		return nil, fmt.Errorf("no operation %s", operation)
+	case op.Kind != "query":
+		return nil, fmt.Errorf("only queries are supported, not %ss", op.Kind)
+	}
+	vars := map[string]any{}
+	for _, def := range op.Variables {
+		v, ok := variables[def.Name]
+		if !ok || v == nil {
+			v = def.Default
+		}
+		if v == nil && strings.HasSuffix(def.Type, "!") {
+			return nil, fmt.Errorf("missing variable $%s of type %s", def.Name, def.Type)
+		}
+		if f, ok := v.(float64); ok && strings.TrimSuffix(def.Type, "!") == "Int" && f == float64(int(f)) {
+			// JSON has no integers.
+			v = int(f)
+		}
+		vars[def.Name] = v
+	}
+	e := &executor{doc: doc, vars: vars, schema: schema, root: root}
+	data := e.object(root, op.Selection, nil)
+	return &Response{Data: data, Errors: e.errors}, nil
+}
+
+type executor struct {
+	doc    *Document
+	vars   map[string]any
+	schema *Schema
+	root   Object
+	errors []Error
+}
+
+func (e *executor) fail(path []any, err error) {
+	e.errors = append(e.errors, Error{Message: err.Error(), Path: append([]any(nil), path...)})
+}
+
+// object returns the fields of obj selection selects.
+func (e *executor) object(obj Object, selection []Selection, path []any) *Map {
+	result := &Map{}
+	for _, sel := range e.collect(obj, selection, map[string]bool{}) {
+		key := sel.Key()
+		fieldPath := append(path, key)
+		if sel.Name == "__typename" {
+			result.set(key, obj.TypeName())
+			continue
+		}
+		args, err := e.arguments(sel.Arguments)
+		if err != nil {
+			e.fail(fieldPath, err)
+			result.set(key, nil)
+			continue
+		}
+		value, err := e.resolveField(obj, sel.Name, args)
+		if err != nil {
+			e.fail(fieldPath, err)
+			result.set(key, nil)
+			continue
+		}
+		result.set(key, e.value(value, sel, fieldPath))
+	}
+	return result
+}
+
+// resolveField returns the value of field of obj, answering the
+// introspection fields of the root from the schema.
+func (e *executor) resolveField(obj Object, field string, args map[string]any) (any, error) {
+	if obj != e.root {
+		return obj.Resolve(field, args)
+	}
+	switch field {
+	case "__schema":
+		return &schemaObject{e.schema}, nil
+	case "__type":
+		name, ok := args["name"].(string)
+		if !ok {
+			return nil, fmt.Errorf("missing argument name")
+		}
+		if e.schema.Types[name] == nil {
+			return nil, nil
+		}
+		return &typeObject{e.schema, name}, nil
+	}
+	return obj.Resolve(field, args)
+}
+
+// value completes the value of the field sel.
+func (e *executor) value(value any, sel Selection, path []any) any {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case Object:
+		if len(sel.Selection) == 0 {
+			e.fail(path, fmt.Errorf("field %s of type %s must have a selection of subfields", sel.Name, v.TypeName()))
+			return nil
+		}
+		return e.object(v, sel.Selection, path)
+	case []Object:
+		list := make([]any, len(v))
+		for i, o := range v {
+			list[i] = e.value(o, sel, append(path, i))
+		}
+		return list
+	case []any:
+		list := make([]any, len(v))
+		for i, o := range v {
+			list[i] = e.value(o, sel, append(path, i))
+		}
+		return list
+	}
+	if len(sel.Selection) > 0 {
+		e.fail(path, fmt.Errorf("field %s is a scalar and has no subfields", sel.Name))
+		return nil
+	}
+	return value
+}
+
+// collect returns the fields of selection to resolve on obj, fragments
+// spread, those skipped left out and those of the same key merged.
+func (e *executor) collect(obj Object, selection []Selection, spread map[string]bool) []Selection {
+	var (
+		result []Selection
+		index  = map[string]int{}
+	)
+	add := func(fields []Selection) {
+		for _, f := range fields {
+			if i, ok := index[f.Key()]; ok {
+				result[i].Selection = append(append([]Selection(nil), result[i].Selection...), f.Selection...)
+				continue
+			}
+			index[f.Key()] = len(result)
+			result = append(result, f)
+		}
+	}
+	for _, sel := range selection {
+		if !e.included(sel.Directives) {
+			continue
+		}
+		switch {
+		case sel.Spread != "":
+			f := e.doc.Fragments[sel.Spread]
+			if f == nil || spread[sel.Spread] || f.On != obj.TypeName() {
+				continue
+			}
+			spread[sel.Spread] = true
+			add(e.collect(obj, f.Selection, spread))
+			delete(spread, sel.Spread)
+		case sel.Name == "":
+			if sel.On == "" || sel.On == obj.TypeName() {
+				add(e.collect(obj, sel.Selection, spread))
+			}
+		default:
+			add([]Selection{sel})
+		}
+	}
+	return result
+}
+
+// included reports whether the @skip and @include directives of a
+// selection keep it.
+func (e *executor) included(directives []Directive) bool {
+	for _, d := range directives {
+		cond, _ := e.resolve(d.Arguments["if"]).(bool)
+		if d.Name == "skip" && cond || d.Name == "include" && !cond {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *executor) arguments(args map[string]any) (map[string]any, error) {
+	result := map[string]any{}
+	for name, v := range args {
+		result[name] = e.resolve(v)
+	}
+	return result, nil
+}
+
+// resolve returns v with the variables it refers to replaced by their
+// values.
+func (e *executor) resolve(v any) any {
+	switch v := v.(type) {
+	case Variable:
+		return e.vars[string(v)]
+	case []any:
+		list := make([]any, len(v))
+		for i, item := range v {
+			list[i] = e.resolve(item)
+		}
+		return list
+	case map[string]any:
+		obj := map[string]any{}
+		for k, item := range v {
+			obj[k] = e.resolve(item)
+		}
+		return obj
+	}
+	return v
+}
@@ -0,0 +1,336 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// character is a Human or Droid of the test data.
+type character struct {
+	kind, name string
+	friends    []string
+}
+
+var characters = map[string]*character{
+	"luke":  {"Human", "Luke", []string{"r2d2", "leia"}},
+	"leia":  {"Human", "Leia", []string{"luke"}},
+	"r2d2":  {"Droid", "R2-D2", []string{"luke"}},
+	"vader": {"Human", "Vader", nil},
+}
+
+func (c *character) TypeName() string { return c.kind }
+
+func (c *character) Resolve(field string, args map[string]any) (any, error) {
+	switch field {
+	case "name":
+		return c.name, nil
+	case "friends":
+		var result []Object
+		for _, f := range c.friends {
+			result = append(result, characters[f])
+		}
+		return result, nil
+	case "primaryFunction":
+		if c.kind == "Droid" {
+			return "astromech", nil
+		}
+	case "height":
+		return 1.72, nil
+	}
+	return nil, fmt.Errorf("unknown field %s on %s", field, c.kind)
+}
+
+type query struct{}
+
+func (query) TypeName() string { return "Query" }
+
+func (query) Resolve(field string, args map[string]any) (any, error) {
+	switch field {
+	case "hero":
+		id, ok := args["id"].(string)
+		if !ok {
+			id = "luke"
+		}
+		if c := characters[id]; c != nil {
+			return c, nil
+		}
+		return nil, nil
+	case "echo":
+		return args["value"], nil
+	case "fail":
+		return nil, fmt.Errorf("failed on purpose")
+	}
+	return nil, fmt.Errorf("unknown field %s on Query", field)
+}
+
+var testSchema = MustParseSchema(`
+type Query {
+  "A character by id."
+  hero(id: String! = "luke"): Human
+  echo(value: String, times: Int = 1, tags: [String!] = ["a"]): String
+}
+
+"""
+A person.
+"""
+type Human {
+  name: String!
+  friends: [Human!]!
+  height: Float
+}
+
+enum Side { LIGHT DARK }
+`)
+
+// run executes query with the JSON object variables, returning the JSON of
+// the response, or error: and the error of the request.
+func run(t *testing.T, query, variables string) string {
+	t.Helper()
+	doc, err := Parse(query)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	var vars map[string]any
+	if variables != "" {
+		if err := json.Unmarshal([]byte(variables), &vars); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := Execute(doc, "", vars, testSchema, &root)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	b, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+var root query
+
+func TestExecute(t *testing.T) {
+	for _, tc := range []struct {
+		name, query, variables, want string
+	}{
+		{"fields in query order",
+			`{ hero(id: "luke") { name friends { name } } }`, "",
+			`{"data":{"hero":{"name":"Luke","friends":[{"name":"R2-D2"},{"name":"Leia"}]}}}`},
+		{"aliases",
+			`{ a: hero(id: "leia") { n: name } b: hero(id: "r2d2") { name } }`, "",
+			`{"data":{"a":{"n":"Leia"},"b":{"name":"R2-D2"}}}`},
+		{"null object",
+			`{ hero(id: "nobody") { name } }`, "",
+			`{"data":{"hero":null}}`},
+		{"empty list",
+			`{ hero(id: "vader") { friends { name } } }`, "",
+			`{"data":{"hero":{"friends":[]}}}`},
+		{"variables",
+			`query Q($id: String!, $v: Int) { hero(id: $id) { name } echo(value: $v) }`, `{"id": "leia", "v": 3}`,
+			`{"data":{"hero":{"name":"Leia"},"echo":3}}`},
+		{"variable defaults",
+			`query Q($id: String = "r2d2") { hero(id: $id) { name } }`, "",
+			`{"data":{"hero":{"name":"R2-D2"}}}`},
+		{"input values",
+			`{ echo(value: {a: [1, 2.5, "s", true, null, ENUM]}) }`, "",
+			`{"data":{"echo":{"a":[1,2.5,"s",true,null,"ENUM"]}}}`},
+		{"block strings and escapes",
+			"{ a: echo(value: \"\"\"\n    one\n      two\n    \"\"\") b: echo(value: \"\\u00e9\\n\") }", "",
+			`{"data":{"a":"one\n  two","b":"é\n"}}`},
+		{"named fragments",
+			`{ hero(id: "luke") { ...names friends { ...names } } } fragment names on Human { name }`, "",
+			`{"data":{"hero":{"name":"Luke","friends":[{},{"name":"Leia"}]}}}`},
+		{"inline fragments by type",
+			`{ hero(id: "luke") { friends { __typename ... on Droid { primaryFunction } ... on Human { height } ... { name } } } }`, "",
+			`{"data":{"hero":{"friends":[{"__typename":"Droid","primaryFunction":"astromech","name":"R2-D2"},{"__typename":"Human","height":1.72,"name":"Leia"}]}}}`},
+		{"merged fields",
+			`{ hero(id: "luke") { friends { name } friends { __typename } } }`, "",
+			`{"data":{"hero":{"friends":[{"name":"R2-D2","__typename":"Droid"},{"name":"Leia","__typename":"Human"}]}}}`},
+		{"recursive fragments stop",
+			`{ hero { ...f } } fragment f on Human { name ...f }`, "",
+			`{"data":{"hero":{"name":"Luke"}}}`},
+		{"skip and include",
+			`query Q($yes: Boolean!) { hero { name @skip(if: $yes) height @include(if: $yes) ... @include(if: false) { friends { name } } } }`, `{"yes": true}`,
+			`{"data":{"hero":{"height":1.72}}}`},
+		{"skip false",
+			`{ hero { name @skip(if: false) height @include(if: false) } }`, "",
+			`{"data":{"hero":{"name":"Luke"}}}`},
+		{"named operation",
+			`query A { hero { name } }`, "",
+			`{"data":{"hero":{"name":"Luke"}}}`},
+		{"typename of the root",
+			`{ __typename }`, "",
+			`{"data":{"__typename":"Query"}}`},
+	} {
+		if got := run(t, tc.query, tc.variables); got != tc.want {
+			t.Errorf("%s:\ngot  %s\nwant %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name, query, variables, want string
+	}{
+		{"field error",
+			`{ fail hero { name } }`, "",
+			`{"data":{"fail":null,"hero":{"name":"Luke"}},"errors":[{"message":"failed on purpose","path":["fail"]}]}`},
+		{"unknown nested field",
+			`{ hero { friends { name nope } } }`, "",
+			`{"data":{"hero":{"friends":[{"name":"R2-D2","nope":null},{"name":"Leia","nope":null}]}},"errors":[{"message":"unknown field nope on Droid","path":["hero","friends",0,"nope"]},{"message":"unknown field nope on Human","path":["hero","friends",1,"nope"]}]}`},
+		{"object without subfields",
+			`{ hero }`, "",
+			`{"data":{"hero":null},"errors":[{"message":"field hero of type Human must have a selection of subfields","path":["hero"]}]}`},
+		{"scalar with subfields",
+			`{ hero { name { x } } }`, "",
+			`{"data":{"hero":{"name":null}},"errors":[{"message":"field name is a scalar and has no subfields","path":["hero","name"]}]}`},
+		{"missing variable",
+			`query Q($id: String!) { hero(id: $id) { name } }`, "",
+			`error: missing variable $id of type String!`},
+		{"mutation",
+			`mutation M { hero { name } }`, "",
+			`error: only queries are supported, not mutations`},
+		{"several operations",
+			`query A { hero { name } } query B { echo }`, "",
+			`error: several operations, name the one to run`},
+		{"syntax error",
+			"{ hero {\n name ", "",
+			`error: 2:7: unterminated selection set`},
+		{"unexpected character",
+			`{ hero ; }`, "",
+			`error: 1:8: unexpected character ';'`},
+		{"unterminated string",
+			`{ echo(value: "a) }`, "",
+			`error: 1:15: unterminated string`},
+		{"empty selection",
+			`{ }`, "",
+			`error: 1:3: empty selection set`},
+		{"fragment defined twice",
+			`{ hero { ...f } } fragment f on Human { name } fragment f on Human { name }`, "",
+			`error: fragment f defined twice`},
+		{"no operation",
+			`fragment f on Human { name }`, "",
+			`error: no operation in the document`},
+	} {
+		if got := run(t, tc.query, tc.variables); got != tc.want {
+			t.Errorf("%s:\ngot  %s\nwant %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestIntrospection(t *testing.T) {
+	for _, tc := range []struct {
+		name, query, want string
+	}{
+		{"query type",
+			`{ __schema { queryType { name kind } mutationType { name } } }`,
+			`{"data":{"__schema":{"queryType":{"name":"Query","kind":"OBJECT"},"mutationType":null}}}`},
+		{"type references",
+			`{ __type(name: "Human") { kind name description fields { name type { kind name ofType { kind name ofType { kind name ofType { name } } } } } } }`,
+			`{"data":{"__type":{"kind":"OBJECT","name":"Human","description":"A person.","fields":[` +
+				`{"name":"name","type":{"kind":"NON_NULL","name":null,"ofType":{"kind":"SCALAR","name":"String","ofType":null}}},` +
+				`{"name":"friends","type":{"kind":"NON_NULL","name":null,"ofType":{"kind":"LIST","name":null,"ofType":{"kind":"NON_NULL","name":null,"ofType":{"name":"Human"}}}}},` +
+				`{"name":"height","type":{"kind":"SCALAR","name":"Float","ofType":null}}]}}}`},
+		{"arguments",
+			`{ __type(name: "Query") { fields { name description args { name type { kind } defaultValue } } } }`,
+			`{"data":{"__type":{"fields":[` +
+				`{"name":"hero","description":"A character by id.","args":[{"name":"id","type":{"kind":"NON_NULL"},"defaultValue":"\"luke\""}]},` +
+				`{"name":"echo","description":null,"args":[{"name":"value","type":{"kind":"SCALAR"},"defaultValue":null},{"name":"times","type":{"kind":"SCALAR"},"defaultValue":"1"},{"name":"tags","type":{"kind":"LIST"},"defaultValue":"[\"a\"]"}]}]}}}`},
+		{"enums",
+			`{ __type(name: "Side") { kind fields { name } enumValues { name isDeprecated } } }`,
+			`{"data":{"__type":{"kind":"ENUM","fields":null,"enumValues":[{"name":"LIGHT","isDeprecated":false},{"name":"DARK","isDeprecated":false}]}}}`},
+		{"unknown type",
+			`{ __type(name: "Nope") { name } }`,
+			`{"data":{"__type":null}}`},
+		{"directives",
+			`{ __schema { directives { name locations args { name type { kind ofType { name } } } } } }`,
+			`{"data":{"__schema":{"directives":[` +
+				`{"name":"skip","locations":["FIELD","FRAGMENT_SPREAD","INLINE_FRAGMENT"],"args":[{"name":"if","type":{"kind":"NON_NULL","ofType":{"name":"Boolean"}}}]},` +
+				`{"name":"include","locations":["FIELD","FRAGMENT_SPREAD","INLINE_FRAGMENT"],"args":[{"name":"if","type":{"kind":"NON_NULL","ofType":{"name":"Boolean"}}}]}]}}}`},
+		{"introspection types",
+			`{ __type(name: "__TypeKind") { enumValues { name } } }`,
+			`{"data":{"__type":{"enumValues":[{"name":"SCALAR"},{"name":"OBJECT"},{"name":"INTERFACE"},{"name":"UNION"},{"name":"ENUM"},{"name":"INPUT_OBJECT"},{"name":"LIST"},{"name":"NON_NULL"}]}}}`},
+	} {
+		if got := run(t, tc.query, ""); got != tc.want {
+			t.Errorf("%s:\ngot  %s\nwant %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+// introspectionQuery is the query GraphiQL and code generators send.
+const introspectionQuery = `
+query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types { ...FullType }
+    directives { name description locations args { ...InputValue } }
+  }
+}
+fragment FullType on __Type {
+  kind name description
+  fields(includeDeprecated: true) {
+    name description
+    args { ...InputValue }
+    type { ...TypeRef }
+    isDeprecated deprecationReason
+  }
+  inputFields { ...InputValue }
+  interfaces { ...TypeRef }
+  enumValues(includeDeprecated: true) { name description isDeprecated deprecationReason }
+  possibleTypes { ...TypeRef }
+}
+fragment InputValue on __InputValue { name description type { ...TypeRef } defaultValue }
+fragment TypeRef on __Type {
+  kind name
+  ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } }
+}`
+
+func TestIntrospectionQuery(t *testing.T) {
+	got := run(t, introspectionQuery, "")
+	var resp struct {
+		Data struct {
+			Schema struct {
+				Types []struct {
+					Kind, Name string
+				}
+			} `json:"__schema"`
+		}
+		Errors []Error
+	}
+	if err := json.Unmarshal([]byte(got), &resp); err != nil {
+		t.Fatalf("%s: %s", err, got)
+	}
+	if len(resp.Errors) > 0 {
+		t.Fatalf("errors: %v", resp.Errors)
+	}
+	var names []string
+	for _, typ := range resp.Data.Schema.Types {
+		names = append(names, typ.Kind+" "+typ.Name)
+	}
+	for _, want := range []string{"OBJECT Query", "OBJECT Human", "ENUM Side", "SCALAR String", "OBJECT __Schema", "ENUM __DirectiveLocation"} {
+		if !strings.Contains(strings.Join(names, ","), want) {
+			t.Errorf("no type %s in %v", want, names)
+		}
+	}
+}
+
+func TestParseSchemaErrors(t *testing.T) {
+	for _, tc := range []struct {
+		sdl, want string
+	}{
+		{`type A { a: String }`, "no Query type in the schema"},
+		{`type Query { a: Nope }`, "unknown type Nope of Query.a"},
+		{`type Query { a(x: [Nope]): String }`, "unknown type Nope of Query.a"},
+		{`type Query { a: String } type Query { b: String }`, "type Query defined twice"},
+		{`interface Query { a: String }`, "unsupported definition interface Query"},
+		{`type Query { a String }`, `1:16: want :, got "String"`},
+	} {
+		if _, err := ParseSchema(tc.sdl); err == nil || err.Error() != tc.want {
+			t.Errorf("ParseSchema(%q) = %v, want %s", tc.sdl, err, tc.want)
+		}
+	}
+}
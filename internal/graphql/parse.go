@@ -0,0 +1,575 @@
+// Package graphql answers GraphQL queries over objects resolving their own
+// fields. It parses the query language, variables, fragments and the @skip
+// and @include directives. The schema, parsed from the schema language,
+// answers introspection; fields are only checked against it by the objects
+// resolving them, so unknown ones are found out while executing.
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Document is a parsed query document.
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+// Operation is a query of a document.
+type Operation struct {
+	Kind      string // query, mutation or subscription
+	Name      string
+	Variables []*VariableDef
+	Selection []Selection
+}
+
+// VariableDef declares a variable of an operation.
+type VariableDef struct {
+	Name    string
+	Type    string
+	Default any // nil if none
+}
+
+// Fragment is a named fragment definition.
+type Fragment struct {
+	Name      string
+	On        string
+	Selection []Selection
+}
+
+// Selection is a field, a fragment spread or an inline fragment.
+type Selection struct {
+	Alias      string
+	Name       string // field name, empty for an inline fragment
+	Arguments  map[string]any
+	Directives []Directive
+	Selection  []Selection
+	Spread     string // name of the fragment spread, if any
+	On         string // type condition of an inline fragment, if any
+}
+
+// Directive is a directive applied to a selection, like @skip(if: $x).
+type Directive struct {
+	Name      string
+	Arguments map[string]any
+}
+
+// Variable is a reference to a variable in a value.
+type Variable string
+
+// Enum is an enum value in a query.
+type Enum string
+
+// Key returns the name the result of s is under.
+func (s *Selection) Key() string {
+	if s.Alias != "" {
+		return s.Alias
+	}
+	return s.Name
+}
+
+// Parse parses a query document.
+func Parse(query string) (*Document, error) {
+	p := &parser{lex: lexer{src: query, line: 1}}
+	p.next()
+	doc := &Document{Fragments: map[string]*Fragment{}}
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.tok.is("{"):
+			sel, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, &Operation{Kind: "query", Selection: sel})
+		case p.tok.kind == tokName && p.tok.text == "fragment":
+			f, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if doc.Fragments[f.Name] != nil {
+				return nil, fmt.Errorf("fragment %s defined twice", f.Name)
+			}
+			doc.Fragments[f.Name] = f
+		case p.tok.kind == tokName && (p.tok.text == "query" || p.tok.text == "mutation" || p.tok.text == "subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, op)
+		default:
+			return nil, p.errorf("unexpected %s", p.tok)
+		}
+		if p.err != nil {
+			return nil, p.err
+		}
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	if len(doc.Operations) == 0 {
+		return nil, fmt.Errorf("no operation in the document")
+	}
+	return doc, nil
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind tokKind
+	text string // unquoted for strings
+	line int
+	col  int
+}
+
+func (t token) is(punct string) bool {
+	return t.kind == tokPunct && t.text == punct
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of query"
+	}
+	return strconv.Quote(t.text)
+}
+
+type lexer struct {
+	src  string
+	pos  int
+	line int
+	bol  int // position of the beginning of the line
+}
+
+// next returns the next token of the query, skipping white space, commas
+// and comments, as they are insignificant.
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\n':
+			l.pos++
+			l.line, l.bol = l.line+1, l.pos
+		case c == ' ' || c == '\t' || c == '\r' || c == ',':
+			l.pos++
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		default:
+			return l.token()
+		}
+	}
+	return token{kind: tokEOF, line: l.line, col: l.pos - l.bol + 1}, nil
+}
+
+func (l *lexer) token() (token, error) {
+	start := l.pos
+	tok := token{line: l.line, col: l.pos - l.bol + 1}
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.pos += 3
+		tok.kind, tok.text = tokPunct, "..."
+	case strings.ContainsRune("!$()[]{}:=@|&", rune(c)):
+		l.pos++
+		tok.kind, tok.text = tokPunct, string(c)
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		for l.pos < len(l.src) && isNameChar(l.src[l.pos]) {
+			l.pos++
+		}
+		tok.kind, tok.text = tokName, l.src[start:l.pos]
+	case c == '-' || c >= '0' && c <= '9':
+		l.pos++
+		tok.kind = tokInt
+		for l.pos < len(l.src) {
+			d := l.src[l.pos]
+			if d == '.' || d == 'e' || d == 'E' || (d == '+' || d == '-') && tok.kind == tokFloat {
+				tok.kind = tokFloat
+			} else if d < '0' || d > '9' {
+				break
+			}
+			l.pos++
+		}
+		tok.text = l.src[start:l.pos]
+	case c == '"':
+		if strings.HasPrefix(l.src[l.pos:], `"""`) {
+			end := strings.Index(l.src[l.pos+3:], `"""`)
+			if end < 0 {
+				return tok, fmt.Errorf("%d:%d: unterminated block string", tok.line, tok.col)
+			}
+			tok.kind, tok.text = tokString, blockString(l.src[l.pos+3:l.pos+3+end])
+			l.line += strings.Count(l.src[l.pos:l.pos+6+end], "\n")
+			l.pos += end + 6
+			break
+		}
+		l.pos++
+		for l.pos < len(l.src) && l.src[l.pos] != '"' && l.src[l.pos] != '\n' {
+			if l.src[l.pos] == '\\' {
+				l.pos++
+			}
+			l.pos++
+		}
+		if l.pos >= len(l.src) || l.src[l.pos] != '"' {
+			return tok, fmt.Errorf("%d:%d: unterminated string", tok.line, tok.col)
+		}
+		l.pos++
+		s, err := strconv.Unquote(l.src[start:l.pos])
+		if err != nil {
+			return tok, fmt.Errorf("%d:%d: bad string %s", tok.line, tok.col, l.src[start:l.pos])
+		}
+		tok.kind, tok.text = tokString, s
+	default:
+		return tok, fmt.Errorf("%d:%d: unexpected character %q", tok.line, tok.col, c)
+	}
+	return tok, nil
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// blockString returns the value of a """block string""", without the
+// indentation common to its lines and its blank first and last lines.
+func blockString(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(raw, `\"""`, `"""`), "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		if trimmed := strings.TrimLeft(line, " \t"); trimmed != "" {
+			if n := len(line) - len(trimmed); indent < 0 || n < indent {
+				indent = n
+			}
+		}
+	}
+	for i := 1; i < len(lines) && indent > 0; i++ {
+		if len(lines[i]) >= indent {
+			lines[i] = lines[i][indent:]
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+type parser struct {
+	lex lexer
+	tok token
+	err error
+}
+
+func (p *parser) next() {
+	if p.err != nil {
+		p.tok = token{kind: tokEOF}
+		return
+	}
+	p.tok, p.err = p.lex.next()
+	if p.err != nil {
+		p.tok = token{kind: tokEOF}
+	}
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	if p.err != nil {
+		return p.err
+	}
+	return fmt.Errorf("%d:%d: %s", p.tok.line, p.tok.col, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.tok.is(punct) {
+		return p.errorf("want %s, got %s", punct, p.tok)
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokName {
+		return "", p.errorf("want a name, got %s", p.tok)
+	}
+	name := p.tok.text
+	p.next()
+	return name, nil
+}
+
+func (p *parser) operation() (*Operation, error) {
+	op := &Operation{Kind: p.tok.text}
+	p.next()
+	if p.tok.kind == tokName {
+		op.Name = p.tok.text
+		p.next()
+	}
+	if p.tok.is("(") {
+		p.next()
+		for !p.tok.is(")") {
+			if err := p.expect("$"); err != nil {
+				return nil, err
+			}
+			v := &VariableDef{}
+			var err error
+			if v.Name, err = p.name(); err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if v.Type, err = p.typeRef(); err != nil {
+				return nil, err
+			}
+			if p.tok.is("=") {
+				p.next()
+				if v.Default, err = p.value(true); err != nil {
+					return nil, err
+				}
+			}
+			op.Variables = append(op.Variables, v)
+		}
+		p.next()
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	var err error
+	op.Selection, err = p.selectionSet()
+	return op, err
+}
+
+func (p *parser) typeRef() (string, error) {
+	var t string
+	if p.tok.is("[") {
+		p.next()
+		inner, err := p.typeRef()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expect("]"); err != nil {
+			return "", err
+		}
+		t = "[" + inner + "]"
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return "", err
+		}
+		t = name
+	}
+	if p.tok.is("!") {
+		p.next()
+		t += "!"
+	}
+	return t, nil
+}
+
+func (p *parser) fragment() (*Fragment, error) {
+	p.next()
+	f := &Fragment{}
+	var err error
+	if f.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokName || p.tok.text != "on" {
+		return nil, p.errorf("want on, got %s", p.tok)
+	}
+	p.next()
+	if f.On, err = p.name(); err != nil {
+		return nil, err
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	f.Selection, err = p.selectionSet()
+	return f, err
+}
+
+func (p *parser) selectionSet() ([]Selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var result []Selection
+	for !p.tok.is("}") {
+		if p.tok.kind == tokEOF {
+			return nil, p.errorf("unterminated selection set")
+		}
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, sel)
+	}
+	if len(result) == 0 {
+		return nil, p.errorf("empty selection set")
+	}
+	p.next()
+	return result, nil
+}
+
+func (p *parser) selection() (Selection, error) {
+	var (
+		sel Selection
+		err error
+	)
+	if p.tok.is("...") {
+		p.next()
+		if p.tok.kind == tokName && p.tok.text != "on" {
+			sel.Spread = p.tok.text
+			p.next()
+			sel.Directives, err = p.directives()
+			return sel, err
+		}
+		if p.tok.kind == tokName {
+			p.next()
+			if sel.On, err = p.name(); err != nil {
+				return sel, err
+			}
+		}
+		if sel.Directives, err = p.directives(); err != nil {
+			return sel, err
+		}
+		sel.Selection, err = p.selectionSet()
+		return sel, err
+	}
+	if sel.Name, err = p.name(); err != nil {
+		return sel, err
+	}
+	if p.tok.is(":") {
+		p.next()
+		sel.Alias = sel.Name
+		if sel.Name, err = p.name(); err != nil {
+			return sel, err
+		}
+	}
+	if sel.Arguments, err = p.arguments(); err != nil {
+		return sel, err
+	}
+	if sel.Directives, err = p.directives(); err != nil {
+		return sel, err
+	}
+	if p.tok.is("{") {
+		sel.Selection, err = p.selectionSet()
+	}
+	return sel, err
+}
+
+func (p *parser) arguments() (map[string]any, error) {
+	if !p.tok.is("(") {
+		return nil, nil
+	}
+	p.next()
+	args := map[string]any{}
+	for !p.tok.is(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if args[name], err = p.value(false); err != nil {
+			return nil, err
+		}
+	}
+	p.next()
+	return args, nil
+}
+
+func (p *parser) directives() ([]Directive, error) {
+	var result []Directive
+	for p.tok.is("@") {
+		p.next()
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.arguments()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, Directive{name, args})
+	}
+	return result, nil
+}
+
+// value parses a value, only made of constants if constant.
+func (p *parser) value(constant bool) (any, error) {
+	tok := p.tok
+	switch {
+	case tok.is("$") && !constant:
+		p.next()
+		name, err := p.name()
+		return Variable(name), err
+	case tok.is("["):
+		p.next()
+		list := []any{}
+		for !p.tok.is("]") {
+			if p.tok.kind == tokEOF {
+				return nil, p.errorf("unterminated list")
+			}
+			v, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		p.next()
+		return list, nil
+	case tok.is("{"):
+		p.next()
+		obj := map[string]any{}
+		for !p.tok.is("}") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if obj[name], err = p.value(constant); err != nil {
+				return nil, err
+			}
+		}
+		p.next()
+		return obj, nil
+	case tok.kind == tokInt:
+		p.next()
+		n, err := strconv.Atoi(tok.text)
+		if err != nil {
+			return nil, fmt.Errorf("%d:%d: bad int %s", tok.line, tok.col, tok.text)
+		}
+		return n, nil
+	case tok.kind == tokFloat:
+		p.next()
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%d:%d: bad float %s", tok.line, tok.col, tok.text)
+		}
+		return f, nil
+	case tok.kind == tokString:
+		p.next()
+		return tok.text, nil
+	case tok.kind == tokName:
+		p.next()
+		switch tok.text {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return Enum(tok.text), nil
+	}
+	return nil, p.errorf("want a value, got %s", tok)
+}
@@ -0,0 +1,511 @@
+package graphql
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Schema is the types of the objects a query resolves, as introspection
+// tells them through __schema and __type. The root type is Query.
+type Schema struct {
+	Types map[string]*TypeDef
+}
+
+// TypeDef is an object, enum or scalar type of a schema.
+type TypeDef struct {
+	Kind        string // OBJECT, ENUM or SCALAR
+	Name        string
+	Description string
+	Fields      []*FieldDef // of objects
+	Values      []string    // of enums
+}
+
+// FieldDef is a field of an object type.
+type FieldDef struct {
+	Name        string
+	Description string
+	Args        []*InputDef
+	Type        string // type reference, like [Node!]!
+}
+
+// InputDef is an argument of a field or directive.
+type InputDef struct {
+	Name        string
+	Description string
+	Type        string
+	Default     string // in the query language, empty if none
+}
+
+// builtinSDL defines the built-in scalars and the types of introspection,
+// which every schema has.
+const builtinSDL = `
+"The String scalar type represents textual data, as UTF-8 character sequences."
+scalar String
+"The Int scalar type represents non-fractional signed whole numeric values."
+scalar Int
+"The Float scalar type represents signed double-precision fractional values."
+scalar Float
+"The Boolean scalar type represents true or false."
+scalar Boolean
+"The ID scalar type represents a unique identifier."
+scalar ID
+
+type __Schema {
+  description: String
+  types: [__Type!]!
+  queryType: __Type!
+  mutationType: __Type
+  subscriptionType: __Type
+  directives: [__Directive!]!
+}
+
+type __Type {
+  kind: __TypeKind!
+  name: String
+  description: String
+  specifiedByURL: String
+  fields(includeDeprecated: Boolean = false): [__Field!]
+  interfaces: [__Type!]
+  possibleTypes: [__Type!]
+  enumValues(includeDeprecated: Boolean = false): [__EnumValue!]
+  inputFields(includeDeprecated: Boolean = false): [__InputValue!]
+  ofType: __Type
+}
+
+enum __TypeKind { SCALAR OBJECT INTERFACE UNION ENUM INPUT_OBJECT LIST NON_NULL }
+
+type __Field {
+  name: String!
+  description: String
+  args(includeDeprecated: Boolean = false): [__InputValue!]!
+  type: __Type!
+  isDeprecated: Boolean!
+  deprecationReason: String
+}
+
+type __InputValue {
+  name: String!
+  description: String
+  type: __Type!
+  defaultValue: String
+  isDeprecated: Boolean!
+  deprecationReason: String
+}
+
+type __EnumValue {
+  name: String!
+  description: String
+  isDeprecated: Boolean!
+  deprecationReason: String
+}
+
+type __Directive {
+  name: String!
+  description: String
+  locations: [__DirectiveLocation!]!
+  args(includeDeprecated: Boolean = false): [__InputValue!]!
+  isRepeatable: Boolean!
+}
+
+enum __DirectiveLocation {
+  QUERY MUTATION SUBSCRIPTION FIELD FRAGMENT_DEFINITION FRAGMENT_SPREAD INLINE_FRAGMENT
+  VARIABLE_DEFINITION SCHEMA SCALAR OBJECT FIELD_DEFINITION ARGUMENT_DEFINITION
+  INTERFACE UNION ENUM ENUM_VALUE INPUT_OBJECT INPUT_FIELD_DEFINITION
+}
+`
+
+// directives are the directives executing queries supports.
+var directives = []struct {
+	name, description string
+}{
+	{"skip", "Directs the executor to skip this field or fragment when the if argument is true."},
+	{"include", "Directs the executor to include this field or fragment only when the if argument is true."},
+}
+
+// ParseSchema parses the type, enum and scalar definitions of a schema in
+// the schema language, with descriptions, which must define the Query type.
+// Interfaces, unions, input types and directives are not supported.
+func ParseSchema(sdl string) (*Schema, error) {
+	s := &Schema{Types: map[string]*TypeDef{}}
+	if err := s.parse(builtinSDL); err != nil {
+		return nil, fmt.Errorf("failed to parse built-in types: %s", err)
+	}
+	if err := s.parse(sdl); err != nil {
+		return nil, err
+	}
+	if s.Types["Query"] == nil {
+		return nil, fmt.Errorf("no Query type in the schema")
+	}
+	for _, t := range s.Types {
+		for _, f := range t.Fields {
+			refs := []string{f.Type}
+			for _, a := range f.Args {
+				refs = append(refs, a.Type)
+			}
+			for _, ref := range refs {
+				if name := strings.Trim(ref, "[]!"); s.Types[name] == nil {
+					return nil, fmt.Errorf("unknown type %s of %s.%s", name, t.Name, f.Name)
+				}
+			}
+		}
+	}
+	return s, nil
+}
+
+// MustParseSchema is like ParseSchema but panics if sdl cannot be parsed,
+// for schemas known to be right.
+func MustParseSchema(sdl string) *Schema {
+	s, err := ParseSchema(sdl)
+	if err != nil {
+		panic(fmt.Sprintf("graphql: failed to parse schema: %s", err))
+	}
+	return s
+}
+
+func (s *Schema) parse(sdl string) error {
+	p := &parser{lex: lexer{src: sdl, line: 1}}
+	p.next()
+	for p.tok.kind != tokEOF {
+		t := &TypeDef{Description: p.description()}
+		keyword, err := p.name()
+		if err != nil {
+			return err
+		}
+		if t.Name, err = p.name(); err != nil {
+			return err
+		}
+		switch keyword {
+		case "scalar":
+			t.Kind = "SCALAR"
+		case "enum":
+			t.Kind = "ENUM"
+			if err := p.expect("{"); err != nil {
+				return err
+			}
+			for !p.tok.is("}") {
+				v, err := p.name()
+				if err != nil {
+					return err
+				}
+				t.Values = append(t.Values, v)
+			}
+			p.next()
+		case "type":
+			t.Kind = "OBJECT"
+			if t.Fields, err = p.fieldDefs(); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported definition %s %s", keyword, t.Name)
+		}
+		if s.Types[t.Name] != nil {
+			return fmt.Errorf("type %s defined twice", t.Name)
+		}
+		s.Types[t.Name] = t
+	}
+	return p.err
+}
+
+// description returns the description string in front of a definition,
+// empty if none.
+func (p *parser) description() string {
+	if p.tok.kind != tokString {
+		return ""
+	}
+	desc := p.tok.text
+	p.next()
+	return desc
+}
+
+func (p *parser) fieldDefs() ([]*FieldDef, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var result []*FieldDef
+	for !p.tok.is("}") {
+		if p.tok.kind == tokEOF {
+			return nil, p.errorf("unterminated type definition")
+		}
+		f := &FieldDef{Description: p.description()}
+		var err error
+		if f.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if p.tok.is("(") {
+			p.next()
+			for !p.tok.is(")") {
+				a := &InputDef{Description: p.description()}
+				if a.Name, err = p.name(); err != nil {
+					return nil, err
+				}
+				if err := p.expect(":"); err != nil {
+					return nil, err
+				}
+				if a.Type, err = p.typeRef(); err != nil {
+					return nil, err
+				}
+				if p.tok.is("=") {
+					p.next()
+					v, err := p.value(true)
+					if err != nil {
+						return nil, err
+					}
+					a.Default = literal(v)
+				}
+				f.Args = append(f.Args, a)
+			}
+			p.next()
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if f.Type, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+		result = append(result, f)
+	}
+	p.next()
+	return result, nil
+}
+
+// literal returns the constant value v in the query language.
+func literal(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(v)
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = literal(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for i, k := range keys {
+			keys[i] = k + ": " + literal(v[k])
+		}
+		return "{" + strings.Join(keys, ", ") + "}"
+	}
+	return fmt.Sprint(v)
+}
+
+// schemaObject resolves the fields of __Schema.
+type schemaObject struct {
+	s *Schema
+}
+
+func (o *schemaObject) TypeName() string { return "__Schema" }
+
+func (o *schemaObject) Resolve(field string, args map[string]any) (any, error) {
+	switch field {
+	case "description", "mutationType", "subscriptionType":
+		return nil, nil
+	case "types":
+		names := make([]string, 0, len(o.s.Types))
+		for name := range o.s.Types {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var result []Object
+		for _, name := range names {
+			result = append(result, &typeObject{o.s, name})
+		}
+		return result, nil
+	case "queryType":
+		return &typeObject{o.s, "Query"}, nil
+	case "directives":
+		var result []Object
+		for _, d := range directives {
+			result = append(result, &directiveObject{o.s, d.name, d.description})
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("unknown field %s on __Schema", field)
+}
+
+// typeObject resolves the fields of __Type for the type reference ref, a
+// named type or a list or non-null type wrapping another.
+type typeObject struct {
+	s   *Schema
+	ref string
+}
+
+func (o *typeObject) TypeName() string { return "__Type" }
+
+func (o *typeObject) Resolve(field string, args map[string]any) (any, error) {
+	var (
+		kind   string
+		ofType string
+		def    *TypeDef
+	)
+	switch {
+	case strings.HasSuffix(o.ref, "!"):
+		kind, ofType = "NON_NULL", strings.TrimSuffix(o.ref, "!")
+	case strings.HasPrefix(o.ref, "["):
+		kind, ofType = "LIST", o.ref[1:len(o.ref)-1]
+	default:
+		def = o.s.Types[o.ref]
+		kind = def.Kind
+	}
+	switch field {
+	case "kind":
+		return kind, nil
+	case "name":
+		if def == nil {
+			return nil, nil
+		}
+		return def.Name, nil
+	case "description":
+		if def == nil || def.Description == "" {
+			return nil, nil
+		}
+		return def.Description, nil
+	case "specifiedByURL", "possibleTypes", "inputFields":
+		return nil, nil
+	case "fields":
+		if kind != "OBJECT" {
+			return nil, nil
+		}
+		result := []Object{}
+		for _, f := range def.Fields {
+			result = append(result, &fieldObject{o.s, f})
+		}
+		return result, nil
+	case "interfaces":
+		if kind != "OBJECT" {
+			return nil, nil
+		}
+		return []Object{}, nil
+	case "enumValues":
+		if kind != "ENUM" {
+			return nil, nil
+		}
+		result := []Object{}
+		for _, v := range def.Values {
+			result = append(result, &enumValueObject{v})
+		}
+		return result, nil
+	case "ofType":
+		if ofType == "" {
+			return nil, nil
+		}
+		return &typeObject{o.s, ofType}, nil
+	}
+	return nil, fmt.Errorf("unknown field %s on __Type", field)
+}
+
+// fieldObject resolves the fields of __Field.
+type fieldObject struct {
+	s *Schema
+	f *FieldDef
+}
+
+func (o *fieldObject) TypeName() string { return "__Field" }
+
+func (o *fieldObject) Resolve(field string, args map[string]any) (any, error) {
+	switch field {
+	case "name":
+		return o.f.Name, nil
+	case "description":
+		return optional(o.f.Description), nil
+	case "args":
+		result := []Object{}
+		for _, a := range o.f.Args {
+			result = append(result, &inputValueObject{o.s, a})
+		}
+		return result, nil
+	case "type":
+		return &typeObject{o.s, o.f.Type}, nil
+	case "isDeprecated":
+		return false, nil
+	case "deprecationReason":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown field %s on __Field", field)
+}
+
+// inputValueObject resolves the fields of __InputValue.
+type inputValueObject struct {
+	s *Schema
+	a *InputDef
+}
+
+func (o *inputValueObject) TypeName() string { return "__InputValue" }
+
+func (o *inputValueObject) Resolve(field string, args map[string]any) (any, error) {
+	switch field {
+	case "name":
+		return o.a.Name, nil
+	case "description":
+		return optional(o.a.Description), nil
+	case "type":
+		return &typeObject{o.s, o.a.Type}, nil
+	case "defaultValue":
+		return optional(o.a.Default), nil
+	case "isDeprecated":
+		return false, nil
+	case "deprecationReason":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown field %s on __InputValue", field)
+}
+
+// enumValueObject resolves the fields of __EnumValue.
+type enumValueObject struct {
+	name string
+}
+
+func (o *enumValueObject) TypeName() string { return "__EnumValue" }
+
+func (o *enumValueObject) Resolve(field string, args map[string]any) (any, error) {
+	switch field {
+	case "name":
+		return o.name, nil
+	case "description", "deprecationReason":
+		return nil, nil
+	case "isDeprecated":
+		return false, nil
+	}
+	return nil, fmt.Errorf("unknown field %s on __EnumValue", field)
+}
+
+// directiveObject resolves the fields of __Directive, for @skip and
+// @include, which take the same argument in the same places.
+type directiveObject struct {
+	s                 *Schema
+	name, description string
+}
+
+func (o *directiveObject) TypeName() string { return "__Directive" }
+
+func (o *directiveObject) Resolve(field string, args map[string]any) (any, error) {
+	switch field {
+	case "name":
+		return o.name, nil
+	case "description":
+		return o.description, nil
+	case "locations":
+		return []any{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"}, nil
+	case "args":
+		return []Object{&inputValueObject{o.s, &InputDef{Name: "if", Type: "Boolean!"}}}, nil
+	case "isRepeatable":
+		return false, nil
+	}
+	return nil, fmt.Errorf("unknown field %s on __Directive", field)
+}
+
+// optional returns s, null if empty.
+func optional(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,138 @@
+// Package pb encodes and decodes protocol buffers messages made of the
+// types baobab's API uses: strings, bools, varint integers and embedded
+// messages.
+package pb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Wire types.
+const (
+	Varint  = 0
+	Fixed64 = 1
+	Bytes   = 2
+	Fixed32 = 5
+)
+
+// Buffer is a message being encoded. Fields at their zero value are left
+// out, as proto3 does for singular fields.
+type Buffer struct {
+	b []byte
+}
+
+func (m *Buffer) tag(field, wire int) {
+	m.b = binary.AppendUvarint(m.b, uint64(field)<<3|uint64(wire))
+}
+
+// String appends field s, if not empty.
+func (m *Buffer) String(field int, s string) {
+	if s != "" {
+		m.bytes(field, []byte(s))
+	}
+}
+
+// Strings appends the repeated field ss, empty strings included.
+func (m *Buffer) Strings(field int, ss []string) {
+	for _, s := range ss {
+		m.bytes(field, []byte(s))
+	}
+}
+
+// Bool appends field v, if true.
+func (m *Buffer) Bool(field int, v bool) {
+	if v {
+		m.Int(field, 1)
+	}
+}
+
+// Int appends field v, if not 0, as an int32 or int64 field.
+func (m *Buffer) Int(field int, v int64) {
+	if v != 0 {
+		m.tag(field, Varint)
+		m.b = binary.AppendUvarint(m.b, uint64(v))
+	}
+}
+
+// Message appends the embedded message sub, even if empty, so it can be an
+// element of a repeated field.
+func (m *Buffer) Message(field int, sub *Buffer) {
+	m.bytes(field, sub.b)
+}
+
+func (m *Buffer) bytes(field int, b []byte) {
+	m.tag(field, Bytes)
+	m.b = binary.AppendUvarint(m.b, uint64(len(b)))
+	m.b = append(m.b, b...)
+}
+
+// Bytes returns the encoded message.
+func (m *Buffer) Bytes() []byte {
+	return m.b
+}
+
+// Field is a field of a decoded message.
+type Field struct {
+	Num  int
+	Wire int
+	Int  uint64 // value of varint and fixed fields
+	Data []byte // value of length-delimited fields
+}
+
+// String returns the value of a string field.
+func (f Field) String() string {
+	return string(f.Data)
+}
+
+// Bool returns the value of a bool field.
+func (f Field) Bool() bool {
+	return f.Int != 0
+}
+
+var errTruncated = errors.New("truncated message")
+
+// Parse decodes the fields of message data, in order.
+func Parse(data []byte) ([]Field, error) {
+	var fields []Field
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errTruncated
+		}
+		data = data[n:]
+		f := Field{Num: int(key >> 3), Wire: int(key & 7)}
+		if f.Num == 0 {
+			return nil, fmt.Errorf("invalid field number 0")
+		}
+		switch f.Wire {
+		case Varint:
+			if f.Int, n = binary.Uvarint(data); n <= 0 {
+				return nil, errTruncated
+			}
+		case Fixed64:
+			if n = 8; len(data) < n {
+				return nil, errTruncated
+			}
+			f.Int = binary.LittleEndian.Uint64(data)
+		case Fixed32:
+			if n = 4; len(data) < n {
+				return nil, errTruncated
+			}
+			f.Int = uint64(binary.LittleEndian.Uint32(data))
+		case Bytes:
+			size, m := binary.Uvarint(data)
+			if m <= 0 || uint64(len(data)-m) < size {
+				return nil, errTruncated
+			}
+			f.Data = data[m : m+int(size)]
+			n = m + int(size)
+		default:
+			return nil, fmt.Errorf("unsupported wire type %d of field %d", f.Wire, f.Num)
+		}
+		data = data[n:]
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
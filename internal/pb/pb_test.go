@@ -0,0 +1,80 @@
+package pb
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	var sub Buffer
+	sub.Int(1, 150)
+	for _, tc := range []struct {
+		name   string
+		encode func(m *Buffer)
+		want   string
+	}{
+		// The examples of the protocol buffers encoding guide.
+		{"varint", func(m *Buffer) { m.Int(1, 150) }, "089601"},
+		{"string", func(m *Buffer) { m.String(2, "testing") }, "120774657374696e67"},
+		{"message", func(m *Buffer) { m.Message(3, &sub) }, "1a03089601"},
+		{"negative", func(m *Buffer) { m.Int(1, -2) }, "08feffffffffffffffff01"},
+		{"bool", func(m *Buffer) { m.Bool(4, true) }, "2001"},
+		{"repeated", func(m *Buffer) { m.Strings(5, []string{"a", ""}) }, "2a01612a00"},
+		{"large field", func(m *Buffer) { m.Int(16, 1) }, "800101"},
+		{"empty message", func(m *Buffer) { m.Message(6, &Buffer{}) }, "3200"},
+		{"zero values", func(m *Buffer) { m.Int(1, 0); m.Bool(2, false); m.String(3, "") }, ""},
+	} {
+		var m Buffer
+		tc.encode(&m)
+		if got := hex.EncodeToString(m.Bytes()); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	data, _ := hex.DecodeString("089601" + "120774657374696e67" + "1a03089601" + "2001" + "2d01000000" + "310100000000000000")
+	fields, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Field{
+		{Num: 1, Wire: Varint, Int: 150},
+		{Num: 2, Wire: Bytes, Data: []byte("testing")},
+		{Num: 3, Wire: Bytes, Data: []byte{0x08, 0x96, 0x01}},
+		{Num: 4, Wire: Varint, Int: 1},
+		{Num: 5, Wire: Fixed32, Int: 1},
+		{Num: 6, Wire: Fixed64, Int: 1},
+	}
+	if len(fields) != len(want) {
+		t.Fatalf("got %d fields, want %d", len(fields), len(want))
+	}
+	for i, f := range fields {
+		w := want[i]
+		if f.Num != w.Num || f.Wire != w.Wire || f.Int != w.Int || !bytes.Equal(f.Data, w.Data) {
+			t.Errorf("field %d = %+v, want %+v", i, f, w)
+		}
+	}
+	if fields[2].String() != "\x08\x96\x01" || !fields[3].Bool() {
+		t.Errorf("accessors of %+v wrong", fields)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name, data string
+	}{
+		{"truncated varint", "0896"},
+		{"truncated string", "120774"},
+		{"truncated fixed32", "2d0100"},
+		{"truncated fixed64", "31010000"},
+		{"field 0", "0001"},
+		{"group", "0b"},
+	} {
+		data, _ := hex.DecodeString(tc.data)
+		if _, err := Parse(data); err == nil {
+			t.Errorf("%s: parsed %s", tc.name, tc.data)
+		}
+	}
+}
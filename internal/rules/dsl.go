@@ -0,0 +1,171 @@
+package rules
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/sequix/baobab/internal/glob"
+)
+
+// Rule is a line of a rules file, saying whether packages matching From may
+// import those matching To:
+//
+//	allow pkg/api/** -> pkg/store/models
+//	deny  pkg/api/** -> pkg/store/** "use the service layer"
+//	warn  cmd/**     -> internal/legacy/**
+//	deny  pkg/domain/** -> !pkg/domain/** "the domain stands alone"
+//
+// The first line matching an import decides, so exceptions go before the
+// rules they carve out of. Globs starting with ! match what the rest does
+// not. Lines are comments from a # on.
+type Rule struct {
+	Verb    string // "deny", "warn" or "allow"
+	From    string
+	To      string
+	Message string // why, if given
+	File    string
+	Line    int
+}
+
+// Warning reports whether breaking r is only worth a warning.
+func (r *Rule) Warning() bool {
+	return r.Verb == "warn"
+}
+
+// Pos returns where r is written, as file:line.
+func (r *Rule) Pos() string {
+	return fmt.Sprintf("%s:%d", r.File, r.Line)
+}
+
+// Matches reports whether r is about package from importing the package
+// to, known by any of the names in to.
+func (r *Rule) Matches(from string, to ...string) bool {
+	if !matchGlob(r.From, from) {
+		return false
+	}
+	for _, name := range to {
+		if name != "" && matchGlob(r.To, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchGlob(pattern, name string) bool {
+	if rest, ok := strings.CutPrefix(pattern, "!"); ok {
+		return !glob.Match(rest, name)
+	}
+	return glob.Match(pattern, name)
+}
+
+// First returns the first of rules about from importing to, nil if none
+// is.
+func First(rules []Rule, from string, to ...string) *Rule {
+	for i := range rules {
+		if rules[i].Matches(from, to...) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// ParseFile reads the rules in file.
+func ParseFile(file string) ([]Rule, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(file, f)
+}
+
+// Parse reads the rules in r, naming it file in errors.
+func Parse(file string, r io.Reader) ([]Rule, error) {
+	var result []Rule
+	scan := bufio.NewScanner(r)
+	for line := 1; scan.Scan(); line++ {
+		fields, err := splitFields(scan.Text())
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", file, line, err)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		rule := Rule{Verb: fields[0], File: file, Line: line}
+		switch rule.Verb {
+		case "deny", "warn", "allow":
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown verb %q, want deny, warn or allow", file, line, rule.Verb)
+		}
+		if len(fields) < 4 || fields[2] != "->" {
+			return nil, fmt.Errorf("%s:%d: want %s FROM -> TO [\"message\"]", file, line, rule.Verb)
+		}
+		rule.From, rule.To = fields[1], fields[3]
+		for _, g := range []string{rule.From, rule.To} {
+			if err := checkGlob(strings.TrimPrefix(g, "!")); err != nil {
+				return nil, fmt.Errorf("%s:%d: bad glob %q: %s", file, line, g, err)
+			}
+		}
+		switch rest := fields[4:]; {
+		case len(rest) == 1 && strings.HasPrefix(rest[0], `"`):
+			rule.Message, _ = strconv.Unquote(rest[0])
+		case len(rest) > 0:
+			return nil, fmt.Errorf("%s:%d: unexpected %s after the globs, quote the message", file, line, rest[0])
+		}
+		result = append(result, rule)
+	}
+	return result, scan.Err()
+}
+
+func checkGlob(pattern string) error {
+	for _, elem := range strings.Split(pattern, "/") {
+		if _, err := path.Match(elem, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitFields splits line into fields separated by spaces, a double-quoted
+// string being a single one, keeping its quotes, up to a # comment.
+func splitFields(line string) ([]string, error) {
+	var fields []string
+	for i := 0; i < len(line); {
+		switch c := line[i]; {
+		case c == '#':
+			return fields, nil
+		case unicode.IsSpace(rune(c)):
+			i++
+		case c == '"':
+			j := i + 1
+			for j < len(line) && line[j] != '"' {
+				if line[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(line) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			if _, err := strconv.Unquote(line[i : j+1]); err != nil {
+				return nil, fmt.Errorf("bad string %s", line[i:j+1])
+			}
+			fields = append(fields, line[i:j+1])
+			i = j + 1
+		default:
+			j := i
+			for j < len(line) && !unicode.IsSpace(rune(line[j])) && line[j] != '"' {
+				j++
+			}
+			fields = append(fields, line[i:j])
+			i = j
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,92 @@
+package rules
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitFields(t *testing.T) {
+	for _, tc := range []struct {
+		line string
+		want []string
+	}{
+		{"", nil},
+		{"  # only a comment", nil},
+		{"deny a/** -> b", []string{"deny", "a/**", "->", "b"}},
+		{"deny\ta -> b # why", []string{"deny", "a", "->", "b"}},
+		{`deny a -> b "say \"no\""`, []string{"deny", "a", "->", "b", `"say \"no\""`}},
+		{`deny a -> b "not # a comment" # a comment`, []string{"deny", "a", "->", "b", `"not # a comment"`}},
+		{`deny a -> b "back\\slash"`, []string{"deny", "a", "->", "b", `"back\\slash"`}},
+		{`a"b"`, []string{"a", `"b"`}},
+	} {
+		got, err := splitFields(tc.line)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("splitFields(%q) = %q, %v, want %q", tc.line, got, err, tc.want)
+		}
+	}
+	for _, line := range []string{`deny a -> b "open`, `deny a -> b "ends in \"`, `deny a -> b "\q"`} {
+		if _, err := splitFields(line); err == nil {
+			t.Errorf("splitFields(%q) succeeded", line)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	input := `# layering
+allow pkg/api/** -> pkg/store/models
+deny  pkg/api/** -> pkg/store/** "use the \"service\" layer # not here"
+
+warn  cmd/**     -> internal/legacy/**  # for now
+deny  pkg/domain/** -> !pkg/domain/** "the domain stands alone"
+`
+	rules, err := Parse("rules.txt", strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Rule{
+		{"allow", "pkg/api/**", "pkg/store/models", "", "rules.txt", 2},
+		{"deny", "pkg/api/**", "pkg/store/**", `use the "service" layer # not here`, "rules.txt", 3},
+		{"warn", "cmd/**", "internal/legacy/**", "", "rules.txt", 5},
+		{"deny", "pkg/domain/**", "!pkg/domain/**", "the domain stands alone", "rules.txt", 6},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Fatalf("got %+v\nwant %+v", rules, want)
+	}
+	for _, tc := range []struct {
+		from, to string
+		want     int // index in rules, -1 for none
+	}{
+		{"pkg/api/v1", "pkg/store/models", 0},
+		{"pkg/api/v1", "pkg/store/sql", 1},
+		{"cmd/tool", "internal/legacy/x", 2},
+		{"pkg/domain/user", "pkg/store/sql", 3},
+		{"pkg/domain/user", "pkg/domain/order", -1},
+		{"pkg/other", "pkg/store/sql", -1},
+	} {
+		got := First(rules, tc.from, tc.to)
+		if (got == nil) != (tc.want < 0) || got != nil && got != &rules[tc.want] {
+			t.Errorf("First(%s -> %s) = %+v, want rule %d", tc.from, tc.to, got, tc.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tc := range []struct {
+		input, want string
+	}{
+		{"forbid a -> b", `rules.txt:1: unknown verb "forbid", want deny, warn or allow`},
+		{"\ndeny a -> b use the service", "rules.txt:2: unexpected use after the globs, quote the message"},
+		{"deny a b", "rules.txt:1: want deny FROM -> TO [\"message\"]"},
+		{"deny a ->", "rules.txt:1: want deny FROM -> TO [\"message\"]"},
+		{`deny a -> b "why" "twice"`, `rules.txt:1: unexpected "why" after the globs, quote the message`},
+		{`warn a -> b "open`, "rules.txt:1: unterminated string"},
+		{"deny a/[ -> b", `rules.txt:1: bad glob "a/[": syntax error in pattern`},
+		{"deny a -> ![", `rules.txt:1: bad glob "![": syntax error in pattern`},
+	} {
+		_, err := Parse("rules.txt", strings.NewReader(tc.input))
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("Parse(%q) error %v, want %s", tc.input, err, tc.want)
+		}
+	}
+}
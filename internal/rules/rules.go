@@ -0,0 +1,54 @@
+// Package rules holds the import rules baobab check and its analyzer share.
+package rules
+
+import (
+	"strings"
+
+	"github.com/sequix/baobab/internal/glob"
+)
+
+// ImportAllowed reports whether package dir may import imp per any of
+// allows, patterns like DIR_GLOB or DIR_GLOB:IMPORT_GLOB.
+func ImportAllowed(allows []string, dir, imp string) bool {
+	for _, allow := range allows {
+		dirGlob, impGlob := allow, "**"
+		if i := strings.Index(allow, ":"); i >= 0 {
+			dirGlob, impGlob = allow[:i], allow[i+1:]
+		}
+		if glob.Match(dirGlob, dir) && glob.Match(impGlob, imp) {
+			return true
+		}
+	}
+	return false
+}
+
+// InternalParent returns the import path packages must be under to import
+// path, if it has an internal element.
+func InternalParent(path string) (string, bool) {
+	elems := strings.Split(path, "/")
+	for i := len(elems) - 1; i >= 0; i-- {
+		if elems[i] == "internal" {
+			return strings.Join(elems[:i], "/"), true
+		}
+	}
+	return "", false
+}
+
+// BoundaryParent returns the directory packages must be under to import the
+// package in dir, a slash-separated path, if dir is in or below a directory
+// matching one of boundaries.
+func BoundaryParent(boundaries []string, dir string) (string, bool) {
+	elems := strings.Split(dir, "/")
+	for i := len(elems); i > 0; i-- {
+		if glob.MatchAny(boundaries, strings.Join(elems[:i], "/")) {
+			return strings.Join(elems[:i-1], "/"), true
+		}
+	}
+	return "", false
+}
+
+// Within reports whether path is parent or below it, everything is within
+// an empty parent.
+func Within(path, parent string) bool {
+	return parent == "" || path == parent || strings.HasPrefix(path, parent+"/")
+}
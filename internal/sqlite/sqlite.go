@@ -0,0 +1,266 @@
+// Package sqlite writes SQLite database files holding tables of rows, in the
+// SQLite file format, version 3, without a SQLite library. Tables have no
+// indexes, and their first column must be an INTEGER PRIMARY KEY, the rowid,
+// which rows leave out: the first row gets 1, the next 2 and so on.
+package sqlite
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	pageSize   = 4096
+	headerSize = 100 // of the database file, in front of page 1
+	maxLocal   = pageSize - 35
+	minLocal   = (pageSize-12)*32/255 - 23
+	fanout     = 256 // children per interior page, well within a page
+)
+
+// DB is a database being built. Values of rows may be nil, bool, int,
+// int64, float64 or string.
+type DB struct {
+	pages  [][]byte // page n is pages[n-1], page 1 is written last
+	tables [][]any  // rows of sqlite_schema
+}
+
+// New returns an empty database.
+func New() *DB {
+	return &DB{pages: [][]byte{nil}}
+}
+
+// AddTable adds the table name, created by the statement sql, holding rows.
+func (db *DB) AddTable(name, sql string, rows [][]any) error {
+	cells := make([][]byte, len(rows))
+	for i, row := range rows {
+		payload, err := record(append([]any{nil}, row...))
+		if err != nil {
+			return fmt.Errorf("failed to encode row %d of %s: %s", i+1, name, err)
+		}
+		cells[i] = db.leafCell(int64(i+1), payload)
+	}
+	root := db.tree(cells)
+	db.tables = append(db.tables, []any{"table", name, name, int64(root), sql})
+	return nil
+}
+
+// WriteTo writes the database file to w.
+func (db *DB) WriteTo(w io.Writer) (int64, error) {
+	var cells [][]byte
+	size := 0
+	for i, table := range db.tables {
+		payload, err := record(table)
+		if err != nil {
+			return 0, err
+		}
+		cell := db.leafCell(int64(i+1), payload)
+		size += len(cell) + 2
+		cells = append(cells, cell)
+	}
+	if size > pageSize-headerSize-8 {
+		return 0, fmt.Errorf("too many tables for the first page")
+	}
+	page := leafPage(cells, headerSize)
+	h := page[:headerSize]
+	copy(h, "SQLite format 3\x00")
+	binary.BigEndian.PutUint16(h[16:], pageSize)
+	h[18], h[19] = 1, 1 // legacy journal
+	h[21], h[22], h[23] = 64, 32, 32
+	binary.BigEndian.PutUint32(h[24:], 1) // change counter
+	binary.BigEndian.PutUint32(h[28:], uint32(len(db.pages)))
+	binary.BigEndian.PutUint32(h[40:], 1) // schema cookie
+	binary.BigEndian.PutUint32(h[44:], 4) // schema format
+	binary.BigEndian.PutUint32(h[56:], 1) // UTF-8
+	binary.BigEndian.PutUint32(h[92:], 1) // version valid for
+	binary.BigEndian.PutUint32(h[96:], 3045000)
+	db.pages[0] = page
+	var n int64
+	for _, p := range db.pages {
+		m, err := w.Write(p)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// tree lays cells out in a table b-tree, leaves first, and returns the page
+// number of its root.
+func (db *DB) tree(cells [][]byte) uint32 {
+	type child struct {
+		page   uint32
+		maxKey int64
+	}
+	var level []child
+	for start := 0; start < len(cells) || len(level) == 0; {
+		end, size := start, 8
+		for end < len(cells) && size+len(cells[end])+2 <= pageSize {
+			size += len(cells[end]) + 2
+			end++
+		}
+		var maxKey int64
+		if end > start {
+			maxKey = int64(end) // rowids are 1 and up
+		}
+		level = append(level, child{db.add(leafPage(cells[start:end], 0)), maxKey})
+		start = end
+	}
+	for len(level) > 1 {
+		var next []child
+		for start := 0; start < len(level); start += fanout {
+			group := level[start:min(start+fanout, len(level))]
+			last := group[len(group)-1]
+			var cells [][]byte
+			for _, c := range group[:len(group)-1] {
+				cells = append(cells, appendVarint(binary.BigEndian.AppendUint32(nil, c.page), uint64(c.maxKey)))
+			}
+			page := newPage(0x05, cells, 0, 12)
+			binary.BigEndian.PutUint32(page[8:], last.page)
+			next = append(next, child{db.add(page), last.maxKey})
+		}
+		level = next
+	}
+	return level[0].page
+}
+
+// leafCell returns the cell of a table leaf holding payload as row rowid,
+// moving what doesn't fit in the page to overflow pages.
+func (db *DB) leafCell(rowid int64, payload []byte) []byte {
+	cell := appendVarint(nil, uint64(len(payload)))
+	cell = appendVarint(cell, uint64(rowid))
+	if len(payload) <= maxLocal {
+		return append(cell, payload...)
+	}
+	local := minLocal + (len(payload)-minLocal)%(pageSize-4)
+	if local > maxLocal {
+		local = minLocal
+	}
+	cell = append(cell, payload[:local]...)
+	return binary.BigEndian.AppendUint32(cell, db.overflow(payload[local:]))
+}
+
+// overflow writes rest to a chain of overflow pages and returns the first.
+func (db *DB) overflow(rest []byte) uint32 {
+	first := uint32(len(db.pages) + 1)
+	for len(rest) > 0 {
+		page := make([]byte, pageSize)
+		n := copy(page[4:], rest)
+		rest = rest[n:]
+		if len(rest) > 0 {
+			binary.BigEndian.PutUint32(page, uint32(len(db.pages)+2))
+		}
+		db.add(page)
+	}
+	return first
+}
+
+// add appends page and returns its number.
+func (db *DB) add(page []byte) uint32 {
+	db.pages = append(db.pages, page)
+	return uint32(len(db.pages))
+}
+
+func leafPage(cells [][]byte, offset int) []byte {
+	return newPage(0x0d, cells, offset, 8)
+}
+
+// newPage returns a b-tree page of type kind holding cells, its header of
+// size header bytes at offset.
+func newPage(kind byte, cells [][]byte, offset, header int) []byte {
+	page := make([]byte, pageSize)
+	h := page[offset:]
+	h[0] = kind
+	binary.BigEndian.PutUint16(h[3:], uint16(len(cells)))
+	end := pageSize
+	for i, c := range cells {
+		end -= len(c)
+		copy(page[end:], c)
+		binary.BigEndian.PutUint16(h[header+2*i:], uint16(end))
+	}
+	binary.BigEndian.PutUint16(h[5:], uint16(end))
+	return page
+}
+
+// record encodes values in the record format.
+func record(values []any) ([]byte, error) {
+	var types, body []byte
+	for _, v := range values {
+		switch v := v.(type) {
+		case nil:
+			types = append(types, 0)
+		case bool:
+			if v {
+				types = append(types, 9)
+			} else {
+				types = append(types, 8)
+			}
+		case int:
+			types, body = appendInt(types, body, int64(v))
+		case int64:
+			types, body = appendInt(types, body, v)
+		case float64:
+			types = append(types, 7)
+			body = binary.BigEndian.AppendUint64(body, math.Float64bits(v))
+		case string:
+			types = appendVarint(types, uint64(2*len(v)+13))
+			body = append(body, v...)
+		default:
+			return nil, fmt.Errorf("unsupported value %v of type %T", v, v)
+		}
+	}
+	n := len(types) + 1
+	for len(appendVarint(nil, uint64(n))) != n-len(types) {
+		n++
+	}
+	return append(append(appendVarint(nil, uint64(n)), types...), body...), nil
+}
+
+// appendInt appends v to the serial types and body of a record, in as few
+// bytes as it fits.
+func appendInt(types, body []byte, v int64) ([]byte, []byte) {
+	switch {
+	case v == 0:
+		return append(types, 8), body
+	case v == 1:
+		return append(types, 9), body
+	}
+	for _, t := range []struct {
+		serial byte
+		size   int
+	}{{1, 1}, {2, 2}, {3, 3}, {4, 4}, {5, 6}} {
+		bits := uint(t.size * 8)
+		if v >= -1<<(bits-1) && v < 1<<(bits-1) {
+			for i := t.size - 1; i >= 0; i-- {
+				body = append(body, byte(v>>(uint(i)*8)))
+			}
+			return append(types, t.serial), body
+		}
+	}
+	return append(types, 6), binary.BigEndian.AppendUint64(body, uint64(v))
+}
+
+// appendVarint appends v as a SQLite varint: big-endian groups of 7 bits,
+// but for a ninth byte holding 8.
+func appendVarint(b []byte, v uint64) []byte {
+	if v >= 1<<56 {
+		var buf [9]byte
+		buf[8] = byte(v)
+		v >>= 8
+		for i := 7; i >= 0; i-- {
+			buf[i] = byte(v&0x7f) | 0x80
+			v >>= 7
+		}
+		return append(b, buf[:]...)
+	}
+	var buf [8]byte
+	i := len(buf) - 1
+	buf[i] = byte(v & 0x7f)
+	for v >>= 7; v > 0; v >>= 7 {
+		i--
+		buf[i] = byte(v&0x7f) | 0x80
+	}
+	return append(b, buf[i:]...)
+}
@@ -0,0 +1,102 @@
+package sqlite
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// query runs the sqlite3 shell on the database file path, returning its
+// output, the test skipped without sqlite3.
+func query(t *testing.T, path, sql string) string {
+	t.Helper()
+	bin, err := exec.LookPath("sqlite3")
+	if err != nil {
+		t.Skip("no sqlite3 to read the database")
+	}
+	out, err := exec.Command(bin, "-bail", path, sql).CombinedOutput()
+	if err != nil {
+		t.Fatalf("sqlite3 %q: %s: %s", sql, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// write writes db to a file in a temporary directory and returns its path.
+func write(t *testing.T, db *DB) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.WriteTo(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestValues(t *testing.T) {
+	db := New()
+	rows := [][]any{
+		{nil, true, 0, "a"},
+		{false, 1, int64(-129), ""},
+		{int64(1 << 40), int64(-1 << 62), 1.5, "héllo"},
+		{127, 32768, int64(1 << 23), strings.Repeat("x", 10000)},
+	}
+	if err := db.AddTable("t", "CREATE TABLE t(id INTEGER PRIMARY KEY, a, b, c, d)", rows); err != nil {
+		t.Fatal(err)
+	}
+	path := write(t, db)
+	if got := query(t, path, "PRAGMA integrity_check"); got != "ok" {
+		t.Fatalf("integrity_check: %s", got)
+	}
+	got := query(t, path, "SELECT id, quote(a), quote(b), quote(c), length(d), substr(d, 1, 5) FROM t")
+	want := strings.Join([]string{
+		"1|NULL|1|0|1|a",
+		"2|0|1|-129|0|",
+		"3|1099511627776|-4611686018427387904|1.5|5|héllo",
+		"4|127|32768|8388608|10000|xxxxx",
+	}, "\n")
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLargeTables(t *testing.T) {
+	db := New()
+	// Enough rows of a kilobyte for leaves under two levels of interior
+	// pages.
+	var rows [][]any
+	for i := range 1200 {
+		rows = append(rows, []any{fmt.Sprintf("%04d%s", i, strings.Repeat("y", 1000))})
+	}
+	if err := db.AddTable("big", "CREATE TABLE big(id INTEGER PRIMARY KEY, s TEXT)", rows); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddTable("empty", "CREATE TABLE empty(id INTEGER PRIMARY KEY, s TEXT)", nil); err != nil {
+		t.Fatal(err)
+	}
+	path := write(t, db)
+	if got := query(t, path, "PRAGMA integrity_check"); got != "ok" {
+		t.Fatalf("integrity_check: %s", got)
+	}
+	got := query(t, path, "SELECT count(*), min(id), max(id), sum(substr(s, 1, 4) + 1 = id) FROM big; SELECT count(*) FROM empty")
+	if want := "1200|1|1200|1200\n0"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := query(t, path, "SELECT substr(s, 1, 4) FROM big WHERE id = 777"); got != "0776" {
+		t.Errorf("row 777 = %q", got)
+	}
+}
+
+func TestUnsupportedValue(t *testing.T) {
+	if err := New().AddTable("t", "CREATE TABLE t(id INTEGER PRIMARY KEY, a)", [][]any{{[]int{1}}}); err == nil {
+		t.Error("added a row holding a slice")
+	}
+}
@@ -0,0 +1,236 @@
+// Package websocket upgrades HTTP requests to WebSocket connections, per RFC
+// 6455, for servers pushing text messages to browsers. Messages from the
+// client are read and dropped, pings answered and closes acknowledged.
+// Extensions, subprotocols and fragmented messages from the server are not
+// supported. Handshakes from pages of another origin than the server are
+// refused, so other sites cannot read what it sends.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// guid is appended to the key of the client to accept its handshake.
+const guid = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Opcodes of frames.
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+)
+
+// maxControl is the largest payload of a control frame.
+const maxControl = 125
+
+// writeTimeout is how long a write may wait for a slow client before
+// failing.
+const writeTimeout = 10 * time.Second
+
+// ErrClosed is returned by writes to a closed connection.
+var ErrClosed = errors.New("websocket: connection closed")
+
+// Conn is a WebSocket connection, safe for concurrent writes.
+type Conn struct {
+	conn   net.Conn
+	mu     sync.Mutex // held by writes
+	closed bool
+	done   chan struct{}
+}
+
+// Upgrade answers the WebSocket handshake of r and returns the connection,
+// reading from the client until it closes the connection. It replies with
+// an error to requests which are no WebSocket handshake.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if !headerHas(r.Header, "Connection", "upgrade") || !headerHas(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "want a websocket handshake", http.StatusBadRequest)
+		return nil, fmt.Errorf("not a websocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "want websocket version 13", http.StatusUpgradeRequired)
+		return nil, fmt.Errorf("unsupported websocket version %q", r.Header.Get("Sec-WebSocket-Version"))
+	}
+	if !sameOrigin(r) {
+		http.Error(w, "cross-origin websocket handshake", http.StatusForbidden)
+		return nil, fmt.Errorf("websocket handshake from origin %q", r.Header.Get("Origin"))
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "want a Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, fmt.Errorf("no websocket key")
+	}
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to hijack the connection: %s", err)
+	}
+	sum := sha1.Sum([]byte(key + guid))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", base64.StdEncoding.EncodeToString(sum[:]))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c := &Conn{conn: conn, done: make(chan struct{})}
+	go c.read(rw.Reader)
+	return c, nil
+}
+
+// sameOrigin reports whether the Origin of r, sent by browsers, has the host
+// r was sent to. Requests without one are not from browsers, and allowed.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// headerHas reports whether the comma-separated list of header name holds
+// token, ignoring case.
+func headerHas(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Done returns a channel closed once the connection is.
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// WriteText sends msg in a text frame.
+func (c *Conn) WriteText(msg []byte) error {
+	return c.write(opText, msg)
+}
+
+// Close sends a close frame, if not done yet, and closes the connection.
+func (c *Conn) Close() error {
+	c.write(opClose, nil)
+	return c.close()
+}
+
+func (c *Conn) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	close(c.done)
+	return c.conn.Close()
+}
+
+// write sends a frame of opcode op, unmasked as servers send them.
+func (c *Conn) write(op byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	header := []byte{0x80 | op, 0}
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err := c.conn.Write(append(header, payload...))
+	return err
+}
+
+// read reads the frames of the client until it closes the connection or
+// breaks the protocol, then closes it.
+func (c *Conn) read(r *bufio.Reader) {
+	defer c.close()
+	for {
+		op, payload, err := readFrame(r)
+		if err != nil {
+			return
+		}
+		switch op {
+		case opClose:
+			// Echo the status code, if any.
+			if len(payload) > 2 {
+				payload = payload[:2]
+			}
+			c.write(opClose, payload)
+			return
+		case opPing:
+			if c.write(opPong, payload) != nil {
+				return
+			}
+		}
+	}
+}
+
+// readFrame reads a frame, unmasking its payload.
+func readFrame(r *bufio.Reader) (op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return 0, nil, err
+	}
+	op = head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+	n := uint64(head[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if !masked {
+		return 0, nil, fmt.Errorf("unmasked frame from the client")
+	}
+	if op >= opClose && n > maxControl {
+		return 0, nil, fmt.Errorf("control frame of %d bytes", n)
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(r, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	if op < opClose {
+		// Messages are dropped, not kept.
+		_, err := io.CopyN(io.Discard, r, int64(n))
+		return op, nil, err
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return op, payload, nil
+}
@@ -0,0 +1,213 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dial opens a WebSocket connection to the server at url by hand, returning
+// the connection and a reader past the handshake.
+func dial(t *testing.T, url string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: x\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status %d", resp.StatusCode)
+	}
+	// The example of RFC 6455, section 1.3.
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Sec-WebSocket-Accept %q", got)
+	}
+	return conn, r
+}
+
+// frame returns a frame from the client, masked unless mask is nil.
+func frame(fin bool, op byte, payload []byte, mask []byte) []byte {
+	b := []byte{op, 0}
+	if fin {
+		b[0] |= 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		b[1] = byte(n)
+	default:
+		b[1] = 126
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	}
+	if mask == nil {
+		return append(b, payload...)
+	}
+	b[1] |= 0x80
+	b = append(b, mask...)
+	for i, c := range payload {
+		b = append(b, c^mask[i%4])
+	}
+	return b
+}
+
+// readServerFrame reads a frame from the server, failing if it is masked.
+func readServerFrame(t *testing.T, r *bufio.Reader) (op byte, payload []byte) {
+	t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		t.Fatal(err)
+	}
+	if head[0]&0x80 == 0 {
+		t.Errorf("fragmented frame from the server")
+	}
+	if head[1]&0x80 != 0 {
+		t.Fatalf("masked frame from the server")
+	}
+	n := uint64(head[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(r, ext[:])
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(r, ext[:])
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		t.Fatal(err)
+	}
+	return head[0] & 0x0F, payload
+}
+
+// serve starts a server upgrading every request and passing the
+// connection to handle.
+func serve(t *testing.T, handle func(c *Conn)) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		handle(c)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestWriteText(t *testing.T) {
+	sizes := []int{0, 5, 125, 126, 200, 65535, 70000}
+	url := serve(t, func(c *Conn) {
+		for _, n := range sizes {
+			c.WriteText(bytes.Repeat([]byte{'a'}, n))
+		}
+	})
+	_, r := dial(t, url)
+	for _, n := range sizes {
+		op, payload := readServerFrame(t, r)
+		if op != opText || len(payload) != n {
+			t.Errorf("got opcode %d of %d bytes, want text of %d", op, len(payload), n)
+		}
+	}
+}
+
+func TestMaskedPingAndFragments(t *testing.T) {
+	done := make(chan *Conn, 1)
+	url := serve(t, func(c *Conn) { done <- c })
+	conn, r := dial(t, url)
+	c := <-done
+	mask := []byte{0x37, 0xfa, 0x21, 0x3d}
+	// A fragmented message, dropped, with a ping between its fragments,
+	// which control frames may come in.
+	conn.Write(frame(false, opText, []byte("Hel"), mask))
+	conn.Write(frame(true, opPing, []byte("Hello"), mask))
+	conn.Write(frame(false, 0x0, bytes.Repeat([]byte("l"), 300), mask))
+	conn.Write(frame(true, 0x0, []byte("o"), mask))
+	if op, payload := readServerFrame(t, r); op != opPong || string(payload) != "Hello" {
+		t.Errorf("got opcode %d %q, want a pong of Hello unmasked", op, payload)
+	}
+	conn.Write(frame(true, opClose, []byte{0x03, 0xe8, 'b', 'y', 'e'}, mask))
+	if op, payload := readServerFrame(t, r); op != opClose || !bytes.Equal(payload, []byte{0x03, 0xe8}) {
+		t.Errorf("got opcode %d %v, want a close echoing 1000", op, payload)
+	}
+	select {
+	case <-c.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection not closed after a close frame")
+	}
+	if err := c.WriteText([]byte("late")); err != ErrClosed {
+		t.Errorf("write after close: %v, want ErrClosed", err)
+	}
+}
+
+func TestUnmaskedFrame(t *testing.T) {
+	done := make(chan *Conn, 1)
+	url := serve(t, func(c *Conn) { done <- c })
+	conn, _ := dial(t, url)
+	c := <-done
+	conn.Write(frame(true, opPing, []byte("x"), nil))
+	select {
+	case <-c.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection not closed after an unmasked frame")
+	}
+}
+
+func TestNoHandshake(t *testing.T) {
+	url := serve(t, func(c *Conn) { t.Error("upgraded a plain request") })
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestOrigin(t *testing.T) {
+	url := serve(t, func(c *Conn) { c.Close() })
+	host := strings.TrimPrefix(url, "http://")
+	for _, tc := range []struct {
+		origin string
+		want   int
+	}{
+		{"", http.StatusSwitchingProtocols},
+		{"http://" + host, http.StatusSwitchingProtocols},
+		{"https://evil.example", http.StatusForbidden},
+		{"http://" + host + ".evil.example", http.StatusForbidden},
+		{"null", http.StatusForbidden},
+	} {
+		req, _ := http.NewRequest("GET", url, nil)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		if tc.origin != "" {
+			req.Header.Set("Origin", tc.origin)
+		}
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.want {
+			t.Errorf("origin %q: status %d, want %d", tc.origin, resp.StatusCode, tc.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	graphs "github.com/sequix/baobab/graph"
+)
+
+type jsonGraph struct {
+	Generator *buildInfo `json:"generator,omitempty"`
+	Nodes     []jsonNode `json:"nodes"`
+	Edges     []jsonEdge `json:"edges"`
+}
+
+type jsonNode struct {
+	Name      string   `json:"name"`
+	Module    string   `json:"module,omitempty"`
+	External  bool     `json:"external,omitempty"`
+	Std       bool     `json:"std,omitempty"`
+	Cgo       bool     `json:"cgo,omitempty"`
+	Marker    bool     `json:"marker,omitempty"`
+	Proto     bool     `json:"proto,omitempty"`
+	Interface bool     `json:"interface,omitempty"`
+	Canonical string   `json:"canonical,omitempty"`
+	Label     string   `json:"label,omitempty"`
+	Packages  int      `json:"packages,omitempty"`
+	Group     string   `json:"group,omitempty"`
+	Owners    []string `json:"owners,omitempty"`
+	Files     int      `json:"files,omitempty"`
+	TestFiles int      `json:"testFiles,omitempty"`
+	Lines     int      `json:"lines,omitempty"`
+	Commits   int      `json:"commits,omitempty"`
+	Vulns     []string `json:"vulns,omitempty"`
+	Coverage  *float64 `json:"coverage,omitempty"`
+}
+
+type jsonEdge struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Kind   string `json:"kind"`
+	Weight int    `json:"weight,omitempty"`
+
+	Vulnerable bool `json:"vulnerable,omitempty"`
+
+	// Evidence is the file:line of each import making the edge.
+	Evidence []string `json:"evidence,omitempty"`
+}
+
+// writeJSON writes g as a JSON document of nodes and edges, along with the
+// build info of baobab.
+func writeJSON(w io.Writer, g *Graph) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(newJSONGraph(g))
+}
+
+// newJSONGraph returns the JSON document of g, as writeJSON writes it.
+func newJSONGraph(g *Graph) jsonGraph {
+	info := readBuildInfo()
+	doc := jsonGraph{
+		Generator: &info,
+		Nodes:     []jsonNode{},
+		Edges:     []jsonEdge{},
+	}
+	for _, name := range g.Nodes() {
+		doc.Nodes = append(doc.Nodes, newJSONNode(g.Node(name)))
+	}
+	for _, e := range g.Edges() {
+		doc.Edges = append(doc.Edges, newJSONEdge(g, e))
+	}
+	return doc
+}
+
+func newJSONNode(n *Node) jsonNode {
+	jn := jsonNode{n.Name, n.Module, n.External, n.Std, n.Cgo, n.Marker, n.Proto, n.Interface, n.Canonical, n.Label, n.Packages, n.Group, n.Owners, n.Files, n.TestFiles, n.Lines, n.Commits, n.Vulns, nil}
+	if c, ok := coverage(n); ok {
+		jn.Coverage = &c
+	}
+	if path, ok := graphs.Outside(n.Name); ok && jn.Label == "" {
+		jn.Label = path
+	}
+	return jn
+}
+
+func newJSONEdge(g *Graph, e *Edge) jsonEdge {
+	return jsonEdge{e.From, e.To, e.Kind(), e.Weight, e.Vulnerable, importPositions(g, e)}
+}
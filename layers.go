@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+var flagLayers string
+
+// checkLayers checks -layers names a way to find them, and that -groups is
+// given for groups.
+func checkLayers() error {
+	switch flagLayers {
+	case "":
+	case "computed":
+	case "groups":
+		if flagGroups == "" {
+			return fmt.Errorf("-layers groups takes the layers from -groups, and none were given")
+		}
+	default:
+		return fmt.Errorf("unknown -layers %q, want computed or groups", flagLayers)
+	}
+	if flagLayers != "" && flagCoverage != "" {
+		return fmt.Errorf("-layers and -coverage both fill the packages of DOT output, give one")
+	}
+	return nil
+}
+
+// layering is the layer of each package of a graph, from the top down, and
+// the imports breaking it.
+type layering struct {
+	layer map[string]int
+	count int
+	wrong map[*Edge]bool
+}
+
+// nodeLayers returns the layers of the packages of g per -layers, nil
+// without. Computed, a package is a layer above the packages it imports,
+// those of a cycle sharing one, and the imports within a cycle break the
+// layering. Per -groups, the groups are the layers in the order of the
+// file, the packages of no group in none, and the imports of a layer above
+// break it.
+func nodeLayers(g *Graph) (*layering, error) {
+	l := &layering{layer: map[string]int{}, wrong: map[*Edge]bool{}}
+	switch flagLayers {
+	case "computed":
+		internal := g.Filter(func(n *Node) bool {
+			return !n.External && !n.Std && !n.Marker && !n.Proto && !n.Interface
+		}, func(*Edge) bool { return true })
+		depths := importDepths(internal)
+		top := 0
+		for _, d := range depths {
+			top = max(top, d)
+		}
+		for name, d := range depths {
+			l.layer[name] = top - d
+		}
+		l.count = top + 1
+	case "groups":
+		groups, err := parseGroups(flagGroups)
+		if err != nil {
+			return nil, err
+		}
+		var layers []string
+		for _, gr := range groups {
+			layers = append(layers, gr.name)
+		}
+		for _, name := range g.Nodes() {
+			if layer := groupLayer(layers, g.Node(name)); layer >= 0 {
+				l.layer[name] = layer
+			}
+		}
+		l.count = len(layers)
+	default:
+		return nil, nil
+	}
+	for _, e := range g.Edges() {
+		from, ok := l.layer[e.From]
+		to, ok2 := l.layer[e.To]
+		if ok && ok2 && (to < from || to == from && flagLayers == "computed" && e.From != e.To) {
+			l.wrong[e] = true
+		}
+	}
+	return l, nil
+}
+
+// groupLayer returns the index of the group of n in layers, -1 if none,
+// whether n is in a cluster of the group or stands for all of it.
+func groupLayer(layers []string, n *Node) int {
+	if n.Group != "" {
+		return slices.Index(layers, n.Group)
+	}
+	return slices.Index(layers, n.Name)
+}
+
+// breaks reports whether e breaks the layering.
+func (l *layering) breaks(e *Edge) bool {
+	return l != nil && l.wrong[e]
+}
+
+// color returns the fill color of layer, from yellow at the top to purple
+// at the bottom, in graphviz's HSV form.
+func (l *layering) color(layer int) string {
+	return strconv.Quote(fmt.Sprintf("%.3f 0.35 1.000", 0.15+0.6*float64(layer)/float64(max(l.count-1, 1))))
+}
+
+// nodeAttrs returns the DOT attributes filling the package name with the
+// color of its layer, empty if in none or its cluster is filled instead.
+func (l *layering) nodeAttrs(name string, n *Node) string {
+	if l == nil {
+		return ""
+	}
+	layer, ok := l.layer[name]
+	if !ok || flagLayers == "groups" && n.Group != "" {
+		return ""
+	}
+	return fmt.Sprintf("style=filled, fillcolor=%s, xlabel=\"L%d\"", l.color(layer), layer)
+}
+
+// clusterAttrs returns the DOT statements filling the cluster of group with
+// the color of its layer, empty if it is not one.
+func (l *layering) clusterAttrs(group string, members []string) string {
+	if l == nil || flagLayers != "groups" || len(members) == 0 {
+		return ""
+	}
+	layer, ok := l.layer[members[0]]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("style=filled\nfillcolor=%s\n", l.color(layer))
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"reflect"
+
+	"github.com/sequix/baobab/internal/websocket"
+)
+
+//go:embed live.html
+var liveHTML []byte
+
+// delta is what changed in the graph served between two scans, as the live
+// view is told on its websocket.
+type delta struct {
+	Nodes        []jsonNode  `json:"nodes"` // added or changed
+	RemovedNodes []string    `json:"removedNodes"`
+	Edges        []jsonEdge  `json:"edges"` // added or changed
+	RemovedEdges [][2]string `json:"removedEdges"`
+}
+
+func (d delta) empty() bool {
+	return len(d.Nodes)+len(d.RemovedNodes)+len(d.Edges)+len(d.RemovedEdges) == 0
+}
+
+// graphDelta returns the nodes and edges added to, changed in and removed
+// from old in new.
+func graphDelta(old, new *Graph) delta {
+	d := delta{Nodes: []jsonNode{}, RemovedNodes: []string{}, Edges: []jsonEdge{}, RemovedEdges: [][2]string{}}
+	for _, name := range new.Nodes() {
+		n := newJSONNode(new.Node(name))
+		if m := old.Node(name); m == nil || !reflect.DeepEqual(newJSONNode(m), n) {
+			d.Nodes = append(d.Nodes, n)
+		}
+	}
+	for _, name := range old.Nodes() {
+		if new.Node(name) == nil {
+			d.RemovedNodes = append(d.RemovedNodes, name)
+		}
+	}
+	for _, e := range new.Edges() {
+		je := newJSONEdge(new, e)
+		if f := old.Edge(e.From, e.To); f == nil || !reflect.DeepEqual(newJSONEdge(old, f), je) {
+			d.Edges = append(d.Edges, je)
+		}
+	}
+	for _, e := range old.Edges() {
+		if new.Edge(e.From, e.To) == nil {
+			d.RemovedEdges = append(d.RemovedEdges, [2]string{e.From, e.To})
+		}
+	}
+	return d
+}
+
+func (s *server) handleLiveUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(themeHTML(liveHTML))
+}
+
+// handleLive upgrades to a websocket, on which the changes of every scan
+// are sent as a delta, until the client goes away.
+func (s *server) handleLive(w http.ResponseWriter, r *http.Request) {
+	c, err := websocket.Upgrade(w, r)
+	if err != nil {
+		logger.Debug("failed to upgrade to a websocket", "err", err)
+		return
+	}
+	send := make(chan []byte, liveBacklog)
+	s.clientsMu.Lock()
+	if s.clients == nil {
+		s.clients = map[*websocket.Conn]chan []byte{}
+	}
+	s.clients[c] = send
+	s.clientsMu.Unlock()
+	defer func() {
+		s.clientsMu.Lock()
+		delete(s.clients, c)
+		s.clientsMu.Unlock()
+		c.Close()
+	}()
+	for {
+		select {
+		case <-c.Done():
+			return
+		case msg, ok := <-send:
+			if !ok || c.WriteText(msg) != nil {
+				return
+			}
+		}
+	}
+}
+
+// liveBacklog is how many deltas a client of the live view may fall behind
+// before it is dropped.
+const liveBacklog = 16
+
+// broadcast sends d to the clients of the live view, unless empty. Clients
+// too slow to keep up are dropped, rather than holding up scans.
+func (s *server) broadcast(d delta) {
+	if d.empty() {
+		return
+	}
+	msg, err := json.Marshal(d)
+	if err != nil {
+		warn("serve", "", "failed to encode the changes", err)
+		return
+	}
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	for c, send := range s.clients {
+		select {
+		case send <- msg:
+		default:
+			logger.Debug("dropping a slow live view client")
+			delete(s.clients, c)
+			close(send)
+		}
+	}
+}
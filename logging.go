@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+)
+
+var (
+	flagVerbose bool
+	flagQuiet   bool
+	flagLogJSON bool
+
+	// logger is where baobab says what it does and what goes wrong, set up
+	// by setupLogging from the flags.
+	logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+)
+
+// logFlags defines the flags of every command about logging.
+func logFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&flagVerbose, "v", false, "also log the directories and files being scanned")
+	fs.BoolVar(&flagQuiet, "q", false, "only log errors, not warnings")
+	fs.BoolVar(&flagLogJSON, "log-json", false, "log JSON objects, one per line, instead of text")
+	fs.StringVar(&flagDiagnostics, "diagnostics", "", "also write the warnings and the error of the command to `FILE`, as a JSON document")
+}
+
+// setupLogging makes logger follow the logging flags.
+func setupLogging() {
+	setupDiagnostics()
+	level := slog.LevelInfo
+	switch {
+	case flagVerbose:
+		level = slog.LevelDebug
+	case flagQuiet:
+		level = slog.LevelError
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	if flagLogJSON {
+		logger = slog.New(slog.NewJSONHandler(os.Stderr, opts))
+	} else {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, opts))
+	}
+}
+
+// fatal logs err, writes it to -diagnostics and exits with exitError.
+func fatal(err error) {
+	logger.Error(err.Error())
+	writeDiagnostics(err)
+	os.Exit(exitError)
+}
@@ -2,168 +2,575 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"time"
+
+	graphs "github.com/sequix/baobab/graph"
+	"github.com/sequix/baobab/scan"
 )
 
 var (
-	flagEntryDir  = flag.String("entry", "", "directory where to start scan")
-	flagGoModName = flag.String("gomod", "github.com/sequix/baobab", "go mod name")
-	flagDepth     = flag.Int("depth", 0, "max depth, 0 for unlimited")
+	flagGoModName      string
+	flagDepth          int
+	flagGOOS           string
+	flagGOARCH         string
+	flagTags           string
+	flagTests          bool
+	flagFormat         string
+	flagStdlib         string
+	flagExternal       bool
+	flagCgo            bool
+	flagProto          bool
+	flagSkipGen        bool
+	flagLenient        bool
+	flagVendor         bool
+	flagBackend        string
+	flagGoList         string
+	flagWorkers        int
+	flagCache          string
+	flagOut            string
+	flagInterval       time.Duration
+	flagRepo           string
+	flagRef            string
+	flagConfig         string
+	flagDryRun         bool
+	flagNoProgress     bool
+	flagEntries        listFlag
+	flagIncludeSpecial listFlag
+	flagExclude        listFlag
+	flagOnly           listFlag
+
+	flagForbidBlank   bool
+	flagAllowBlank    listFlag
+	flagForbidDot     bool
+	flagAllowDot      listFlag
+	flagCheckInternal bool
+	flagBoundary      listFlag
+	flagCanonical     bool
+	flagForbidCycles  bool
+	flagRules         string
+	flagFailOn        listFlag
+	flagReport        string
+	flagStaged        bool
+	flagBaseline      string
+	flagUpdateBase    bool
+)
+
+// scanFlags defines the flags of the commands scanning packages.
+func scanFlags(fs *flag.FlagSet) {
+	fs.StringVar(&flagGoModName, "gomod", "github.com/sequix/baobab", "go mod name, read from go.mod or go.work if not given, or from the location of a project in GOPATH")
+	fs.IntVar(&flagDepth, "depth", 0, "max depth, 0 for unlimited")
+	fs.StringVar(&flagGOOS, "goos", "", "only scan files built for this GOOS, as in //go:build lines and _GOOS file suffixes")
+	fs.StringVar(&flagGOARCH, "goarch", "", "only scan files built for this GOARCH")
+	fs.StringVar(&flagTags, "tags", "", "comma-separated build tags to satisfy, implies build constraint matching")
+	fs.BoolVar(&flagTests, "include-tests", false, "also scan _test.go files, marking edges only they produce as test-only")
+	fs.StringVar(&flagStdlib, "stdlib", "off", "show standard library imports: off, all (one node per package), top (one per top-level package) or single (one stdlib node)")
+	fs.BoolVar(&flagExternal, "include-external", false, "show imports of third-party packages, one node per module")
+	fs.BoolVar(&flagCgo, "cgo", false, "add a cgo marker node, imported by every package using cgo")
+	fs.BoolVar(&flagProto, "proto", false, "overlay the imports of .proto files, a node per directory of them, imported by the Go packages their go_package names")
+	fs.BoolVar(&flagSkipGen, "skip-generated", false, "skip files with a // Code generated ... DO NOT EDIT. header, reporting edges only they produce to stderr")
+	fs.BoolVar(&flagLenient, "lenient", false, "warn about files failing to parse and skip them, instead of aborting")
+	fs.BoolVar(&flagVendor, "vendor", false, "show vendored packages as external nodes instead of ignoring them")
+	fs.StringVar(&flagBackend, "backend", "scanner", "how to find imports: scanner (fast), parser (go/parser, robust) or packages (go/packages, exact)")
+	fs.StringVar(&flagGoList, "golist", "", "`FILE` of go list -deps -json output to take the packages and imports from instead of scanning, - for stdin")
+	fs.IntVar(&flagWorkers, "workers", runtime.NumCPU(), "number of files parsed concurrently")
+	fs.StringVar(&flagCache, "cache", "", "`DIR` caching the imports of files by content hash, default baobab in the user cache dir, off to disable")
+	fs.StringVar(&flagRepo, "repo", "", "`URL` of a git repository to scan, cloned shallowly into a temporary directory")
+	fs.StringVar(&flagRef, "ref", "", "branch, tag or commit of -repo to scan, its default branch if not given")
+	fs.StringVar(&flagConfig, "config", "", "`FILE` to read flags from, the closest .baobab.yaml if not given, off to disable")
+	fs.BoolVar(&flagNoProgress, "no-progress", false, "never show the progress of long scans on stderr, as when it is not a terminal")
+	fs.StringVar(&flagCPUProfile, "cpuprofile", "", "write a CPU profile of the command to `FILE`, for go tool pprof")
+	fs.StringVar(&flagMemProfile, "memprofile", "", "write a heap profile to `FILE` once the command is done, for go tool pprof")
+	fs.BoolVar(&flagDryRun, "dry-run", false, "list the directories and files scanned, and why some were left out, instead of doing the command")
+	fs.Var(&flagEntries, "entry", "`DIR` or import path where to start scan, repeatable, - to read them from stdin, none to scan every package of every module")
+	fs.Var(&flagOnly, "only", "`GLOB` of the only directories to scan and output, like pkg/payment/**, repeatable")
+	fs.Var(&flagExclude, "exclude", "`GLOB` of directories to leave out of the scan and the output, like internal/legacy/**, repeatable")
+	fs.Var(&flagIncludeSpecial, "include-special", "kinds of directories the go tool ignores to scan anyway when scanning whole modules: testdata, hidden, underscore")
+}
+
+// outputFlags defines the flags of the commands writing a report.
+func outputFlags(fs *flag.FlagSet) {
+	fs.StringVar(&flagOut, "o", "", "write the output to `FILE` instead of stdout, replacing it at once")
+}
+
+// formatFlags defines the flags of the commands writing the graph.
+func formatFlags(fs *flag.FlagSet) {
+	fs.StringVar(&flagFormat, "format", "dot", "output format: dot, json, cypher, sqlite, bazel, bundle, an HTML page drawing the imports bundled along the directory tree, treemap, one drawing the packages sized by lines of code and colored by importers, or sankey, one drawing those between -groups as a Sankey diagram")
+	fs.StringVar(&flagFilterNode, "filter-node", "", "`REGEXP` of packages to leave out of the output, still scanned and followed")
+	fs.StringVar(&flagFilterEdge, "filter-edge", "", "`REGEXP` of imports, as \"FROM -> TO\", to leave out of the output")
+	fs.StringVar(&flagGroups, "groups", "", "YAML `FILE` mapping group names to lists of globs of directories, whose packages are merged into one node per group in the output")
+	fs.BoolVar(&flagClusters, "clusters", false, "draw the packages of each -groups group in a DOT cluster instead of merging them")
+	fs.IntVar(&flagCollapseDepth, "collapse-depth", 0, "merge the packages below `N` directories into one node per subtree in the output, 0 to keep them apart")
+	fs.StringVar(&flagSizeBy, "size-by", "", "size the nodes of DOT output by a `METRIC` of their packages: loc, lines of go code, files, go files, tests aside, churn, commits since -churn, fanin, packages importing them, or coverage, share of statements covered per -coverage")
+	fs.StringVar(&flagChurn, "churn", "", "count the commits changing the go files of each package since `DATE`, as git log --since takes it, like 3.months, to outline the packages of DOT output by churn")
+	fs.StringVar(&flagLayers, "layers", "", "fill the packages of DOT output by their layer, from the top down, and draw the imports breaking the layering in red: computed, a layer above the packages they import, cycles breaking it, or groups, the -groups in file order, -clusters filling theirs")
+	fs.StringVar(&flagCoverage, "coverage", "", "coverage profile `FILE`, as go test -coverprofile writes it, to color the packages of DOT output by the share of their statements tests cover")
+	fs.BoolVar(&flagCoupling, "coupling", false, "type check the packages and weight each import by the number of exported names of the imported package the importer uses")
+	codeOwnersFlags(fs)
+	themeFlags(fs)
+	fs.StringVar(&flagVulns, "vulns", "", "`FILE` of govulncheck -json output, - for stdin, marking the packages and imports on call paths to vulnerable functions")
+	fs.BoolVar(&flagGovulncheck, "govulncheck", false, "run govulncheck on the root scanned for -vulns")
+	fs.BoolVar(&flagGroupOwners, "group-owners", false, "group the packages by their first owner per -codeowners, like -groups")
+	fs.StringVar(&flagRelabel, "relabel", "", "`FILE` of REGEXP [LABEL] lines renaming the packages matching REGEXP in the output, the first matching line wins")
+}
+
+// ruleFlags defines the rules of the check command.
+func ruleFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&flagForbidBlank, "forbid-blank", false, "forbid blank imports not allowed by -allow-blank")
+	fs.Var(&flagAllowBlank, "allow-blank", "`DIR_GLOB[:IMPORT_GLOB]` of packages allowed to blank import, like cmd/**:github.com/lib/pq, repeatable")
+	fs.BoolVar(&flagForbidDot, "forbid-dot", false, "forbid dot imports not allowed by -allow-dot")
+	fs.Var(&flagAllowDot, "allow-dot", "`DIR_GLOB[:IMPORT_GLOB]` of packages allowed to dot import, repeatable")
+	fs.BoolVar(&flagCheckInternal, "check-internal", false, "forbid imports of internal packages, and of packages behind -boundary directories, from outside their parent")
+	fs.Var(&flagBoundary, "boundary", "`GLOB` of directories acting like internal ones for -check-internal: only importable from below their parent, repeatable")
+	fs.BoolVar(&flagCanonical, "check-canonical", false, "forbid importing packages by another path than the one in their import comment")
+	fs.BoolVar(&flagForbidCycles, "forbid-cycles", false, "forbid import cycles, test-only imports aside")
+	fs.BoolVar(&flagForbidDeprecated, "forbid-deprecated", false, "forbid importing packages, and using names of other packages, whose doc comment has a Deprecated: paragraph, from packages not deprecated themselves")
+	fs.StringVar(&flagRules, "rules", "", "`FILE` of rules like deny pkg/api/** -> pkg/store/** \"use the service layer\", one per line, the first matching an import deciding")
+	fs.Var(&flagPlugins, "plugin", "`COMMAND` reading the graph as JSON on stdin and printing findings on stdout, a JSON object with a message per line, reported as violations of the rule named after it, repeatable")
+	fs.Var(&flagPolicies, "policy", "`FILE` of Rego policies, or directory of them, whose data.baobab.deny rule reports violations in the graph as JSON, evaluated with opa, repeatable")
+}
+
+// checkFlags defines how the check command reports violations.
+func checkFlags(fs *flag.FlagSet) {
+	fs.Var(&flagFailOn, "fail-on", "`RULES` whose violations make check exit 1, rule names or the groups forbidden-imports and cycles, all if not given")
+	fs.StringVar(&flagReport, "report", "text", "how to print violations: text, or github for GitHub Actions workflow commands, shown as annotations on pull requests")
+	fs.BoolVar(&flagStaged, "staged", false, "only check the packages of the go files staged in git, and the imports they make, as a pre-commit hook")
+	fs.StringVar(&flagBaseline, "baseline", "", "`FILE` of known violations, only failing on new ones")
+	fs.BoolVar(&flagUpdateBase, "update-baseline", false, "record the violations found in the -baseline file instead of reporting them")
+}
+
+// watchFlags defines the flags of the watch command.
+func watchFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&flagInterval, "interval", time.Second, "how long files must stay unchanged before scanning again")
+}
+
+// The graph types, used throughout baobab.
+type (
+	Graph  = graphs.Graph
+	Node   = graphs.Node
+	Edge   = graphs.Edge
+	Import = graphs.Import
 )
 
 var (
-	edges      = map[string]struct{}{}
-	dirsParsed = map[string]struct{}{}
+	graph = graphs.New()
+
+	// scanner scans what the flags ask for, set up by prepare.
+	scanner *scan.Scanner
+
+	// recordFiles makes the scanner record the files it considers, for
+	// commands reading them after the scan.
+	recordFiles bool
+)
+
+// Exit codes, so CI can tell a broken architecture from a failed run.
+const (
+	exitViolations = 1 // check found violations -fail-on cares about
+	exitError      = 2 // baobab could not do its job, including bad usage
 )
 
 func main() {
-	flag.Parse()
-	if err := parseDir(*flagEntryDir, 0); err != nil {
-		log.Fatal(err)
+	name := "graph"
+	if len(os.Args) > 1 && (os.Args[1] == "-version" || os.Args[1] == "--version") {
+		os.Args[1] = "version"
 	}
-	fmt.Println("digraph G {")
-	for e := range edges {
-		e = strings.ReplaceAll(e, string(os.PathSeparator), "_")
-		e = strings.ReplaceAll(e, "-", "_")
-		e = strings.ReplaceAll(e, " _> ", " -> ")
-		e = strings.ReplaceAll(e, ".", "_")
-		fmt.Println(e)
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		name = os.Args[1]
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+	cmd := findCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", name)
+		usage()
+		os.Exit(exitError)
+	}
+	defineFlags()
+	fs := flagSets[cmd.name]
+	fs.Parse(os.Args[1:])
+	if err := loadEnv(fs); err != nil {
+		setupLogging()
+		fatal(err)
+	}
+	setupLogging()
+	if fs.Lookup("config") != nil {
+		if err := loadConfig(fs, flagConfig, flagNames()); err != nil {
+			fatal(err)
+		}
+		// The config file may set logging flags too.
+		setupLogging()
+	}
+	stopProfiling, err := startProfiling()
+	if err != nil {
+		fatal(err)
+	}
+	err = cmd.run(fs, fs.Args())
+	if perr := stopProfiling(); perr != nil && (err == nil || err == errDryRun || err == errViolations) {
+		err = perr
+	}
+	switch err {
+	case nil, errDryRun:
+		writeDiagnostics(nil)
+	case errViolations:
+		writeDiagnostics(nil)
+		os.Exit(exitViolations)
+	default:
+		fatal(err)
 	}
-	fmt.Println("}")
 }
 
-func parseDir(dir string, depth int) error {
-	if *flagDepth > 0 && depth > *flagDepth {
-		return nil
+// prepare gets ready to scan as the flags of fs say, source being the module
+// version or archive to scan, if any, instead of the current module: it
+// changes to the root of what to scan, sets up scanner and returns the
+// function to call once done with the files.
+func prepare(fs *flag.FlagSet, source string) (cleanup func(), err error) {
+	cleanup = func() {}
+	gomodSet := false
+	fs.Visit(func(f *flag.Flag) { gomodSet = gomodSet || f.Name == "gomod" })
+	switch flagBackend {
+	case "scanner", "parser", "packages":
+	default:
+		return nil, fmt.Errorf("unknown backend %q", flagBackend)
+	}
+	if flagWorkers < 1 {
+		return nil, fmt.Errorf("-workers must be at least 1, got %d", flagWorkers)
+	}
+	var goList []byte
+	if flagGoList != "" {
+		if source != "" || flagRepo != "" {
+			return nil, fmt.Errorf("cannot take go list output for another source")
+		}
+		if goList, err = readGoList(flagGoList); err != nil {
+			return nil, err
+		}
+	}
+	if flagEntries, err = readEntries(flagEntries, os.Stdin); err != nil {
+		return nil, err
+	}
+	switch flagStdlib {
+	case "off", "all", "top", "single":
+	default:
+		return nil, fmt.Errorf("unknown -stdlib mode %q", flagStdlib)
+	}
+	if source != "" && flagRepo != "" {
+		return nil, fmt.Errorf("cannot scan both a -repo and %s", source)
+	}
+	if flagChurn != "" && (source != "" || flagRepo != "") {
+		return nil, fmt.Errorf("cannot count the churn of another source than the git repository here")
+	}
+	if flagCoupling && flagGoList != "" {
+		return nil, fmt.Errorf("cannot type check go list output")
+	}
+	if flagVulns != "" && flagVulns != "-" {
+		if flagVulns, err = filepath.Abs(flagVulns); err != nil {
+			return nil, err
+		}
+	}
+	if flagBaseline != "" {
+		if flagBaseline, err = filepath.Abs(flagBaseline); err != nil {
+			return nil, err
+		}
+	}
+	for i, p := range flagPolicies {
+		if flagPolicies[i], err = filepath.Abs(p); err != nil {
+			return nil, err
+		}
+	}
+	if flagOut != "" {
+		// Relative to where baobab runs, not the root it changes to.
+		if flagOut, err = filepath.Abs(flagOut); err != nil {
+			return nil, err
+		}
+	}
+	var dir string
+	switch {
+	case flagRepo != "":
+		dir, err = cloneRepo(flagRepo, flagRef)
+		cleanup = func() { os.RemoveAll(dir) }
+	case isArchive(source):
+		dir, err = extractArchive(source)
+		cleanup = func() { os.RemoveAll(dir) }
+	case source != "":
+		// Older modules may have no go.mod to read the path from.
+		flagGoModName, dir, err = downloadModule(source)
+		gomodSet = true
 	}
-	fis, err := ioutil.ReadDir(dir)
 	if err != nil {
-		return fmt.Errorf("failed to read dir %s: %s", dir, err)
+		return nil, err
 	}
-	for _, fi := range fis {
-		if fi.IsDir() {
-			continue
+	fail := func(err error) (func(), error) {
+		cleanup()
+		return nil, err
+	}
+	if dir != "" {
+		if err := os.Chdir(dir); err != nil {
+			return fail(err)
 		}
-		if !strings.HasSuffix(fi.Name(), ".go") {
-			continue
+	}
+	base, err := chdirRoot(gomodSet)
+	if err != nil {
+		return fail(err)
+	}
+	if configFlags["entry"] && dir == "" {
+		// Entries in the config file are relative to it, unless scanning
+		// another source.
+		if base, err = relToCwd(configDir); err != nil {
+			return fail(err)
 		}
-		if strings.HasSuffix(fi.Name(), "_test.go") {
+	}
+	opts := scan.Options{
+		Dir:            base,
+		Entries:        flagEntries,
+		Depth:          flagDepth,
+		GOOS:           flagGOOS,
+		GOARCH:         flagGOARCH,
+		Tests:          flagTests,
+		Stdlib:         flagStdlib,
+		External:       flagExternal,
+		Vendor:         flagVendor,
+		Cgo:            flagCgo,
+		Proto:          flagProto,
+		Exclude:        flagExclude,
+		Only:           flagOnly,
+		IncludeSpecial: flagIncludeSpecial,
+		SkipGenerated:  flagSkipGen,
+		Lenient:        flagLenient,
+		Backend:        flagBackend,
+		GoList:         goList,
+		Workers:        flagWorkers,
+		Cache:          setupCache(flagCache),
+		RecordFiles:    flagDryRun || recordFiles || flagForbidDeprecated,
+		Logger:         logger,
+		OnWarning:      func(w scan.Warning) { warn(w.Kind, w.Path, w.Message, w.Err) },
+	}
+	if flagTags != "" {
+		opts.Tags = strings.Split(flagTags, ",")
+	}
+	if gomodSet || !hasFile("go.mod") && !hasFile("go.work") && !inGOPATH() {
+		opts.Module = flagGoModName
+	}
+	opts.Progress = scanHook{}
+	if scanner, err = scan.New(opts); err != nil {
+		return fail(err)
+	}
+	return cleanup, nil
+}
+
+// chdirRoot changes to the root of the workspace, or if -gomod was given or
+// there is none, of the module baobab runs in, so it can run from any of
+// their directories. It returns the directory it ran in, relative to the
+// root.
+func chdirRoot(gomodSet bool) (string, error) {
+	root, err := scan.FindRoot(".", !gomodSet)
+	if err != nil {
+		return "", err
+	}
+	cwd, err := os.Getwd()
+	if err != nil || root == cwd {
+		return ".", err
+	}
+	if err := os.Chdir(root); err != nil {
+		return "", err
+	}
+	return filepath.Rel(root, cwd)
+}
+
+// inGOPATH reports whether the current directory is in a GOPATH project,
+// whose import path the scanner derives from where it is.
+func inGOPATH() bool {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return false
+	}
+	_, ok := scan.GOPATHImportPath(cwd)
+	return ok
+}
+
+// hasFile reports whether file exists.
+func hasFile(file string) bool {
+	_, err := os.Stat(file)
+	return err == nil
+}
+
+// readEntries replaces an entry "-" with the entries listed in r, one per
+// line, as in git diff --name-only | xargs dirname | sort -u.
+func readEntries(entries []string, r io.Reader) ([]string, error) {
+	var result []string
+	for _, entry := range entries {
+		if entry != "-" {
+			result = append(result, entry)
 			continue
 		}
-		file := filepath.Join(dir, fi.Name())
-		imports, err := parseFile(file)
-		if err != nil {
-			return fmt.Errorf("failed to parse file %s: %s", file, err)
-		}
-		for _, imp := range imports {
-			if !strings.HasPrefix(imp, *flagGoModName) {
-				continue
-			}
-			nextDir := strings.TrimPrefix(imp, *flagGoModName)
-			nextDir = strings.TrimPrefix(nextDir, "/")
-			if nextDir == dir {
-				continue
+		n := len(result)
+		sc := bufio.NewScanner(r)
+		for sc.Scan() {
+			if line := strings.TrimSpace(sc.Text()); line != "" {
+				result = append(result, line)
 			}
-			edges[fmt.Sprintf("%s -> %s", dir, nextDir)] = struct{}{}
-			if _, parsed := dirsParsed[nextDir]; !parsed {
-				if err := parseDir(nextDir, depth+1); err != nil {
-					return err
-				}
+		}
+		if err := sc.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read entries from stdin: %s", err)
+		}
+		if len(result) == n {
+			// Scanning every package instead would hide an empty input.
+			return nil, fmt.Errorf("no entries on stdin")
+		}
+	}
+	return result, nil
+}
+
+// readGoList reads the go list output in file, stdin if "-", before
+// prepare changes directories and reads entries from stdin.
+func readGoList(file string) ([]byte, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if file == "-" {
+		for _, entry := range flagEntries {
+			if entry == "-" {
+				return nil, fmt.Errorf("cannot read both entries and go list output from stdin")
 			}
 		}
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read go list output: %s", err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, fmt.Errorf("empty go list output")
+	}
+	return data, nil
+}
+
+// scanGraph fills the graph as the flags of fs say, see prepare.
+func scanGraph(fs *flag.FlagSet, source string) error {
+	cleanup, err := prepare(fs, source)
+	if err != nil {
+		return err
+	}
+	scanProgress = startProgress()
+	err = scanAll()
+	scanProgress.Stop()
+	scanProgress = nil
+	// Everything needed from the files is in the graph now.
+	cleanup()
+	if err != nil {
+		return err
+	}
+	if flagDryRun {
+		if err := writeOutput(printScannedFiles); err != nil {
+			return err
+		}
+		return errDryRun
+	}
+	if flagSkipGen {
+		printGeneratedReport(os.Stderr)
 	}
-	dirsParsed[dir] = struct{}{}
 	return nil
 }
 
-func parseFile(file string) ([]string, error) {
-	fileReader, err := os.Open(file)
+// scanAll scans everything again with scanner, replacing the graph.
+func scanAll() error {
+	g, err := scanner.Scan(context.Background())
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file %s: %s", file, err)
+		return err
 	}
-	defer fileReader.Close()
-	var (
-		result []string
-		scan   = NewScanner(bufio.NewReader(fileReader))
-	)
-	for {
-		token := scan.Next()
-		switch token.Type {
-		case EOF:
-			return result, nil
-		case Error:
-			return nil, fmt.Errorf("scan file %s error: %s", file, token)
-		case Word:
-			switch token.Text {
-			case "package":
-				nextToken := scan.Next()
-				if nextToken.Type != Word {
-					return nil, fmt.Errorf("expected a word after 'package' got %s", token)
-				}
-			case "import":
-				partial, err := parseImport(scan)
-				if err != nil {
-					return nil, err
-				}
-				result = append(result, partial...)
-			case "var", "const", "func", "type":
-				return result, nil
-			}
-		default:
-			return nil, fmt.Errorf("unexpected token %s", token)
+	graph = g
+	return annotate(g)
+}
+
+// annotate adds what -churn, -vulns and -govulncheck ask for to g, scanned
+// from the current directory, and reads the deprecations -forbid-deprecated
+// checks while the files are there.
+func annotate(g *Graph) error {
+	if err := applyChurn(g); err != nil {
+		return err
+	}
+	if err := applyCoupling(g); err != nil {
+		return err
+	}
+	if flagForbidDeprecated {
+		if err := readDeprecations(g); err != nil {
+			return err
 		}
 	}
+	return applyVulns(g)
 }
 
-func parseImport(scan *Scanner) ([]string, error) {
-	token := scan.Next()
-	switch token.Type {
-	case EOF:
-		return nil, fmt.Errorf("unexpected EOF after 'import'")
-	case Error:
-		return nil, fmt.Errorf("scan element after 'import' error: %s", token)
-	case Word:
-		nextToken := scan.Next()
-		if nextToken.Type != String {
-			return nil, fmt.Errorf("expected string after import alias: %s", token)
+// printScannedFiles writes the files a -dry-run scan considered, grouped by
+// directory, with why they were left out if they were.
+func printScannedFiles(w io.Writer) error {
+	var dir string
+	for _, f := range scanner.Files() {
+		if f.Dir != dir {
+			dir = f.Dir
+			fmt.Fprintf(w, "%s/\n", filepath.ToSlash(dir))
 		}
-		return []string{strings.Trim(nextToken.Text, "`\"")}, nil
-	case String:
-		return []string{strings.Trim(token.Text, "`\"")}, nil
-	case LeftParen:
-		return parseImportParen(scan)
-	default:
-		return nil, fmt.Errorf("unexpected token while scanning 'import' %s", token)
+		if f.Skipped != "" {
+			fmt.Fprintf(w, "  %s (%s)\n", filepath.Base(f.Path), f.Skipped)
+		} else {
+			fmt.Fprintf(w, "  %s\n", filepath.Base(f.Path))
+		}
+	}
+	return nil
+}
+
+// printGeneratedReport writes how many files -skip-generated skipped and the
+// edges only they produce, which are missing from the graph.
+func printGeneratedReport(w io.Writer) {
+	files, only := scanner.Generated()
+	fmt.Fprintf(w, "skipped %d generated files, %d edges only come from generated code\n", files, len(only))
+	for _, e := range only {
+		fmt.Fprintf(w, "  %s -> %s\n", e[0], e[1])
 	}
 }
 
-func parseImportParen(scan *Scanner) ([]string, error) {
-	var result []string
-	for {
-		token := scan.Next()
-		switch token.Type {
-		case EOF:
-			return nil, fmt.Errorf("unexpected EOF after 'import ('")
-		case Error:
-			return nil, fmt.Errorf("scan element after 'import (' error: %s", token)
-		case Word:
-			nextToken := scan.Next()
-			if nextToken.Type != String {
-				return nil, fmt.Errorf("expected string after import alias: %s", token)
-			}
-			result = append(result, strings.Trim(nextToken.Text, "`\""))
-		case String:
-			result = append(result, strings.Trim(token.Text, "`\""))
-		case RightParen:
-			return result, nil
-		default:
-			return nil, fmt.Errorf("unexpected token while scanning 'import (' %s", token)
+// setupCache returns the directory -cache asks for, empty if off.
+func setupCache(dir string) string {
+	switch dir {
+	case "off":
+		return ""
+	case "":
+		base, err := os.UserCacheDir()
+		if err != nil {
+			warn("cache", "", "no parse cache", err)
+			return ""
 		}
+		return filepath.Join(base, "baobab")
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
+
+// writeOutput calls write with -o, replaced once write succeeds, or stdout.
+func writeOutput(write func(io.Writer) error) error {
+	if flagOut == "" {
+		return write(os.Stdout)
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(flagOut), ".baobab-")
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+	err = write(tmp)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
 	}
+	return os.Rename(tmp.Name(), flagOut)
 }
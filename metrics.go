@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	graphs "github.com/sequix/baobab/graph"
+)
+
+// health sums up the architecture of a scanned graph for /metrics.
+type health struct {
+	packages   int
+	edges      int
+	cycles     int            // import cycles, test-only imports aside
+	depth      int            // imports in the longest chain without cycles
+	violations map[string]int // per enabled rule
+	scanned    time.Time
+	duration   time.Duration
+}
+
+// newHealth measures g, scanned in duration, checking the enabled rules.
+func newHealth(g *Graph, duration time.Duration) health {
+	h := health{
+		packages:   len(g.Nodes()),
+		edges:      len(g.Edges()),
+		depth:      maxDepth(g),
+		violations: map[string]int{},
+		scanned:    time.Now(),
+		duration:   duration,
+	}
+	for _, scc := range graphs.StronglyConnected(g, func(e *Edge) bool { return !e.Test }) {
+		if len(scc) > 1 {
+			h.cycles++
+		}
+	}
+	for _, rule := range enabledRules() {
+		h.violations[rule] = 0
+	}
+	violations := runChecks(g, enabledChecks())
+	external, err := checkExternal(g)
+	if err != nil {
+		warn("check", "", "failed to run external checks", err)
+	}
+	for _, v := range append(violations, external...) {
+		h.violations[v.Rule]++
+	}
+	return h
+}
+
+// enabledRules returns the rules the enabled checks report violations of.
+func enabledRules() []string {
+	var result []string
+	for _, r := range []struct {
+		enabled bool
+		rules   []string
+	}{
+		{flagForbidBlank, []string{"blank-import"}},
+		{flagForbidDot, []string{"dot-import"}},
+		{flagCheckInternal, []string{"internal", "boundary"}},
+		{flagCanonical, []string{"canonical"}},
+		{flagForbidCycles, []string{"cycle"}},
+		{flagRules != "", []string{"import-rule"}},
+		{len(flagPolicies) > 0, []string{"policy"}},
+	} {
+		if r.enabled {
+			result = append(result, r.rules...)
+		}
+	}
+	for _, command := range flagPlugins {
+		result = append(result, pluginName(command))
+	}
+	return result
+}
+
+// maxDepth returns the number of imports in the longest chain of g, each
+// cycle counting as a single package.
+func maxDepth(g *Graph) int {
+	result := 0
+	for _, d := range importDepths(g) {
+		result = max(result, d)
+	}
+	return result
+}
+
+// importDepths returns the number of imports in the longest chain of g from
+// each package, each cycle counting as a single package, so those of a
+// cycle share theirs.
+func importDepths(g *Graph) map[string]int {
+	component := map[string]int{}
+	sccs := graphs.StronglyConnected(g, func(*Edge) bool { return true })
+	for i, scc := range sccs {
+		for _, name := range scc {
+			component[name] = i
+		}
+	}
+	depth := make([]int, len(sccs))
+	done := make([]bool, len(sccs))
+	var visit func(c int) int
+	visit = func(c int) int {
+		if !done[c] {
+			done[c] = true
+			for _, name := range sccs[c] {
+				for _, to := range g.Succ(name) {
+					if next := component[to]; next != c {
+						depth[c] = max(depth[c], visit(next)+1)
+					}
+				}
+			}
+		}
+		return depth[c]
+	}
+	result := map[string]int{}
+	for name, c := range component {
+		result[name] = visit(c)
+	}
+	return result
+}
+
+// writeMetrics writes h in the Prometheus text exposition format.
+func writeMetrics(w io.Writer, h health) error {
+	var b strings.Builder
+	gauge := func(name, help string, value any) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n%s %v\n", name, help, name, name, value)
+	}
+	gauge("baobab_packages", "Number of packages in the graph.", h.packages)
+	gauge("baobab_edges", "Number of imports between packages in the graph.", h.edges)
+	gauge("baobab_cycles", "Number of import cycles, test-only imports aside.", h.cycles)
+	gauge("baobab_max_depth", "Number of imports in the longest chain of imports, cycles counting as one package.", h.depth)
+	fmt.Fprintf(&b, "# HELP baobab_violations Number of violations of each enabled rule.\n# TYPE baobab_violations gauge\n")
+	rules := make([]string, 0, len(h.violations))
+	for rule := range h.violations {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	for _, rule := range rules {
+		fmt.Fprintf(&b, "baobab_violations{rule=%q} %d\n", rule, h.violations[rule])
+	}
+	gauge("baobab_last_scan_timestamp_seconds", "When the last scan ended, in seconds since the epoch.", h.scanned.Unix())
+	gauge("baobab_last_scan_duration_seconds", "How long the last scan took, in seconds.", h.duration.Seconds())
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// downloadModule downloads a module version, as in example.com/mod@v1.2.3,
+// through GOPROXY into the module cache, like go mod download does, and
+// returns its module path and the directory it was extracted to.
+func downloadModule(version string) (path, dir string, err error) {
+	i := strings.LastIndex(version, "@")
+	if i <= 0 || i == len(version)-1 {
+		return "", "", fmt.Errorf("bad module version %q, want path@version", version)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("go", "mod", "download", "-json", version)
+	// Outside of any module or workspace, which would get in the way.
+	cmd.Dir = os.TempDir()
+	cmd.Env = append(os.Environ(), "GO111MODULE=on", "GOWORK=off", "GOFLAGS=-mod=mod")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	logger.Debug("downloading module", "cmd", cmd.Args)
+	err = cmd.Run()
+	var mod struct {
+		Path  string
+		Dir   string
+		Error string
+	}
+	if jerr := json.Unmarshal(stdout.Bytes(), &mod); jerr == nil && mod.Error != "" {
+		return "", "", fmt.Errorf("failed to download %s: %s", version, mod.Error)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to download %s: %s: %s", version, err, strings.TrimSpace(stderr.String()))
+	}
+	if mod.Dir == "" {
+		return "", "", fmt.Errorf("failed to download %s: no directory in go mod download output", version)
+	}
+	return mod.Path, mod.Dir, nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sequix/baobab/internal/glob"
+)
+
+var (
+	flagCodeOwners  string
+	flagGroupOwners bool
+)
+
+// codeOwnersFlags defines the flag naming the CODEOWNERS file.
+func codeOwnersFlags(fs *flag.FlagSet) {
+	fs.StringVar(&flagCodeOwners, "codeowners", "", "CODEOWNERS `FILE` naming the owners of the packages, found in the root, .github or docs of the repository if empty for the owners command")
+}
+
+// codeOwners are the rules of a CODEOWNERS file.
+type codeOwners struct {
+	root  string // absolute directory the patterns are relative to
+	rules []ownerRule
+}
+
+// ownerRule is a line of a CODEOWNERS file.
+type ownerRule struct {
+	glob    string // the pattern, as a glob of paths relative to the root
+	dirOnly bool   // whether the pattern only matches directories
+	owners  []string
+}
+
+// readCodeOwners reads the CODEOWNERS file, its patterns relative to the
+// directory it is in, or to the one above for those in .github or docs.
+func readCodeOwners(file string) (*codeOwners, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(abs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CODEOWNERS: %s", err)
+	}
+	defer f.Close()
+	co := &codeOwners{root: filepath.Dir(abs)}
+	if base := filepath.Base(co.root); base == ".github" || base == "docs" {
+		co.root = filepath.Dir(co.root)
+	}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.Index(line, "#"); i >= 0 && (i == 0 || line[i-1] != '\\') {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		// GitLab sections, [Name] owners..., only set default owners.
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "[") || strings.HasPrefix(fields[0], "^[") {
+			continue
+		}
+		co.rules = append(co.rules, newOwnerRule(fields[0], fields[1:]))
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read CODEOWNERS: %s", err)
+	}
+	return co, nil
+}
+
+// findCodeOwners returns the CODEOWNERS file of the repository of the current
+// directory, looking in it and the directories above.
+func findCodeOwners() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		for _, sub := range []string{"", ".github", "docs"} {
+			file := filepath.Join(dir, sub, "CODEOWNERS")
+			if hasFile(file) {
+				return file, nil
+			}
+		}
+		if hasFile(filepath.Join(dir, ".git")) || filepath.Dir(dir) == dir {
+			return "", fmt.Errorf("no CODEOWNERS file found, give one with -codeowners")
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// newOwnerRule turns a gitignore style pattern into a rule: anchored to the
+// root if it has a slash other than at its end, matching anywhere below it
+// otherwise.
+func newOwnerRule(pattern string, owners []string) ownerRule {
+	r := ownerRule{owners: owners}
+	if strings.HasSuffix(pattern, "/") {
+		r.dirOnly = true
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	r.glob = strings.TrimPrefix(pattern, "/")
+	return r
+}
+
+// ownersOf returns the owners of the package in dir, relative to the root,
+// per the last rule matching it or a directory above, or its go files.
+func (co *codeOwners) ownersOf(dir string) []string {
+	dir = filepath.ToSlash(dir)
+	var owners []string
+	for _, r := range co.rules {
+		if glob.Match(r.glob, dir) || glob.Match(r.glob+"/**", dir) ||
+			!r.dirOnly && glob.Match(r.glob, path.Join(dir, "x.go")) {
+			owners = r.owners
+		}
+	}
+	return owners
+}
+
+// applyOwners sets the owners of the packages of g scanned, their names
+// directories relative to the current one.
+func applyOwners(g *Graph, co *codeOwners) {
+	for _, name := range g.Nodes() {
+		n := g.Node(name)
+		if n.External || n.Std || n.Marker || n.Proto {
+			continue
+		}
+		abs, err := filepath.Abs(name)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(co.root, abs); err == nil && !strings.HasPrefix(rel, "..") {
+			n.Owners = co.ownersOf(rel)
+		}
+	}
+}
+
+// ownerGroup returns the group of -group-owners of n, its first owner.
+func ownerGroup(n *Node) string {
+	if len(n.Owners) == 0 {
+		return ""
+	}
+	return n.Owners[0]
+}
+
+func runOwners(fs *flag.FlagSet, args []string) error {
+	source, err := sourceArg(args)
+	if err != nil {
+		return err
+	}
+	p, err := newPainter()
+	if err != nil {
+		return err
+	}
+	var co *codeOwners
+	if flagCodeOwners != "" {
+		// Relative to where baobab runs, not the root it changes to.
+		if co, err = readCodeOwners(flagCodeOwners); err != nil {
+			return err
+		}
+	}
+	if err := scanGraph(fs, source); err != nil {
+		return err
+	}
+	if co == nil {
+		file, err := findCodeOwners()
+		if err != nil {
+			return err
+		}
+		if co, err = readCodeOwners(file); err != nil {
+			return err
+		}
+	}
+	applyOwners(graph, co)
+	return writeOutput(func(w io.Writer) error { return printCrossOwners(w, p, graph) })
+}
+
+// printCrossOwners writes the imports between packages sharing no owner,
+// grouped by the owners of both ends, leaving out packages nobody owns.
+func printCrossOwners(w io.Writer, p painter, g *Graph) error {
+	type crossing struct {
+		from, to string // owners
+		edge     *Edge
+	}
+	var rows []crossing
+	for _, e := range g.Edges() {
+		from, to := g.Node(e.From).Owners, g.Node(e.To).Owners
+		if len(from) == 0 || len(to) == 0 || shareOwner(from, to) {
+			continue
+		}
+		rows = append(rows, crossing{strings.Join(from, " "), strings.Join(to, " "), e})
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].from != rows[j].from {
+			return rows[i].from < rows[j].from
+		}
+		return rows[i].to < rows[j].to
+	})
+	table := [][]cell{{{"FROM OWNERS", bold}, {"TO OWNERS", bold}, {"IMPORT", bold}}}
+	for _, r := range rows {
+		kind := dim
+		if !r.edge.Test {
+			kind = plain
+		}
+		table = append(table, []cell{{r.from, cyan}, {r.to, cyan}, {r.edge.From + " -> " + r.edge.To, kind}})
+	}
+	if err := p.printTable(w, table); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%d imports across owners\n", len(rows))
+	return err
+}
+
+// shareOwner reports whether a and b have an owner in common.
+func shareOwner(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var flagPlugins listFlag
+
+// pluginFinding is a line of what a plugin prints.
+type pluginFinding struct {
+	Message  string   `json:"message"`
+	Packages []string `json:"packages"`
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Warning  bool     `json:"warning"`
+}
+
+// pluginName returns the name of the rule a plugin run by command reports
+// violations of, the base name of its program.
+func pluginName(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(filepath.Base(fields[0]), ".exe")
+}
+
+// checkPlugins runs the commands of -plugin in the root of the scan, each
+// reading g as JSON output writes it on stdin and printing its findings on
+// stdout, a JSON object per line, which become violations of the rule named
+// after it. What they print on stderr goes to ours.
+func checkPlugins(g *Graph) ([]Violation, error) {
+	if len(flagPlugins) == 0 {
+		return nil, nil
+	}
+	var input bytes.Buffer
+	if err := writeJSON(&input, g); err != nil {
+		return nil, err
+	}
+	var result []Violation
+	for _, command := range flagPlugins {
+		name := pluginName(command)
+		fields := strings.Fields(command)
+		var stdout bytes.Buffer
+		cmd := exec.Command(fields[0], fields[1:]...)
+		logger.Debug("running plugin", "cmd", cmd.Args)
+		cmd.Stdin = bytes.NewReader(input.Bytes())
+		cmd.Stdout, cmd.Stderr = &stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("failed to run plugin %s: %s", name, err)
+		}
+		sc := bufio.NewScanner(&stdout)
+		sc.Buffer(nil, 1<<20)
+		for line := 1; sc.Scan(); line++ {
+			text := strings.TrimSpace(sc.Text())
+			if text == "" {
+				continue
+			}
+			var f pluginFinding
+			if err := json.Unmarshal([]byte(text), &f); err != nil || f.Message == "" {
+				return nil, fmt.Errorf("plugin %s printed a bad finding on line %d, want a JSON object with a message: %s", name, line, text)
+			}
+			result = append(result, f.violation(g, name))
+		}
+		if err := sc.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read the findings of plugin %s: %s", name, err)
+		}
+	}
+	return result, nil
+}
+
+// violation returns f as a violation of rule, at the import from its first
+// package to the second if it has no file and two packages.
+func (f pluginFinding) violation(g *Graph, rule string) Violation {
+	v := Violation{Rule: rule, File: filepath.FromSlash(f.File), Line: f.Line, Message: f.Message, Warning: f.Warning}
+	for _, pkg := range f.Packages {
+		v.Packages = append(v.Packages, filepath.FromSlash(pkg))
+	}
+	if v.File == "" && len(v.Packages) == 2 {
+		if e := g.Edge(v.Packages[0], v.Packages[1]); e != nil {
+			v.File, v.Line = importPos(g, e)
+		}
+	}
+	return v
+}
+
+// checkExternal runs the checks of -policy and -plugin, which may fail to
+// run unlike the others.
+func checkExternal(g *Graph) ([]Violation, error) {
+	policies, err := checkPolicies(g)
+	if err != nil {
+		return nil, err
+	}
+	plugins, err := checkPlugins(g)
+	if err != nil {
+		return nil, err
+	}
+	return append(policies, plugins...), nil
+}
+
+// knownPlugin reports whether rule is named after one of the plugins of
+// -plugin.
+func knownPlugin(rule string) bool {
+	for _, command := range flagPlugins {
+		if pluginName(command) == rule {
+			return true
+		}
+	}
+	return false
+}
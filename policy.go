@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var flagPolicies listFlag
+
+// policyQuery is the rule Rego policies define violations with, as a set of
+// messages, or of objects with a msg and the packages involved.
+const policyQuery = "data.baobab.deny"
+
+// opaOutput is what opa eval --format json prints.
+type opaOutput struct {
+	Result []struct {
+		Expressions []struct {
+			Value []json.RawMessage `json:"value"`
+		} `json:"expressions"`
+	} `json:"result"`
+}
+
+// policyDenial is an element of the deny set of a policy, when not a
+// string.
+type policyDenial struct {
+	Msg      string   `json:"msg"`
+	Packages []string `json:"packages"`
+}
+
+// checkPolicies evaluates the Rego policies of -policy against g as JSON
+// output writes it, with opa, and reports a violation of the rule "policy"
+// per element of their deny sets.
+func checkPolicies(g *Graph) ([]Violation, error) {
+	if len(flagPolicies) == 0 {
+		return nil, nil
+	}
+	var input bytes.Buffer
+	if err := writeJSON(&input, g); err != nil {
+		return nil, err
+	}
+	args := []string{"eval", "--format", "json", "--stdin-input"}
+	for _, p := range flagPolicies {
+		args = append(args, "--data", p)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("opa", append(args, policyQuery)...)
+	logger.Debug("running opa", "cmd", cmd.Args)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = &input, &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			// opa prints its errors along with the results.
+			msg = strings.TrimSpace(stdout.String())
+		}
+		if msg == "" {
+			return nil, fmt.Errorf("failed to evaluate policies: %s", err)
+		}
+		return nil, fmt.Errorf("failed to evaluate policies: %s: %s", err, msg)
+	}
+	var out opaOutput
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return nil, fmt.Errorf("failed to parse opa output: %s", err)
+	}
+	var result []Violation
+	for _, r := range out.Result {
+		for _, expr := range r.Expressions {
+			for _, value := range expr.Value {
+				v, err := policyViolation(g, value)
+				if err != nil {
+					return nil, err
+				}
+				result = append(result, v)
+			}
+		}
+	}
+	return result, nil
+}
+
+// policyViolation turns an element of a deny set into a violation, at the
+// import from the first package to the second if there are two.
+func policyViolation(g *Graph, value json.RawMessage) (Violation, error) {
+	var d policyDenial
+	if err := json.Unmarshal(value, &d.Msg); err != nil {
+		if err := json.Unmarshal(value, &d); err != nil || d.Msg == "" {
+			return Violation{}, fmt.Errorf("want a string or an object with a msg in %s, got %s", policyQuery, value)
+		}
+	}
+	v := Violation{Rule: "policy", Message: d.Msg}
+	for _, pkg := range d.Packages {
+		v.Packages = append(v.Packages, filepath.FromSlash(pkg))
+	}
+	if len(v.Packages) == 2 {
+		if e := g.Edge(v.Packages[0], v.Packages[1]); e != nil {
+			v.File, v.Line = importPos(g, e)
+		}
+	}
+	return v, nil
+}
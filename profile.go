@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+)
+
+var (
+	flagCPUProfile string
+	flagMemProfile string
+)
+
+// startProfiling starts the CPU profile asked for with -cpuprofile and
+// returns the function to call once done, which stops it and writes the
+// -memprofile heap profile, if asked for.
+func startProfiling() (stop func() error, err error) {
+	// Scans change to the module root, profiles go where baobab runs.
+	memProfile := flagMemProfile
+	if memProfile != "" {
+		if memProfile, err = filepath.Abs(memProfile); err != nil {
+			return nil, err
+		}
+	}
+	var cpu *os.File
+	if flagCPUProfile != "" {
+		if cpu, err = os.Create(flagCPUProfile); err != nil {
+			return nil, fmt.Errorf("failed to create CPU profile: %s", err)
+		}
+		if err := pprof.StartCPUProfile(cpu); err != nil {
+			cpu.Close()
+			return nil, fmt.Errorf("failed to start CPU profile: %s", err)
+		}
+	}
+	stop = func() error {
+		if cpu != nil {
+			pprof.StopCPUProfile()
+			if err := cpu.Close(); err != nil {
+				return fmt.Errorf("failed to write CPU profile: %s", err)
+			}
+		}
+		if memProfile == "" {
+			return nil
+		}
+		f, err := os.Create(memProfile)
+		if err != nil {
+			return fmt.Errorf("failed to create memory profile: %s", err)
+		}
+		defer f.Close()
+		// Get up-to-date statistics of what is still in use.
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			return fmt.Errorf("failed to write memory profile: %s", err)
+		}
+		return f.Close()
+	}
+	return stop, nil
+}
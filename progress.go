@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// progressDelay is how long a scan runs before showing its progress.
+const progressDelay = time.Second
+
+// progress shows how far a scan is on stderr, the number of directories
+// scanned out of those known so far and the file being parsed. A nil
+// progress shows nothing.
+type progress struct {
+	mu        sync.Mutex
+	done      int
+	total     int
+	remaining map[string]int // files left to parse, by directory
+	current   string
+	stop      chan struct{}
+	stopped   sync.WaitGroup
+}
+
+// scanProgress is the progress of the running scan, nil if not shown.
+var scanProgress *progress
+
+// scanHook tells scanProgress, whatever it is at the time, how scans go.
+type scanHook struct{}
+
+func (scanHook) AddDir(dir string, files int) { scanProgress.AddDir(dir, files) }
+func (scanHook) Parsing(file string)          { scanProgress.Parsing(file) }
+func (scanHook) Parsed(dir string)            { scanProgress.Parsed(dir) }
+
+// startProgress starts showing the progress of a scan, unless -no-progress,
+// -q or stderr not being a terminal say otherwise.
+func startProgress() *progress {
+	if flagNoProgress || flagQuiet || flagVerbose || !isTerminal(os.Stderr) {
+		return nil
+	}
+	p := &progress{remaining: map[string]int{}, stop: make(chan struct{})}
+	p.stopped.Add(1)
+	go p.run()
+	return p
+}
+
+func (p *progress) run() {
+	defer p.stopped.Done()
+	var (
+		delay  = time.NewTimer(progressDelay)
+		ticker *time.Ticker
+		tick   <-chan time.Time
+	)
+	defer delay.Stop()
+	for {
+		select {
+		case <-p.stop:
+			if ticker != nil {
+				ticker.Stop()
+				fmt.Fprint(os.Stderr, "\r\033[K")
+			}
+			return
+		case <-delay.C:
+			ticker = time.NewTicker(100 * time.Millisecond)
+			tick = ticker.C
+			p.show()
+		case <-tick:
+			p.show()
+		}
+	}
+}
+
+func (p *progress) show() {
+	p.mu.Lock()
+	line := fmt.Sprintf("scanned %d/%d directories %s", p.done, p.total, p.current)
+	p.mu.Unlock()
+	if len(line) > 79 {
+		line = line[:76] + "..."
+	}
+	fmt.Fprintf(os.Stderr, "\r\033[K%s", line)
+}
+
+// Stop stops showing the progress, and clears it.
+func (p *progress) Stop() {
+	if p == nil {
+		return
+	}
+	close(p.stop)
+	p.stopped.Wait()
+}
+
+// AddDir counts a directory with files to parse.
+func (p *progress) AddDir(dir string, files int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total++
+	if files == 0 {
+		p.done++
+		return
+	}
+	p.remaining[dir] += files
+}
+
+// Parsing notes that file is being parsed.
+func (p *progress) Parsing(file string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = file
+}
+
+// Parsed notes that file of dir was parsed.
+func (p *progress) Parsed(dir string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.remaining[dir]--; p.remaining[dir] == 0 {
+		delete(p.remaining, dir)
+		p.done++
+	}
+}
+
+// isTerminal reports whether f is a terminal rather than a file or a pipe.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
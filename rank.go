@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+const (
+	pageRankDamping   = 0.85
+	pageRankTolerance = 1e-9
+	pageRankMaxIter   = 100
+)
+
+// Rank holds the importance scores of a single package.
+type Rank struct {
+	Node        string
+	PageRank    float64
+	Betweenness float64
+}
+
+// PageRank computes the PageRank of every node, following import edges,
+// so packages imported by many (important) packages score high.
+// Scores of nodes without outgoing edges are spread over all nodes.
+func PageRank(g *Graph) map[string]float64 {
+	nodes := g.Nodes()
+	n := float64(len(nodes))
+	rank := make(map[string]float64, len(nodes))
+	for _, node := range nodes {
+		rank[node] = 1 / n
+	}
+	for iter := 0; iter < pageRankMaxIter; iter++ {
+		dangling := 0.0
+		for _, node := range nodes {
+			if len(g.Succ(node)) == 0 {
+				dangling += rank[node]
+			}
+		}
+		next := make(map[string]float64, len(nodes))
+		for _, node := range nodes {
+			next[node] = (1-pageRankDamping)/n + pageRankDamping*dangling/n
+		}
+		for _, node := range nodes {
+			succ := g.Succ(node)
+			for _, to := range succ {
+				next[to] += pageRankDamping * rank[node] / float64(len(succ))
+			}
+		}
+		delta := 0.0
+		for _, node := range nodes {
+			delta += math.Abs(next[node] - rank[node])
+		}
+		rank = next
+		if delta < pageRankTolerance {
+			break
+		}
+	}
+	return rank
+}
+
+// Betweenness computes the betweenness centrality of every node with
+// Brandes' algorithm, that is how often a node sits on the shortest import
+// paths between other nodes.
+func Betweenness(g *Graph) map[string]float64 {
+	nodes := g.Nodes()
+	result := make(map[string]float64, len(nodes))
+	for _, node := range nodes {
+		result[node] = 0
+	}
+	for _, s := range nodes {
+		var (
+			stack []string
+			pred  = map[string][]string{}
+			sigma = map[string]float64{s: 1}
+			dist  = map[string]int{s: 0}
+			queue = []string{s}
+		)
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			stack = append(stack, v)
+			for _, w := range g.Succ(v) {
+				if _, seen := dist[w]; !seen {
+					dist[w] = dist[v] + 1
+					queue = append(queue, w)
+				}
+				if dist[w] == dist[v]+1 {
+					sigma[w] += sigma[v]
+					pred[w] = append(pred[w], v)
+				}
+			}
+		}
+		delta := map[string]float64{}
+		for i := len(stack) - 1; i >= 0; i-- {
+			w := stack[i]
+			for _, v := range pred[w] {
+				delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+			}
+			if w != s {
+				result[w] += delta[w]
+			}
+		}
+	}
+	return result
+}
+
+// Ranks returns the importance scores of all nodes, most important first.
+func Ranks(g *Graph) []Rank {
+	var (
+		pr     = PageRank(g)
+		bc     = Betweenness(g)
+		result []Rank
+	)
+	for _, node := range g.Nodes() {
+		result = append(result, Rank{node, pr[node], bc[node]})
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].PageRank != result[j].PageRank {
+			return result[i].PageRank > result[j].PageRank
+		}
+		return result[i].Betweenness > result[j].Betweenness
+	})
+	return result
+}
+
+// printRanks writes the ranked list of packages as an aligned table.
+func printRanks(w io.Writer, p painter, ranks []Rank) error {
+	rows := [][]cell{{{"RANK", bold}, {"PAGERANK", bold}, {"BETWEENNESS", bold}, {"PACKAGE", bold}}}
+	for i, r := range ranks {
+		rows = append(rows, []cell{
+			{fmt.Sprint(i + 1), dim},
+			{fmt.Sprintf("%.4f", r.PageRank), plain},
+			{fmt.Sprintf("%.1f", r.Betweenness), plain},
+			{r.Node, cyan},
+		})
+	}
+	return p.printTable(w, rows)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	graphs "github.com/sequix/baobab/graph"
+)
+
+// rankGraph returns a graph with the cycle a -> b -> c -> a, d importing
+// into it, e imported from it and importing nothing, and f alone.
+func rankGraph() *Graph {
+	g := graphs.New()
+	for _, e := range [][2]string{{"a", "b"}, {"b", "c"}, {"c", "a"}, {"c", "e"}, {"d", "a"}, {"d", "b"}} {
+		g.AddEdge(e[0], e[1])
+	}
+	g.AddNode("f")
+	return g
+}
+
+func TestPageRank(t *testing.T) {
+	// Found by iterating to a fixed point independently; d and f, imported
+	// by none, only get their share of teleports and of e's rank.
+	want := map[string]float64{
+		"a": 0.196097, "b": 0.248564, "c": 0.268741,
+		"d": 0.057461, "e": 0.171676, "f": 0.057461,
+	}
+	got := PageRank(rankGraph())
+	sum := 0.0
+	for node, w := range want {
+		if math.Abs(got[node]-w) > 1e-6 {
+			t.Errorf("PageRank of %s = %f, want %f", node, got[node], w)
+		}
+		sum += got[node]
+	}
+	if len(got) != len(want) || math.Abs(sum-1) > 1e-9 {
+		t.Errorf("PageRank of %d nodes summing to %f, want 6 summing to 1", len(got), sum)
+	}
+}
+
+func TestBetweenness(t *testing.T) {
+	// b is on a -> c, a -> e, d -> c and d -> e; c on b -> a, b -> e, a -> e
+	// and d -> e; a on c -> b alone.
+	want := map[string]float64{"a": 1, "b": 4, "c": 4, "d": 0, "e": 0, "f": 0}
+	got := Betweenness(rankGraph())
+	for node, w := range want {
+		if math.Abs(got[node]-w) > 1e-9 {
+			t.Errorf("Betweenness of %s = %f, want %f", node, got[node], w)
+		}
+	}
+
+	// Two shortest paths from s to t share the credit.
+	g := graphs.New()
+	for _, e := range [][2]string{{"s", "x"}, {"s", "y"}, {"x", "t"}, {"y", "t"}} {
+		g.AddEdge(e[0], e[1])
+	}
+	if got := Betweenness(g); got["x"] != 0.5 || got["y"] != 0.5 {
+		t.Errorf("Betweenness of x and y = %f and %f, want 0.5", got["x"], got["y"])
+	}
+}
+
+func TestRanks(t *testing.T) {
+	ranks := Ranks(rankGraph())
+	var order []string
+	for _, r := range ranks {
+		order = append(order, r.Node)
+	}
+	// d and f tie, keeping their order by name.
+	want := []string{"c", "b", "a", "e", "d", "f"}
+	if len(order) != len(want) {
+		t.Fatalf("ranks %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("ranks %v, want %v", order, want)
+		}
+	}
+}
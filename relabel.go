@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	graphs "github.com/sequix/baobab/graph"
+)
+
+var flagRelabel string
+
+// relabelRule renames the packages matching re to label, which may refer to
+// submatches as in regexp.Expand, like $1.
+type relabelRule struct {
+	re    *regexp.Regexp
+	label string
+}
+
+// parseRelabel reads the rules in file, one REGEXP [LABEL] per line, blank
+// lines and those starting with # ignored. Without a LABEL, the match is
+// removed from the name.
+func parseRelabel(file string) ([]relabelRule, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var (
+		result []relabelRule
+		scan   = bufio.NewScanner(f)
+	)
+	for line := 1; scan.Scan(); line++ {
+		fields := strings.Fields(scan.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("%s:%d: expected REGEXP [LABEL]", file, line)
+		}
+		re, err := regexp.Compile(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", file, line, err)
+		}
+		rule := relabelRule{re: re}
+		if len(fields) == 2 {
+			rule.label = fields[1]
+		}
+		result = append(result, rule)
+	}
+	if err := scan.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", file, err)
+	}
+	return result, nil
+}
+
+// relabel sets the label of every node of g the first of rules matches.
+func relabel(g *Graph, rules []relabelRule) {
+	for _, name := range g.Nodes() {
+		n := g.Node(name)
+		n.Label = ""
+		slashed := nodePath(name)
+		for _, r := range rules {
+			if r.re.MatchString(slashed) {
+				n.Label = r.re.ReplaceAllString(slashed, r.label)
+				break
+			}
+		}
+	}
+}
+
+// nodeLabel returns how n is shown: its -relabel label, else its canonical
+// import path, else its name, that of a package outside the scanned
+// modules or a marker without the prefix of its kind.
+func nodeLabel(n *Node) string {
+	switch {
+	case n.Label != "":
+		return n.Label
+	case n.Canonical != "":
+		return n.Canonical
+	}
+	path, _ := graphs.Outside(n.Name)
+	return path
+}
+
+// nodePath returns the directory of the package node name, with slashes,
+// or the import path, module path or marker name it stands for.
+func nodePath(name string) string {
+	if path, ok := graphs.Outside(name); ok {
+		return path
+	}
+	return filepath.ToSlash(name)
+}
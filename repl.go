@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	graphs "github.com/sequix/baobab/graph"
+)
+
+// replHelp describes the queries of baobab repl.
+const replHelp = `deps PKG        packages PKG imports
+rdeps PKG       packages importing PKG
+path FROM TO    shortest chain of imports from FROM to TO
+cycles          import cycles, test-only imports aside
+help            this help
+quit            leave, as does end of input
+`
+
+func runREPL(fs *flag.FlagSet, args []string) error {
+	source, err := sourceArg(args)
+	if err != nil {
+		return err
+	}
+	p, err := newPainter()
+	if err != nil {
+		return err
+	}
+	if err := scanGraph(fs, source); err != nil {
+		return err
+	}
+	prompt := isTerminal(os.Stdin)
+	in := bufio.NewScanner(os.Stdin)
+	for {
+		if prompt {
+			fmt.Print("> ")
+		}
+		if !in.Scan() {
+			if prompt {
+				fmt.Println()
+			}
+			return in.Err()
+		}
+		fields := strings.Fields(in.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "quit" || fields[0] == "exit" {
+			return nil
+		}
+		if err := query(os.Stdout, p, fields[0], fields[1:]); err != nil {
+			// A bad query does not end the session.
+			logger.Error(err.Error())
+		}
+	}
+}
+
+// query answers a query of baobab repl, writing the answer to w.
+func query(w io.Writer, p painter, name string, args []string) error {
+	want := map[string]int{"deps": 1, "rdeps": 1, "path": 2, "cycles": 0, "help": 0}
+	n, ok := want[name]
+	if !ok {
+		return fmt.Errorf("unknown query %q, see help", name)
+	}
+	if len(args) != n {
+		return fmt.Errorf("%s wants %d arguments, got %d", name, n, len(args))
+	}
+	var pkgs []string
+	for _, arg := range args {
+		pkg := graphNode(arg)
+		if pkg == "" {
+			return fmt.Errorf("no package %s in the graph", arg)
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	switch name {
+	case "deps", "rdeps":
+		next := graph.Succ
+		if name == "rdeps" {
+			next = graph.Pred
+		}
+		for _, pkg := range next(pkgs[0]) {
+			fmt.Fprintln(w, p.paint(pkg, cyan))
+		}
+	case "path":
+		path := graphs.ShortestPath(graph, pkgs[0], pkgs[1])
+		if path == nil {
+			return fmt.Errorf("%s does not import %s", pkgs[0], pkgs[1])
+		}
+		return printPath(w, p, path)
+	case "cycles":
+		return printViolations(w, p, checkCycles(graph))
+	case "help":
+		fmt.Fprint(w, replHelp)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// cloneRepo fetches ref, or the default branch if empty, of the git
+// repository at url into a new temporary directory and returns it. Only
+// that commit is fetched, whether ref is a branch, a tag or a commit hash.
+func cloneRepo(url, ref string) (string, error) {
+	dir, err := ioutil.TempDir("", "baobab-")
+	if err != nil {
+		return "", err
+	}
+	if ref == "" {
+		ref = "HEAD"
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet", dir},
+		{"-C", dir, "fetch", "--quiet", "--depth", "1", "--", url, ref},
+		{"-C", dir, "checkout", "--quiet", "FETCH_HEAD"},
+	} {
+		var stderr bytes.Buffer
+		cmd := exec.Command("git", args...)
+		logger.Debug("fetching repository", "cmd", cmd.Args)
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("failed to clone %s at %s: %s: %s", url, ref, err, strings.TrimSpace(stderr.String()))
+		}
+	}
+	return dir, nil
+}
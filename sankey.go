@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"io"
+	"slices"
+)
+
+//go:embed sankey.html
+var sankeyHTML []byte
+
+// sankeyFlow is the imports from the packages of a layer to those of
+// another.
+type sankeyFlow struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Imports int    `json:"imports"`
+	Wrong   bool   `json:"wrong,omitempty"` // from a layer to one above it
+}
+
+// writeSankey writes a standalone HTML page drawing the imports between the
+// -groups of g as a Sankey diagram, the groups taken as layers from the top
+// down in the order of the file: the packages of a layer should only import
+// those of the layers after it. The packages of no group make the layer
+// "other". Imports within a layer are left out.
+func writeSankey(w io.Writer, g *Graph) error {
+	groups, err := parseGroups(flagGroups)
+	if err != nil {
+		return err
+	}
+	var layers []string
+	for _, gr := range groups {
+		layers = append(layers, gr.name)
+	}
+	layerOf := func(n *Node) string {
+		if layer := groupLayer(layers, n); layer >= 0 {
+			return layers[layer]
+		}
+		return "other"
+	}
+	flows := map[[2]string]*sankeyFlow{}
+	var keys [][2]string
+	for _, e := range g.Edges() {
+		key := [2]string{layerOf(g.Node(e.From)), layerOf(g.Node(e.To))}
+		if key[0] == key[1] {
+			continue
+		}
+		f := flows[key]
+		if f == nil {
+			f = &sankeyFlow{From: key[0], To: key[1]}
+			from, to := slices.Index(layers, key[0]), slices.Index(layers, key[1])
+			f.Wrong = from >= 0 && to >= 0 && from > to
+			flows[key] = f
+			keys = append(keys, key)
+		}
+		f.Imports += max(e.Weight, 1)
+	}
+	doc := struct {
+		Layers []string      `json:"layers"`
+		Flows  []*sankeyFlow `json:"flows"`
+	}{append(layers, "other"), []*sankeyFlow{}}
+	for _, key := range keys {
+		doc.Flows = append(doc.Flows, flows[key])
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(themeHTML(bytes.Replace(sankeyHTML, []byte("/*FLOWS*/null"), data, 1)))
+	return err
+}
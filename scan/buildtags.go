@@ -0,0 +1,190 @@
+package scan
+
+import (
+	"bufio"
+	"fmt"
+	"go/build"
+	"go/build/constraint"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// buildContext returns the context matching files against a target
+// platform and build tags, nil if none were asked for.
+func buildContext(goos, goarch string, tags []string) *build.Context {
+	if goos == "" && goarch == "" && len(tags) == 0 {
+		return nil
+	}
+	ctx := build.Default
+	if goos != "" {
+		ctx.GOOS = goos
+	}
+	if goarch != "" {
+		ctx.GOARCH = goarch
+	}
+	if ctx.GOOS != build.Default.GOOS || ctx.GOARCH != build.Default.GOARCH {
+		// Same as the go tool, cgo is off when cross compiling unless asked.
+		ctx.CgoEnabled = os.Getenv("CGO_ENABLED") == "1"
+	}
+	ctx.BuildTags = tags
+	return &ctx
+}
+
+// matchFile reports whether file name in dir should be scanned, that is its
+// name suffixes and //go:build or +build lines match the target platform.
+// Without a target platform, only files that never build on any platform,
+// like those with //go:build ignore, are left out.
+func (s *Scanner) matchFile(dir, name string) (bool, error) {
+	if s.buildCtx != nil {
+		return s.buildCtx.MatchFile(s.path(dir), name)
+	}
+	expr, err := readConstraint(filepath.Join(s.path(dir), name))
+	if err != nil || expr == nil {
+		return true, err
+	}
+	return satisfiable(expr), nil
+}
+
+var (
+	knownOS = []string{
+		"aix", "android", "darwin", "dragonfly", "freebsd", "hurd", "illumos", "ios", "js",
+		"linux", "netbsd", "openbsd", "plan9", "solaris", "wasip1", "windows", "zos",
+	}
+	knownArch = []string{
+		"386", "amd64", "arm", "arm64", "loong64", "mips", "mips64", "mips64le", "mipsle",
+		"ppc64", "ppc64le", "riscv64", "s390x", "wasm",
+	}
+	unixOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true,
+		"hurd": true, "illumos": true, "ios": true, "linux": true, "netbsd": true,
+		"openbsd": true, "solaris": true,
+	}
+	// impliedOS holds the GOOS whose files also build on another one.
+	impliedOS = map[string]string{"android": "linux", "ios": "darwin", "illumos": "solaris"}
+)
+
+// maxFreeTags bounds the number of tags, other than platform ones, tried in
+// all combinations by satisfiable.
+const maxFreeTags = 12
+
+// satisfiable reports whether expr holds on some platform with some set of
+// build tags, the ignore tag never being set.
+func satisfiable(expr constraint.Expr) bool {
+	var free []string
+	seen := map[string]bool{}
+	expr.Eval(func(tag string) bool {
+		if !seen[tag] && !isPlatformTag(tag) && tag != "ignore" {
+			free = append(free, tag)
+		}
+		seen[tag] = true
+		return false
+	})
+	if len(free) > maxFreeTags {
+		return true
+	}
+	for _, goos := range knownOS {
+		for _, goarch := range knownArch {
+			for set := 0; set < 1<<len(free); set++ {
+				ok := expr.Eval(func(tag string) bool {
+					switch {
+					case tag == goos || tag == goarch || tag == impliedOS[goos]:
+						return true
+					case tag == "unix":
+						return unixOS[goos]
+					case isPlatformTag(tag) || tag == "ignore":
+						return false
+					}
+					for i, t := range free {
+						if t == tag {
+							return set&(1<<i) != 0
+						}
+					}
+					return false
+				})
+				if ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// isPlatformTag reports whether tag is a GOOS, GOARCH or unix.
+func isPlatformTag(tag string) bool {
+	if tag == "unix" {
+		return true
+	}
+	for _, t := range knownOS {
+		if t == tag {
+			return true
+		}
+	}
+	for _, t := range knownArch {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// readConstraint returns the build constraint of a go file, from its
+// //go:build line or else its +build lines, nil if it has none.
+func readConstraint(file string) (constraint.Expr, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var (
+		goBuild   constraint.Expr
+		plusBuild constraint.Expr
+		scan      = bufio.NewScanner(f)
+	)
+	for scan.Scan() {
+		line := strings.TrimSpace(scan.Text())
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+		if !constraint.IsGoBuild(line) && !constraint.IsPlusBuild(line) {
+			continue
+		}
+		expr, err := constraint.Parse(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", file, err)
+		}
+		switch {
+		case constraint.IsGoBuild(line):
+			goBuild = expr
+		case plusBuild == nil:
+			plusBuild = expr
+		default:
+			plusBuild = &constraint.AndExpr{X: plusBuild, Y: expr}
+		}
+	}
+	if goBuild != nil {
+		return goBuild, scan.Err()
+	}
+	return plusBuild, scan.Err()
+}
+
+// goListEnv returns the environment and flags for go list to target the
+// same platform as the scanner.
+func (s *Scanner) goListEnv() ([]string, []string) {
+	var (
+		env  = os.Environ()
+		args []string
+	)
+	if s.gopath {
+		env = append(env, "GO111MODULE=off")
+	}
+	if s.buildCtx == nil {
+		return env, nil
+	}
+	env = append(env, "GOOS="+s.buildCtx.GOOS, "GOARCH="+s.buildCtx.GOARCH)
+	if len(s.buildCtx.BuildTags) > 0 {
+		args = append(args, "-tags", strings.Join(s.buildCtx.BuildTags, ","))
+	}
+	return env, args
+}
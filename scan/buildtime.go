@@ -0,0 +1,89 @@
+package scan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// action is a step of the build in the action graph go build
+// -debug-actiongraph writes.
+type action struct {
+	Mode      string
+	Package   string
+	TimeStart time.Time
+	TimeDone  time.Time
+}
+
+// CompileTimes returns how long compiling each package took, by import path,
+// read from the action graph in file as go build -debug-actiongraph writes
+// it or, if file is empty, building every package of the scanned modules
+// and what they import from scratch for it. The cgo steps of a package count
+// in its time, linking does not.
+func (s *Scanner) CompileTimes(ctx context.Context, file string) (map[string]time.Duration, error) {
+	if file == "" {
+		tmp, err := os.CreateTemp("", "baobab-actiongraph-")
+		if err != nil {
+			return nil, err
+		}
+		tmp.Close()
+		defer os.Remove(tmp.Name())
+		file = tmp.Name()
+		if err := s.buildAll(ctx, file); err != nil {
+			return nil, err
+		}
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read action graph: %s", err)
+	}
+	var actions []action
+	if err := json.Unmarshal(data, &actions); err != nil {
+		return nil, fmt.Errorf("failed to decode action graph %s: %s", file, err)
+	}
+	result := map[string]time.Duration{}
+	for _, a := range actions {
+		if a.Package == "" || a.TimeStart.IsZero() || a.TimeDone.IsZero() {
+			continue
+		}
+		if a.Mode == "build" || strings.HasPrefix(a.Mode, "cgo ") {
+			result[a.Package] += a.TimeDone.Sub(a.TimeStart)
+		}
+	}
+	return result, nil
+}
+
+// buildAll builds every package of the scanned modules with go build -a,
+// writing the action graph to file.
+func (s *Scanner) buildAll(ctx context.Context, file string) error {
+	var patterns []string
+	for _, m := range s.modules {
+		patterns = append(patterns, "./"+filepath.ToSlash(m.Dir)+"/...")
+	}
+	return s.goBuild(ctx, []string{"-a", "-debug-actiongraph=" + file}, patterns)
+}
+
+// BuildBinary builds the main package in dir, relative to the root, into
+// the executable out, for the scanned build constraints.
+func (s *Scanner) BuildBinary(ctx context.Context, dir, out string) error {
+	return s.goBuild(ctx, []string{"-o", out}, []string{"./" + filepath.ToSlash(dir)})
+}
+
+// goBuild runs go build with flags on the packages matching patterns, from
+// the root.
+func (s *Scanner) goBuild(ctx context.Context, flags, patterns []string) error {
+	env, tags := s.goListEnv()
+	args := append(append(append([]string{"build"}, flags...), tags...), patterns...)
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir, cmd.Env = s.root, env
+	s.logger.Debug("building packages", "cmd", cmd.Args)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to build packages: %s: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package scan
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// cacheVersion is part of every cache key, bump it when GoFile or what the
+// backends find changes.
+const cacheVersion = "3"
+
+// cachedParse wraps parse, the function of backend, so files whose content
+// was parsed before are read from the cache in dir instead. Files failing to
+// parse are not cached, and failing to use the cache is not an error.
+func cachedParse(dir, backend string, parse func(string) (*GoFile, error)) func(string) (*GoFile, error) {
+	return func(file string) (*GoFile, error) {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file %s: %s", file, err)
+		}
+		sum := sha256.Sum256([]byte(cacheVersion + "\x00" + backend + "\x00" + string(content)))
+		key := hex.EncodeToString(sum[:])
+		entry := filepath.Join(dir, key[:2], key)
+		if data, err := ioutil.ReadFile(entry); err == nil {
+			f := &GoFile{}
+			if json.Unmarshal(data, f) == nil {
+				return f, nil
+			}
+		}
+		f, err := parse(file)
+		if err != nil {
+			return nil, err
+		}
+		writeCacheEntry(entry, f)
+		return f, nil
+	}
+}
+
+// writeCacheEntry stores f in file, through a rename so concurrent runs
+// never read a partial entry.
+func writeCacheEntry(file string, f *GoFile) {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(file), ".tmp-")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil || os.Rename(tmp.Name(), file) != nil {
+		os.Remove(tmp.Name())
+	}
+}
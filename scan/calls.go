@@ -0,0 +1,88 @@
+package scan
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sequix/baobab/graph"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/callgraph/static"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// CallGraph returns the graph of calls between the functions of the scanned
+// modules, type checking every package of them. Nodes are functions, named
+// by the directory of their package and their name, like pkg/log.Printf or
+// pkg/log.(*Logger).Print, in the Group of their package; closures stand
+// for the function they are declared in. An edge's Weight is the number of
+// calls it stands for, Test if all are in _test.go files. Only static calls
+// are followed unless dynamic, when calls through interfaces and function
+// values are too, to every function of a matching type.
+func (s *Scanner) CallGraph(ctx context.Context, dynamic bool) (*graph.Graph, error) {
+	pkgs, err := s.loadTyped(ctx, packages.LoadAllSyntax, s.opts.Tests)
+	if err != nil {
+		return nil, err
+	}
+	prog, _ := ssautil.AllPackages(pkgs, ssa.InstantiateGenerics)
+	prog.Build()
+	var cg *callgraph.Graph
+	if dynamic {
+		cg = cha.CallGraph(prog)
+	} else {
+		cg = static.CallGraph(prog)
+	}
+
+	g := graph.New()
+	names := map[*ssa.Function]string{}
+	name := func(fn *ssa.Function) string {
+		if n, ok := names[fn]; ok {
+			return n
+		}
+		n := s.funcNode(g, fn)
+		names[fn] = n
+		return n
+	}
+	err = callgraph.GraphVisitEdges(cg, func(e *callgraph.Edge) error {
+		if e.Site == nil {
+			return nil
+		}
+		from, to := name(e.Caller.Func), name(e.Callee.Func)
+		if from == "" || to == "" || from == to {
+			return nil
+		}
+		test := strings.HasSuffix(prog.Fset.Position(e.Site.Pos()).Filename, "_test.go")
+		edge := g.Edge(from, to)
+		if edge == nil {
+			edge = g.AddEdge(from, to)
+			edge.Test = test
+		} else if !test {
+			edge.Test = false
+		}
+		edge.Weight++
+		return nil
+	})
+	return g, err
+}
+
+// funcNode adds the node of fn to g and returns its name, empty if fn is
+// not a function of the scanned modules.
+func (s *Scanner) funcNode(g *graph.Graph, fn *ssa.Function) string {
+	for fn.Parent() != nil {
+		fn = fn.Parent()
+	}
+	if o := fn.Origin(); o != nil {
+		fn = o
+	}
+	if fn.Pkg == nil || strings.HasSuffix(fn.Pkg.Pkg.Path(), ".test") {
+		// Wrappers and other synthetic functions, test mains.
+		return ""
+	}
+	dir, mod, ok := s.resolveImport(strings.TrimSuffix(fn.Pkg.Pkg.Path(), "_test"))
+	if !ok || s.excluded(dir) {
+		return ""
+	}
+	return s.objectNode(g, dir, mod, fn.Pkg.Pkg.Path(), fn.RelString(fn.Pkg.Pkg))
+}
@@ -0,0 +1,60 @@
+package scan
+
+import (
+	"context"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Coupling returns, for each import between packages of the scanned modules,
+// by importing and imported directory, the number of distinct exported
+// names of the imported package the importer uses: its functions, types,
+// variables and constants, and the methods and fields of its types. It type
+// checks every package of the modules, and their tests too with
+// Options.Tests, the external tests of a package counting as it.
+func (s *Scanner) Coupling(ctx context.Context) (map[[2]string]int, error) {
+	pkgs, err := s.loadTyped(ctx, packages.NeedName|packages.NeedTypes|packages.NeedSyntax|packages.NeedTypesInfo, s.opts.Tests)
+	if err != nil {
+		return nil, err
+	}
+	// Test variants of packages have objects of their own: objects are told
+	// apart by where they are declared.
+	used := map[[2]string]map[token.Pos]bool{}
+	for _, pkg := range pkgs {
+		if pkg.TypesInfo == nil {
+			continue
+		}
+		from, _, ok := s.resolveImport(strings.TrimSuffix(pkg.PkgPath, "_test"))
+		if !ok || s.excluded(from) {
+			continue
+		}
+		for _, obj := range pkg.TypesInfo.Uses {
+			if obj.Pkg() == nil || !obj.Exported() {
+				continue
+			}
+			switch o := obj.(type) {
+			case *types.Func:
+				obj = o.Origin()
+			case *types.Var:
+				obj = o.Origin()
+			}
+			to, _, ok := s.resolveImport(obj.Pkg().Path())
+			if !ok || to == from {
+				continue
+			}
+			key := [2]string{from, to}
+			if used[key] == nil {
+				used[key] = map[token.Pos]bool{}
+			}
+			used[key][obj.Pos()] = true
+		}
+	}
+	result := map[[2]string]int{}
+	for key, objs := range used {
+		result[key] = len(objs)
+	}
+	return result, nil
+}
@@ -0,0 +1,114 @@
+package scan
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sequix/baobab/graph"
+)
+
+// cgoNode is the name of the marker node packages using cgo point to.
+const cgoNode = graph.MarkerPrefix + "cgo"
+
+// addOutside adds an edge from dir for an import imp outside of the scanned
+// modules, if Options ask for such imports to be shown, and returns the node
+// it points to, empty if none. Packages outside of the scanned modules are
+// never scanned themselves.
+func (s *Scanner) addOutside(dir string, imp graph.Import) string {
+	switch {
+	case imp.Path == "C":
+		return s.addCgo(dir, imp)
+	case isStdlib(imp.Path):
+		return s.addStdlib(dir, imp)
+	case s.opts.External:
+		return s.addExternal(dir, imp)
+	default:
+		return s.addVendored(dir, imp)
+	}
+}
+
+// addCgo marks package dir as using cgo, because it has a file importing the
+// pseudo-package "C", and with Cgo adds an edge to the cgo marker node.
+func (s *Scanner) addCgo(dir string, imp graph.Import) string {
+	s.graph.Node(dir).Cgo = true
+	if !s.opts.Cgo {
+		return ""
+	}
+	s.graph.AddImport(dir, cgoNode, imp)
+	s.graph.Node(cgoNode).Marker = true
+	return cgoNode
+}
+
+// isStdlib reports whether imp is a standard library package, that is its
+// first path element has no dot, like the go tool decides.
+func isStdlib(imp string) bool {
+	if imp == "C" {
+		return false
+	}
+	first := strings.SplitN(imp, "/", 2)[0]
+	return !strings.Contains(first, ".")
+}
+
+// addStdlib adds an edge from dir to the standard library package imp, as
+// chosen by Stdlib: to imp itself, to its top-level package, or to a single
+// node standing for the whole standard library.
+func (s *Scanner) addStdlib(dir string, imp graph.Import) string {
+	var name string
+	switch s.opts.Stdlib {
+	case "all":
+		name = imp.Path
+	case "top":
+		name = strings.SplitN(imp.Path, "/", 2)[0]
+	case "single":
+		name = "stdlib"
+	default:
+		return ""
+	}
+	name = graph.StdPrefix + name
+	s.graph.AddImport(dir, name, imp)
+	s.graph.Node(name).Std = true
+	return name
+}
+
+// addExternal adds an edge from dir to the third-party module providing imp,
+// as required by the go.mod of dir, or to imp itself if none is.
+func (s *Scanner) addExternal(dir string, imp graph.Import) string {
+	name := imp.Path
+	if m := s.moduleOfDir(dir); m != nil {
+		best := ""
+		for _, req := range m.Requires {
+			if (imp.Path == req || strings.HasPrefix(imp.Path, req+"/")) && len(req) > len(best) {
+				best = req
+			}
+		}
+		if best != "" {
+			name = best
+		}
+	}
+	s.graph.AddImport(dir, graph.ExternalPrefix+name, imp)
+	n := s.graph.Node(graph.ExternalPrefix + name)
+	n.External = true
+	n.Module = name
+	return n.Name
+}
+
+// addVendored adds an edge from dir to the package imp as an external node
+// if Vendor is set and imp is vendored in the module of dir.
+func (s *Scanner) addVendored(dir string, imp graph.Import) string {
+	if !s.opts.Vendor {
+		return ""
+	}
+	m := s.moduleOfDir(dir)
+	if m == nil {
+		return ""
+	}
+	fi, err := os.Stat(filepath.Join(s.path(m.Dir), "vendor", filepath.FromSlash(imp.Path)))
+	if err != nil || !fi.IsDir() {
+		return ""
+	}
+	name := graph.ExternalPrefix + imp.Path
+	s.graph.AddImport(dir, name, imp)
+	s.graph.Node(name).External = true
+	return name
+}
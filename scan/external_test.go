@@ -0,0 +1,105 @@
+package scan
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sequix/baobab/graph"
+)
+
+// writeFiles writes files, by path relative to dir, into dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestOutsideNodesKeepApartFromDirectories(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":     "module example.com/m\n\ngo 1.21\n",
+		"main.go":    "package main\n\nimport (\n\t\"log\"\n\n\tmlog \"example.com/m/log\"\n\t_ \"example.com/m/cgo\"\n)\n\nfunc main() { log.Print(mlog.X) }\n",
+		"log/log.go": "package log\n\nimport \"log\"\n\nvar X = log.Prefix()\n",
+		"cgo/cgo.go": "package cgo\n\nimport \"C\"\n",
+	})
+	g, err := Scan(context.Background(), Options{Dir: dir, Stdlib: "all", Cgo: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name   string
+		std    bool
+		marker bool
+	}{
+		{"log", false, false},
+		{"cgo", false, false},
+		{graph.StdPrefix + "log", true, false},
+		{graph.MarkerPrefix + "cgo", false, true},
+	} {
+		n := g.Node(tc.name)
+		if n == nil {
+			t.Errorf("no node %s in %v", tc.name, g.Nodes())
+			continue
+		}
+		if n.Std != tc.std || n.Marker != tc.marker {
+			t.Errorf("node %s: std %v, marker %v, want %v, %v", tc.name, n.Std, n.Marker, tc.std, tc.marker)
+		}
+	}
+	for _, e := range [][2]string{{".", "log"}, {".", graph.StdPrefix + "log"}, {"log", graph.StdPrefix + "log"}, {"cgo", graph.MarkerPrefix + "cgo"}} {
+		if g.Edge(e[0], e[1]) == nil {
+			t.Errorf("no edge %s -> %s", e[0], e[1])
+		}
+	}
+	if g.Edge("log", "log") != nil {
+		t.Errorf("log imports itself")
+	}
+	if !g.Node("cgo").Cgo {
+		t.Errorf("package cgo not marked as using cgo")
+	}
+}
+
+func TestOutsideImportsNameTheirNode(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":  "module example.com/m\n\ngo 1.21\n\nrequire example.com/dep v1.0.0\n",
+		"main.go": "package main\n\nimport (\n\t\"net/http\"\n\t\"example.com/dep/sub\"\n\t\"C\"\n)\n\nfunc main() {}\n",
+	})
+	for _, tc := range []struct {
+		opts Options
+		want map[string]string
+	}{
+		{Options{Stdlib: "top", External: true, Cgo: true}, map[string]string{
+			"net/http":            graph.StdPrefix + "net",
+			"example.com/dep/sub": graph.ExternalPrefix + "example.com/dep",
+			"C":                   graph.MarkerPrefix + "cgo",
+		}},
+		{Options{Stdlib: "single"}, map[string]string{
+			"net/http":            graph.StdPrefix + "stdlib",
+			"example.com/dep/sub": "",
+			"C":                   "",
+		}},
+	} {
+		tc.opts.Dir = dir
+		g, err := Scan(context.Background(), tc.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, imp := range g.Node(".").Imports {
+			if imp.Node != tc.want[imp.Path] {
+				t.Errorf("%+v: import %s drawn to %q, want %q", tc.opts, imp.Path, imp.Node, tc.want[imp.Path])
+			}
+			if imp.Node != "" && g.Edge(".", imp.Node) == nil {
+				t.Errorf("%+v: no edge to %s", tc.opts, imp.Node)
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package scan
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sequix/baobab/graph"
+)
+
+// generatedRe matches the comment marking generated files, see
+// https://golang.org/s/generatedcode.
+var generatedRe = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// isGenerated reports whether file has the generated code comment before
+// its package clause.
+func isGenerated(file string) (bool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return false, fmt.Errorf("failed to open file %s: %s", file, err)
+	}
+	defer f.Close()
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		line := scan.Text()
+		if generatedRe.MatchString(line) {
+			return true, nil
+		}
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+	}
+	return false, scan.Err()
+}
+
+// skipGenerated records the edges the imports of a generated file in dir
+// would have added, instead of adding them.
+func (s *Scanner) skipGenerated(dir string, imports []graph.Import) {
+	s.generatedFiles[dir]++
+	for _, imp := range imports {
+		if nextDir, _, ok := s.resolveImport(imp.Path); ok && nextDir != dir {
+			s.generatedEdges[[2]string{dir, nextDir}] = struct{}{}
+		}
+	}
+}
+
+// forgetGenerated forgets the generated files of dir, about to be scanned
+// again.
+func (s *Scanner) forgetGenerated(dir string) {
+	delete(s.generatedFiles, dir)
+	for e := range s.generatedEdges {
+		if e[0] == dir {
+			delete(s.generatedEdges, e)
+		}
+	}
+}
+
+// Generated returns how many files SkipGenerated skipped in the packages
+// of the last scan, and the edges only they produce, which are missing from
+// the graph, sorted.
+func (s *Scanner) Generated() (files int, only [][2]string) {
+	for e := range s.generatedEdges {
+		if s.graph.Node(e[0]) != nil && s.graph.Edge(e[0], e[1]) == nil {
+			only = append(only, e)
+		}
+	}
+	for dir, n := range s.generatedFiles {
+		if s.graph.Node(dir) != nil {
+			files += n
+		}
+	}
+	sort.Slice(only, func(i, j int) bool {
+		return only[i][0] < only[j][0] || only[i][0] == only[j][0] && only[i][1] < only[j][1]
+	})
+	return files, only
+}
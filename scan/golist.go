@@ -0,0 +1,125 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sequix/baobab/graph"
+)
+
+// listedPackage is the part of `go list -json` output we care about.
+type listedPackage struct {
+	ImportPath   string
+	ForTest      string
+	DepOnly      bool
+	GoFiles      []string
+	CgoFiles     []string
+	TestGoFiles  []string
+	XTestGoFiles []string
+	Imports      []string
+	TestImports  []string
+	XTestImports []string
+	Error        *struct {
+		Err string
+	}
+}
+
+// readGoList fills the graph from Options.GoList, starting from the
+// packages in entries, or those listed for themselves rather than as
+// dependencies if there are none. Imports are taken as listed, without
+// reading any file, so they have no position.
+func (s *Scanner) readGoList(ctx context.Context, entries []string) error {
+	var (
+		pkgs  = map[string]*listedPackage{} // by directory
+		roots []string
+		dec   = json.NewDecoder(bytes.NewReader(s.opts.GoList))
+	)
+	for {
+		pkg := &listedPackage{}
+		if err := dec.Decode(pkg); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to decode go list output: %s", err)
+		}
+		if pkg.ForTest != "" || strings.HasSuffix(pkg.ImportPath, ".test") {
+			// Test variants, the package lists their imports already.
+			continue
+		}
+		dir, _, ok := s.resolveImport(pkg.ImportPath)
+		if !ok {
+			continue
+		}
+		if pkg.Error != nil {
+			if err := s.tolerate(pkg.ImportPath, fmt.Errorf("failed to load package %s: %s", pkg.ImportPath, pkg.Error.Err)); err != nil {
+				return err
+			}
+		}
+		pkgs[dir] = pkg
+		if !pkg.DepOnly {
+			roots = append(roots, dir)
+		}
+	}
+	if len(entries) > 0 {
+		roots = entries
+	}
+	if len(roots) == 0 {
+		return fmt.Errorf("no package of the scanned modules in the go list output")
+	}
+
+	type item struct {
+		dir   string
+		depth int
+	}
+	var (
+		queue []item
+		seen  = map[string]struct{}{}
+	)
+	for _, dir := range roots {
+		if pkgs[dir] == nil {
+			return fmt.Errorf("package %s is not in the go list output", dir)
+		}
+		queue = append(queue, item{dir, 0})
+		seen[dir] = struct{}{}
+	}
+	for len(queue) > 0 {
+		it := queue[0]
+		queue = queue[1:]
+		if s.opts.Depth > 0 && it.depth > s.opts.Depth || s.excluded(it.dir) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		pkg := pkgs[it.dir]
+		s.logger.Debug("adding listed package", "dir", it.dir, "depth", it.depth)
+		s.addPackage(it.dir)
+		node := s.graph.Node(it.dir)
+		node.Files, node.TestFiles = len(pkg.GoFiles)+len(pkg.CgoFiles), len(pkg.TestGoFiles)+len(pkg.XTestGoFiles)
+		f := &GoFile{}
+		for _, imp := range pkg.Imports {
+			f.Imports = append(f.Imports, graph.Import{Path: imp})
+		}
+		if len(pkg.CgoFiles) > 0 {
+			f.Imports = append(f.Imports, graph.Import{Path: "C"})
+		}
+		next := s.addFile(it.dir, "", false, f)
+		if s.opts.Tests {
+			f := &GoFile{}
+			for _, imp := range append(pkg.TestImports, pkg.XTestImports...) {
+				f.Imports = append(f.Imports, graph.Import{Path: imp})
+			}
+			next = append(next, s.addFile(it.dir, "", true, f)...)
+		}
+		for _, nextDir := range next {
+			if _, ok := seen[nextDir]; !ok && pkgs[nextDir] != nil {
+				seen[nextDir] = struct{}{}
+				queue = append(queue, item{nextDir, it.depth + 1})
+			}
+		}
+	}
+	return nil
+}
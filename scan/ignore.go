@@ -0,0 +1,115 @@
+package scan
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sequix/baobab/internal/glob"
+)
+
+// ignoreFile lists, like a .gitignore, files and directories to leave out
+// of scans, relative to the directory it is in.
+const ignoreFile = ".baobabignore"
+
+// ignoreRule is a pattern of an ignoreFile.
+type ignoreRule struct {
+	pattern  string
+	negate   bool // a !pattern, including back what earlier rules ignore
+	dirOnly  bool // a pattern/, only matching directories
+	anchored bool // matched against the path relative to the ignoreFile, not the base name
+}
+
+// match reports whether the file, or directory if dir, at rel, a slash
+// separated path relative to the ignoreFile of r, matches r.
+func (r ignoreRule) match(rel string, dir bool) bool {
+	if r.dirOnly && !dir {
+		return false
+	}
+	if r.anchored {
+		return glob.Match(r.pattern, rel)
+	}
+	return glob.Match(r.pattern, rel[strings.LastIndex(rel, "/")+1:])
+}
+
+// rulesOf returns the rules of the ignoreFile in dir, none if there is no
+// such file or it cannot be read.
+func (s *Scanner) rulesOf(dir string) []ignoreRule {
+	s.ignoreMu.Lock()
+	defer s.ignoreMu.Unlock()
+	if rules, ok := s.ignoreRules[dir]; ok {
+		return rules
+	}
+	rules, err := parseIgnore(filepath.Join(s.path(dir), ignoreFile))
+	if err != nil && !os.IsNotExist(err) {
+		s.warn("ignore-file", filepath.Join(dir, ignoreFile), "failed to read ignore file", err)
+	}
+	s.ignoreRules[dir] = rules
+	return rules
+}
+
+// parseIgnore reads the rules of an ignoreFile, blank lines and lines
+// starting with # ignored.
+func parseIgnore(file string) ([]ignoreRule, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var (
+		result []ignoreRule
+		scan   = bufio.NewScanner(f)
+	)
+	for scan.Scan() {
+		line := strings.TrimRight(scan.Text(), " \t")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var r ignoreRule
+		if strings.HasPrefix(line, "!") {
+			r.negate, line = true, line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly, line = true, strings.TrimRight(line, "/")
+		}
+		r.anchored = strings.Contains(line, "/")
+		r.pattern = strings.TrimPrefix(line, "/")
+		if r.pattern != "" {
+			result = append(result, r)
+		}
+	}
+	return result, scan.Err()
+}
+
+// ignored reports whether the file, or directory if dir, at path, relative
+// to the root of the scan, is left out by the ignoreFile of the root or of a
+// directory below it. As with git, nothing in an ignored directory can be
+// included back.
+func (s *Scanner) ignored(path string, dir bool) bool {
+	elems := strings.Split(filepath.ToSlash(filepath.Clean(path)), "/")
+	if elems[0] == "." || elems[0] == ".." {
+		return false
+	}
+	for i := 1; i <= len(elems); i++ {
+		if s.ignoredIn(elems[:i], i < len(elems) || dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoredIn reports whether the last rule of the ignoreFiles from the root
+// down to the parent of the path made of elems matching it ignores it.
+func (s *Scanner) ignoredIn(elems []string, dir bool) bool {
+	result := false
+	for i := 0; i < len(elems); i++ {
+		rel := strings.Join(elems[i:], "/")
+		for _, r := range s.rulesOf(filepath.Join(elems[:i]...)) {
+			if r.match(rel, dir) {
+				result = !r.negate
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,85 @@
+package scan
+
+import (
+	"context"
+	"go/types"
+
+	"github.com/sequix/baobab/graph"
+	"golang.org/x/tools/go/packages"
+)
+
+// namedType is a type declared at package level in the scanned modules.
+type namedType struct {
+	obj  *types.TypeName
+	dir  string
+	mod  *Module
+	node string // name in the graph, once added
+}
+
+// ImplementsGraph returns the graph of the types of the scanned modules
+// implementing their interfaces, type checking every package of them.
+// Nodes are types, named like the functions of CallGraph, interfaces marked
+// Interface, and an edge goes from a type to an interface it or a pointer
+// to it implements. Generic types and interfaces with no method are left
+// out, as are types nothing implements or implementing nothing.
+func (s *Scanner) ImplementsGraph(ctx context.Context) (*graph.Graph, error) {
+	pkgs, err := s.loadTyped(ctx, packages.NeedName|packages.NeedTypes|packages.NeedDeps|packages.NeedImports, false)
+	if err != nil {
+		return nil, err
+	}
+	var ifaces, concrete []*namedType
+	for _, t := range s.namedTypes(pkgs) {
+		named, ok := t.obj.Type().(*types.Named)
+		if !ok || named.TypeParams().Len() > 0 {
+			continue
+		}
+		if iface, ok := named.Underlying().(*types.Interface); ok {
+			if iface.NumMethods() > 0 {
+				ifaces = append(ifaces, t)
+			}
+			continue
+		}
+		concrete = append(concrete, t)
+	}
+
+	g := graph.New()
+	add := func(t *namedType) string {
+		if t.node == "" {
+			t.node = s.objectNode(g, t.dir, t.mod, t.obj.Pkg().Path(), t.obj.Name())
+		}
+		return t.node
+	}
+	for _, c := range concrete {
+		ptr := types.NewPointer(c.obj.Type())
+		for _, i := range ifaces {
+			iface := i.obj.Type().Underlying().(*types.Interface)
+			if types.Implements(c.obj.Type(), iface) || types.Implements(ptr, iface) {
+				g.AddEdge(add(c), add(i))
+				g.Node(i.node).Interface = true
+			}
+		}
+	}
+	return g, nil
+}
+
+// namedTypes returns the types declared at package level in pkgs and the
+// packages they import, for those of the scanned modules.
+func (s *Scanner) namedTypes(pkgs []*packages.Package) []*namedType {
+	var result []*namedType
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if pkg.Types == nil {
+			return
+		}
+		dir, mod, ok := s.resolveImport(pkg.PkgPath)
+		if !ok || s.excluded(dir) {
+			return
+		}
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			if obj, ok := scope.Lookup(name).(*types.TypeName); ok && !obj.IsAlias() {
+				result = append(result, &namedType{obj: obj, dir: dir, mod: mod})
+			}
+		}
+	})
+	return result
+}
@@ -0,0 +1,156 @@
+package scan
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// packageFiles are the go files of a package, by base name, the build
+// decided on.
+type packageFiles struct {
+	goFiles   []string
+	testFiles []string // _test.go files, the external tests' included
+}
+
+// loadPackages fills the graph starting from the packages in entries, or
+// every package of every module if there are none, loading them with
+// golang.org/x/tools/go/packages to learn which files make up the build.
+// Build tags, cgo, vendoring and nested modules are handled exactly like in
+// a build, but it is much slower than scanning on huge repos.
+func (s *Scanner) loadPackages(ctx context.Context, entries []string) error {
+	var patterns []string
+	for _, entry := range entries {
+		patterns = append(patterns, "./"+filepath.ToSlash(filepath.Clean(entry)))
+	}
+	if len(entries) == 0 {
+		for _, m := range s.modules {
+			patterns = append(patterns, "./"+filepath.ToSlash(m.Dir)+"/...")
+		}
+	}
+	env, flags := s.goListEnv()
+	cfg := &packages.Config{
+		Context:    ctx,
+		Mode:       packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps | packages.NeedForTest,
+		Dir:        s.root,
+		Env:        env,
+		BuildFlags: flags,
+		// Tests are loaded anyway, to count their files.
+		Tests: true,
+	}
+	s.logger.Debug("loading packages", "patterns", patterns)
+	loaded, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return fmt.Errorf("failed to load packages %s: %s", patterns, err)
+	}
+
+	var (
+		pkgs    = map[string]*packageFiles{} // by directory
+		roots   []string
+		loadErr error
+	)
+	packages.Visit(loaded, nil, func(pkg *packages.Package) {
+		if strings.HasSuffix(pkg.PkgPath, ".test") {
+			// The generated main package of tests.
+			return
+		}
+		path := pkg.PkgPath
+		if pkg.ForTest != "" {
+			path = pkg.ForTest
+		}
+		dir, _, ok := s.resolveImport(path)
+		if !ok {
+			return
+		}
+		for _, e := range pkg.Errors {
+			if loadErr == nil && (pkg.ForTest == "" || s.opts.Tests) {
+				loadErr = s.tolerate(pkg.PkgPath, fmt.Errorf("failed to load package %s: %s", pkg.PkgPath, e))
+			}
+		}
+		files := pkgs[dir]
+		if files == nil {
+			files = &packageFiles{}
+			pkgs[dir] = files
+		}
+		for _, file := range pkg.GoFiles {
+			name := filepath.Base(file)
+			switch {
+			case !strings.HasSuffix(name, "_test.go"):
+				if pkg.ForTest == "" {
+					files.goFiles = append(files.goFiles, name)
+				}
+			case !slices.Contains(files.testFiles, name):
+				files.testFiles = append(files.testFiles, name)
+			}
+		}
+	})
+	if loadErr != nil {
+		return loadErr
+	}
+	for _, pkg := range loaded {
+		if pkg.ForTest != "" || strings.HasSuffix(pkg.PkgPath, ".test") {
+			continue
+		}
+		if dir, _, ok := s.resolveImport(pkg.PkgPath); ok && !slices.Contains(roots, dir) {
+			roots = append(roots, dir)
+		}
+	}
+	if len(roots) == 0 {
+		return fmt.Errorf("no package found in %s", patterns)
+	}
+
+	type item struct {
+		dir   string
+		depth int
+	}
+	var (
+		queue []item
+		seen  = map[string]struct{}{}
+	)
+	for _, dir := range roots {
+		queue = append(queue, item{dir, 0})
+		seen[dir] = struct{}{}
+	}
+	for len(queue) > 0 {
+		it := queue[0]
+		queue = queue[1:]
+		if s.opts.Depth > 0 && it.depth > s.opts.Depth || s.excluded(it.dir) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var (
+			pkg   = pkgs[it.dir]
+			files = slices.Clone(pkg.goFiles)
+			tests = len(files)
+		)
+		if s.opts.Tests {
+			files = append(files, pkg.testFiles...)
+		}
+		s.logger.Debug("scanning package", "dir", it.dir, "depth", it.depth)
+		s.addPackage(it.dir)
+		s.graph.Node(it.dir).TestFiles = len(pkg.testFiles)
+		s.progress.AddDir(it.dir, len(files))
+		parsed := make([]*parsedFile, len(files))
+		for i, name := range files {
+			parsed[i] = &parsedFile{dir: it.dir, path: filepath.Join(it.dir, name), test: i >= tests}
+		}
+		s.parseFiles(parsed)
+		next, err := s.addFiles(parsed)
+		if err != nil {
+			return err
+		}
+		for _, nextDir := range next {
+			if _, ok := seen[nextDir]; !ok && pkgs[nextDir] != nil {
+				seen[nextDir] = struct{}{}
+				queue = append(queue, item{nextDir, it.depth + 1})
+			}
+		}
+	}
+	return nil
+}
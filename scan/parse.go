@@ -0,0 +1,135 @@
+package scan
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sequix/baobab/graph"
+)
+
+// parseFile returns the imports and more of file, reading only up to its
+// first declaration with the hand-rolled lexer.
+func parseFile(file string) (*GoFile, error) {
+	fileReader, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %s", file, err)
+	}
+	defer fileReader.Close()
+	var (
+		result  = &GoFile{}
+		pkgLine int
+		scan    = NewLexer(bufio.NewReader(fileReader))
+	)
+	for {
+		token := scan.Next()
+		switch token.Type {
+		case EOF:
+			result.Canonical = canonicalComment(scan.Comments(), pkgLine)
+			return result, nil
+		case Error:
+			return nil, posErrorf(file, token, "%s", token.Text)
+		case Word:
+			switch token.Text {
+			case "package":
+				nextToken := scan.Next()
+				if nextToken.Type != Word {
+					return nil, posErrorf(file, nextToken, "expected a word after 'package' got %s", nextToken)
+				}
+				result.Package = nextToken.Text
+				pkgLine = nextToken.Line
+			case "import":
+				partial, err := parseImport(file, scan)
+				if err != nil {
+					return nil, err
+				}
+				result.Imports = append(result.Imports, partial...)
+			case "var", "const", "func", "type":
+				result.Canonical = canonicalComment(scan.Comments(), pkgLine)
+				return result, nil
+			}
+		default:
+			return nil, posErrorf(file, token, "unexpected token %s", token)
+		}
+	}
+}
+
+// canonicalRe matches an import comment, as in package foo // import "path".
+var canonicalRe = regexp.MustCompile(`^//\s*import\s+("[^"]*"|` + "`[^`]*`" + `)\s*$`)
+
+// canonicalComment returns the path of the import comment on line, the line
+// of the package clause, if there is one.
+func canonicalComment(comments []Token, line int) string {
+	for _, c := range comments {
+		if c.Line != line {
+			continue
+		}
+		if m := canonicalRe.FindStringSubmatch(c.Text); m != nil {
+			return unquote(m[1])
+		}
+	}
+	return ""
+}
+
+// posErrorf returns an error located at token in file, like file.go:12:5: msg.
+func posErrorf(file string, token Token, format string, args ...interface{}) error {
+	return fmt.Errorf("%s:%d:%d: %s", file, token.Line, token.Col, fmt.Sprintf(format, args...))
+}
+
+// unquote returns the value of a string token, interpreting escapes.
+func unquote(text string) string {
+	if s, err := strconv.Unquote(text); err == nil {
+		return s
+	}
+	return strings.Trim(text, "`\"")
+}
+
+func parseImport(file string, scan *Lexer) ([]graph.Import, error) {
+	token := scan.Next()
+	switch token.Type {
+	case EOF:
+		return nil, posErrorf(file, token, "unexpected EOF after 'import'")
+	case Error:
+		return nil, posErrorf(file, token, "scan element after 'import' error: %s", token.Text)
+	case Word, Dot:
+		nextToken := scan.Next()
+		if nextToken.Type != String {
+			return nil, posErrorf(file, nextToken, "expected string after import alias %s got %s", token.Text, nextToken)
+		}
+		return []graph.Import{{Name: token.Text, Path: unquote(nextToken.Text), Line: nextToken.Line}}, nil
+	case String:
+		return []graph.Import{{Path: unquote(token.Text), Line: token.Line}}, nil
+	case LeftParen:
+		return parseImportParen(file, scan)
+	default:
+		return nil, posErrorf(file, token, "unexpected token while scanning 'import' %s", token)
+	}
+}
+
+func parseImportParen(file string, scan *Lexer) ([]graph.Import, error) {
+	var result []graph.Import
+	for {
+		token := scan.Next()
+		switch token.Type {
+		case EOF:
+			return nil, posErrorf(file, token, "unexpected EOF after 'import ('")
+		case Error:
+			return nil, posErrorf(file, token, "scan element after 'import (' error: %s", token.Text)
+		case Word, Dot:
+			nextToken := scan.Next()
+			if nextToken.Type != String {
+				return nil, posErrorf(file, nextToken, "expected string after import alias %s got %s", token.Text, nextToken)
+			}
+			result = append(result, graph.Import{Name: token.Text, Path: unquote(nextToken.Text), Line: nextToken.Line})
+		case String:
+			result = append(result, graph.Import{Path: unquote(token.Text), Line: token.Line})
+		case RightParen:
+			return result, nil
+		default:
+			return nil, posErrorf(file, token, "unexpected token while scanning 'import (' %s", token)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package scan
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"strconv"
+
+	"github.com/sequix/baobab/graph"
+)
+
+// parseFileGo returns the imports and more of file, like parseFile, but uses
+// go/parser instead of the hand-rolled scanner. It is slower, but accepts
+// any file the go tool accepts.
+func parseFileGo(file string) (*GoFile, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, nil, parser.ImportsOnly|parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	result := &GoFile{Package: f.Name.Name}
+	pkgLine := fset.Position(f.Name.Pos()).Line
+	for _, group := range f.Comments {
+		for _, c := range group.List {
+			if fset.Position(c.Pos()).Line == pkgLine && c.Pos() > f.Name.Pos() {
+				if m := canonicalRe.FindStringSubmatch(c.Text); m != nil {
+					result.Canonical = unquote(m[1])
+				}
+			}
+		}
+	}
+	for _, imp := range f.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			return nil, fmt.Errorf("bad import path %s: %s", imp.Path.Value, err)
+		}
+		var name string
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		result.Imports = append(result.Imports, graph.Import{Name: name, Path: path, Line: fset.Position(imp.Path.Pos()).Line})
+	}
+	return result, nil
+}
@@ -0,0 +1,241 @@
+package scan
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sequix/baobab/graph"
+)
+
+// protoFile is what matters of a .proto file: its imports and the Go
+// package generated from it.
+type protoFile struct {
+	path      string // relative to the root, slash-separated
+	dir       string
+	module    string
+	imports   []graph.Import
+	goPackage string // import path in option go_package, if any
+}
+
+// ProtoNode returns the name of the node standing for the .proto files of
+// dir.
+func ProtoNode(dir string) string {
+	if dir == "." {
+		return ".proto"
+	}
+	return dir + ".proto"
+}
+
+// addProtos overlays the imports of the .proto files in the scanned modules
+// on the graph: a node per directory of them, edges to the directories they
+// import from, and edges from the Go packages generated from them. When
+// scanning from entries, only the protos Go packages of the graph are
+// generated from, and those they import, are kept.
+func (s *Scanner) addProtos() error {
+	files, err := s.protoFiles()
+	if err != nil {
+		return err
+	}
+	byPath := map[string]*protoFile{}
+	for _, f := range files {
+		byPath[f.path] = f
+	}
+	goNodes := s.graph.Nodes()
+	for _, f := range files {
+		name := ProtoNode(f.dir)
+		node := s.graph.AddNode(name)
+		node.Proto, node.Module = true, f.module
+		for _, imp := range f.imports {
+			to := resolveProto(byPath, imp.Path)
+			if to != nil {
+				imp.Dir = ProtoNode(to.dir)
+				if to.dir != f.dir {
+					s.graph.AddImport(name, imp.Dir, imp)
+				}
+			}
+			node.Imports = append(node.Imports, imp)
+		}
+		if f.goPackage == "" {
+			continue
+		}
+		if dir, _, ok := s.resolveImport(f.goPackage); ok && s.graph.Node(dir) != nil && !s.graph.Node(dir).Proto {
+			s.graph.AddEdge(dir, name)
+		}
+	}
+	if len(s.entries) > 0 {
+		reachable := graph.Reachable(s.graph, goNodes)
+		for _, n := range s.graph.Nodes() {
+			if !reachable[n] {
+				s.graph.RemoveNode(n)
+			}
+		}
+	}
+	return nil
+}
+
+// removeProtos removes the nodes addProtos added.
+func (s *Scanner) removeProtos() {
+	for _, n := range s.graph.Nodes() {
+		if s.graph.Node(n).Proto {
+			s.graph.RemoveNode(n)
+		}
+	}
+}
+
+// resolveProto returns the file an import statement of path refers to,
+// relative to an unknown include directory: the file whose path ends with
+// it, the shortest such path if several do.
+func resolveProto(byPath map[string]*protoFile, imp string) *protoFile {
+	if f, ok := byPath[imp]; ok {
+		return f
+	}
+	var best *protoFile
+	for p, f := range byPath {
+		if strings.HasSuffix(p, "/"+imp) && (best == nil || len(p) < len(best.path) || len(p) == len(best.path) && p < best.path) {
+			best = f
+		}
+	}
+	return best
+}
+
+// protoFiles finds and parses the .proto files of every module, in the
+// directories a whole module scan would look at.
+func (s *Scanner) protoFiles() ([]*protoFile, error) {
+	var result []*protoFile
+	for _, m := range s.modules {
+		top := s.path(m.Dir)
+		err := filepath.Walk(top, func(p string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(top, p)
+			if err != nil {
+				return err
+			}
+			dir := filepath.Join(m.Dir, rel)
+			if fi.Mode()&os.ModeSymlink != 0 {
+				return nil
+			}
+			if fi.IsDir() {
+				if dir != m.Dir && (s.isModuleRoot(dir) || s.isIgnoredDir(fi.Name())) || fi.Name() == "vendor" || s.pruned(dir) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			dir = filepath.Dir(dir)
+			if !strings.HasSuffix(fi.Name(), ".proto") || s.excluded(dir) {
+				return nil
+			}
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			f := parseProto(string(data))
+			f.dir, f.module = dir, m.Path
+			f.path = filepath.ToSlash(filepath.Join(dir, fi.Name()))
+			for i := range f.imports {
+				f.imports[i].File = filepath.Join(dir, fi.Name())
+			}
+			s.logger.Debug("scanning proto file", "path", f.path)
+			result = append(result, f)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk module %s: %s", m.Path, err)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].path < result[j].path })
+	return result, nil
+}
+
+// parseProto returns the imports and go_package option of the .proto file
+// src. Statements it does not know are skipped.
+func parseProto(src string) *protoFile {
+	f := &protoFile{}
+	toks := protoTokens(src)
+	for i := 0; i < len(toks); i++ {
+		switch toks[i].text {
+		case "import":
+			j := i + 1
+			if j < len(toks) && (toks[j].text == "public" || toks[j].text == "weak") {
+				j++
+			}
+			if j < len(toks) {
+				if p, err := strconv.Unquote(toks[j].text); err == nil {
+					f.imports = append(f.imports, graph.Import{Path: p, Line: toks[j].line})
+				}
+			}
+		case "option":
+			if i+3 < len(toks) && toks[i+1].text == "go_package" && toks[i+2].text == "=" {
+				if p, err := strconv.Unquote(toks[i+3].text); err == nil {
+					// "example.com/foo;foo" names the package too.
+					f.goPackage = path.Clean(strings.SplitN(p, ";", 2)[0])
+				}
+			}
+		}
+	}
+	return f
+}
+
+type protoToken struct {
+	text string
+	line int
+}
+
+// protoTokens splits src into identifiers, double-quoted strings, as Go
+// literals, and punctuation, leaving comments out.
+func protoTokens(src string) []protoToken {
+	var toks []protoToken
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				end = len(src) - i - 2
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && src[j] != c && src[j] != '\n' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			text := src[i:min(j+1, len(src))]
+			if c == '\'' && len(text) >= 2 {
+				text = `"` + strings.ReplaceAll(text[1:len(text)-1], `"`, `\"`) + `"`
+			}
+			toks = append(toks, protoToken{text, line})
+			i = j + 1
+		case c == '_' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9':
+			j := i
+			for j < len(src) && (src[j] == '_' || src[j] == '.' || src[j] >= 'a' && src[j] <= 'z' || src[j] >= 'A' && src[j] <= 'Z' || src[j] >= '0' && src[j] <= '9') {
+				j++
+			}
+			toks = append(toks, protoToken{src[i:j], line})
+			i = j
+		default:
+			toks = append(toks, protoToken{string(c), line})
+			i++
+		}
+	}
+	return toks
+}
@@ -0,0 +1,586 @@
+// Package scan builds the import graph of the packages of a go module, or
+// of every module of a workspace, by reading their go files.
+//
+// Packages are named by their directory relative to the root of the module
+// or workspace, and only the imports of the scanned modules are followed:
+//
+//	g, err := scan.Scan(ctx, scan.Options{Dir: "path/to/module", Entries: []string{"cmd/app"}})
+package scan
+
+import (
+	"context"
+	"fmt"
+	"go/build"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sequix/baobab/graph"
+	"github.com/sequix/baobab/internal/glob"
+)
+
+// Options says what to scan and how.
+type Options struct {
+	// Dir is the directory to scan from, the current one if empty. Like
+	// the go tool, it scans the workspace of the closest go.work, else the
+	// module of the closest go.mod.
+	Dir string
+	// Module is the path of the module, read from its go.mod if empty. When
+	// set, go.work files are ignored.
+	Module string
+	// Entries are the packages to start from, as directories relative to
+	// Dir or import paths. Without any, every package of every module is
+	// scanned.
+	Entries []string
+	// Depth is how many imports away from Entries to scan, 0 for no limit.
+	Depth int
+
+	// GOOS, GOARCH and Tags restrict the scan to the files built for that
+	// target. If all are empty, every file building somewhere is scanned.
+	GOOS   string
+	GOARCH string
+	Tags   []string
+	// Tests adds the imports of _test.go files, marking the edges only they
+	// make as test-only.
+	Tests bool
+
+	// Stdlib adds standard library imports: "all" one node per package,
+	// "top" per top-level package, "single" one node for all of them, none
+	// if empty or "off".
+	Stdlib string
+	// External adds the third-party imports, one node per module required.
+	External bool
+	// Vendor adds the vendored packages imported, unless External is set.
+	Vendor bool
+	// Cgo adds a marker node named "marker:cgo" imported by packages using
+	// cgo.
+	Cgo bool
+	// Proto adds a node per directory of .proto files, named by ProtoNode,
+	// importing those their import statements name and imported by the Go
+	// packages their go_package option names.
+	Proto bool
+
+	// Exclude and Only are globs of directories to leave out, and the only
+	// ones to scan, "**" matching any number of directories.
+	Exclude []string
+	Only    []string
+	// IncludeSpecial lists the kinds of directories the go tool ignores to
+	// scan anyway when scanning whole modules: testdata, hidden, underscore.
+	IncludeSpecial []string
+	// SkipGenerated leaves out files with a // Code generated ... DO NOT
+	// EDIT. comment, see Scanner.Generated.
+	SkipGenerated bool
+	// Lenient skips files failing to parse and missing package directories
+	// with a warning, instead of failing.
+	Lenient bool
+
+	// Backend is how to find imports: "scanner", the default, a fast
+	// hand-rolled scanner, "parser", go/parser, or "packages", which loads
+	// the packages with golang.org/x/tools/go/packages to learn the files of
+	// the build and parses those with go/parser.
+	Backend string
+	// GoList, if not empty, is the output of go list -deps -json to take the
+	// packages and their imports from, instead of any file.
+	GoList []byte
+	// Workers is the number of files parsed concurrently, one per CPU if 0.
+	Workers int
+	// Cache is a directory caching the imports of files by content hash,
+	// none if empty.
+	Cache string
+	// RecordFiles records every file considered, see Scanner.Files.
+	RecordFiles bool
+
+	// Logger gets debug logs of what is scanned, nothing is logged if nil.
+	Logger *slog.Logger
+	// OnWarning gets the problems a scan goes on despite, they are logged
+	// to Logger if nil.
+	OnWarning func(Warning)
+	// Progress, if not nil, is told how the scan goes.
+	Progress Progress
+}
+
+// Warning is a problem a scan went on despite.
+type Warning struct {
+	Kind    string // parse-error, missing-dir, symlink or ignore-file
+	Path    string // file or directory concerned, if any
+	Message string
+	Err     error // cause, if any
+}
+
+// GoFile is what a backend found in a go file.
+type GoFile struct {
+	Package   string // package name
+	Canonical string // path from a package clause // import "path" comment
+	Imports   []graph.Import
+	Lines     int // number of lines
+}
+
+// Progress is told how a scan goes, from concurrent goroutines.
+type Progress interface {
+	AddDir(dir string, files int) // a directory with files to parse is scanned
+	Parsing(file string)          // a file starts being parsed
+	Parsed(dir string)            // a file of dir was parsed
+}
+
+// noProgress is the Progress of scans nobody follows.
+type noProgress struct{}
+
+func (noProgress) AddDir(string, int) {}
+func (noProgress) Parsing(string)     {}
+func (noProgress) Parsed(string)      {}
+
+// Scanner scans the modules Options asks for, and can scan them again.
+type Scanner struct {
+	opts    Options
+	logger  *slog.Logger
+	root    string // root of the workspace or module, relative to the current directory if Dir is
+	base    string // Dir, relative to root
+	entries []string
+	modules []Module
+	gopath  bool // scanning a GOPATH project, without go.mod
+
+	// buildCtx decides which files get compiled for the target platform,
+	// nil to scan every file regardless of its build constraints.
+	buildCtx *build.Context
+	// parse returns the imports and more of a go file, per Backend.
+	parse func(file string) (*GoFile, error)
+	// progress is Options.Progress, or does nothing.
+	progress Progress
+
+	graph  *graph.Graph
+	parsed map[string]struct{} // directories scanned
+	files  []*parsedFile       // with RecordFiles
+
+	// generatedFiles counts the files skipped by SkipGenerated, by package.
+	generatedFiles map[string]int
+	// generatedEdges holds the edges the skipped files would have added.
+	generatedEdges map[[2]string]struct{}
+
+	ignoreMu    sync.Mutex
+	ignoreRules map[string][]ignoreRule // by directory
+}
+
+// Scan scans what opts asks for and returns the graph of its packages.
+func Scan(ctx context.Context, opts Options) (*graph.Graph, error) {
+	s, err := New(opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.Scan(ctx)
+}
+
+// New checks opts and finds the modules to scan.
+func New(opts Options) (*Scanner, error) {
+	s := &Scanner{opts: opts, logger: opts.Logger, ignoreRules: map[string][]ignoreRule{}}
+	if s.logger == nil {
+		s.logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	if s.progress = opts.Progress; s.progress == nil {
+		s.progress = noProgress{}
+	}
+	switch opts.Backend {
+	case "", "scanner":
+		s.opts.Backend = "scanner"
+		s.parse = parseFile
+	case "parser", "packages":
+		s.parse = parseFileGo
+	default:
+		return nil, fmt.Errorf("unknown backend %q", opts.Backend)
+	}
+	s.parse = countLines(s.parse)
+	if opts.Cache != "" {
+		s.parse = cachedParse(opts.Cache, s.opts.Backend, s.parse)
+	}
+	switch opts.Stdlib {
+	case "", "off", "all", "top", "single":
+	default:
+		return nil, fmt.Errorf("unknown stdlib mode %q", opts.Stdlib)
+	}
+	if opts.Workers < 0 {
+		return nil, fmt.Errorf("workers must not be negative, got %d", opts.Workers)
+	} else if opts.Workers == 0 {
+		s.opts.Workers = runtime.NumCPU()
+	}
+	if opts.Dir == "" {
+		s.opts.Dir = "."
+	}
+	s.buildCtx = buildContext(opts.GOOS, opts.GOARCH, opts.Tags)
+	if fi, err := os.Stat(s.opts.Dir); err != nil {
+		return nil, err
+	} else if !fi.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", s.opts.Dir)
+	}
+	root, err := FindRoot(s.opts.Dir, opts.Module == "")
+	if err != nil {
+		return nil, err
+	}
+	dir, err := filepath.Abs(s.opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if s.base, err = filepath.Rel(root, dir); err != nil {
+		return nil, err
+	}
+	s.root = root
+	if !filepath.IsAbs(s.opts.Dir) {
+		// Keep paths, in errors too, as short as Dir makes them.
+		if cwd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(cwd, root); err == nil {
+				s.root = rel
+			}
+		}
+	}
+	if err := s.setupModules(opts.Module); err != nil {
+		return nil, err
+	}
+	for _, entry := range opts.Entries {
+		s.entries = append(s.entries, s.Resolve(entry))
+	}
+	s.reset()
+	return s, nil
+}
+
+// reset forgets everything scanned so far.
+func (s *Scanner) reset() {
+	s.graph = graph.New()
+	s.parsed = map[string]struct{}{}
+	s.files = nil
+	s.generatedFiles = map[string]int{}
+	s.generatedEdges = map[[2]string]struct{}{}
+}
+
+// Scan scans the packages of Options.Entries and those they import, or
+// every package of every module without entries, forgetting any previous
+// scan, and returns their graph.
+func (s *Scanner) Scan(ctx context.Context) (*graph.Graph, error) {
+	s.reset()
+	var err error
+	switch {
+	case len(s.opts.GoList) > 0:
+		err = s.readGoList(ctx, s.entries)
+	case s.opts.Backend == "packages":
+		err = s.loadPackages(ctx, s.entries)
+	case len(s.entries) == 0:
+		var dirs []string
+		if dirs, err = s.PackageDirs(); err == nil {
+			err = s.scanDirs(ctx, dirs)
+		}
+	default:
+		err = s.scanDirs(ctx, s.entries)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.opts.Proto {
+		if err := s.addProtos(); err != nil {
+			return nil, err
+		}
+	}
+	return s.graph, nil
+}
+
+// Rescan updates the graph of the last scan for the packages in dirs, whose
+// files changed, then drops the packages no longer reachable. The graph
+// may differ from the one a new scan would make with Depth or the packages
+// backend, whose results depend on the whole scan.
+func (s *Scanner) Rescan(ctx context.Context, dirs []string) error {
+	if len(s.opts.GoList) > 0 {
+		return fmt.Errorf("cannot rescan packages taken from go list output")
+	}
+	if s.opts.Proto {
+		s.removeProtos()
+	}
+	var again []string
+	for _, dir := range dirs {
+		_, parsed := s.parsed[dir]
+		if !parsed && len(s.entries) > 0 {
+			// Not imported from any entry, at least not yet.
+			continue
+		}
+		delete(s.parsed, dir)
+		s.forgetGenerated(dir)
+		if n := s.graph.Node(dir); n != nil {
+			s.graph.RemoveOut(dir)
+			n.Imports, n.Cgo, n.Canonical = nil, false, ""
+			n.Files, n.TestFiles, n.Lines = 0, 0, 0
+		}
+		if _, err := os.Stat(s.path(dir)); err == nil {
+			again = append(again, dir)
+		}
+	}
+	if err := s.scanDirs(ctx, again); err != nil {
+		return err
+	}
+	roots := s.entries
+	if len(roots) == 0 {
+		var err error
+		if roots, err = s.PackageDirs(); err != nil {
+			return err
+		}
+	}
+	var clean []string
+	for _, root := range roots {
+		clean = append(clean, filepath.Clean(root))
+	}
+	reachable := graph.Reachable(s.graph, clean)
+	for _, n := range s.graph.Nodes() {
+		if !reachable[n] {
+			s.graph.RemoveNode(n)
+			delete(s.parsed, n)
+		}
+	}
+	if s.opts.Proto {
+		return s.addProtos()
+	}
+	return nil
+}
+
+// Graph returns the graph of the last scan.
+func (s *Scanner) Graph() *graph.Graph {
+	return s.graph
+}
+
+// Root returns the root directory of the workspace or module scanned, which
+// package names are relative to. It is relative to the current directory
+// if Options.Dir is.
+func (s *Scanner) Root() string {
+	return s.root
+}
+
+// Modules returns the modules scanned, longest path first.
+func (s *Scanner) Modules() []Module {
+	return s.modules
+}
+
+// Resolve returns the name of the package of entry, given either as an
+// import path in one of the modules, or a directory relative to
+// Options.Dir.
+func (s *Scanner) Resolve(entry string) string {
+	return s.resolveEntry(entry, s.base)
+}
+
+// path returns the path of dir, a directory or file named relative to the
+// root, relative to the current directory.
+func (s *Scanner) path(dir string) string {
+	return filepath.Join(s.root, dir)
+}
+
+// warn reports a problem the scan goes on despite.
+func (s *Scanner) warn(kind, path, msg string, err error) {
+	w := Warning{Kind: kind, Path: path, Message: msg, Err: err}
+	if s.opts.OnWarning != nil {
+		s.opts.OnWarning(w)
+		return
+	}
+	var args []any
+	if path != "" {
+		args = append(args, "path", path)
+	}
+	if err != nil {
+		args = append(args, "err", err)
+	}
+	s.logger.Warn(msg, args...)
+}
+
+// PackageDirs returns the directories of the packages in every module, as
+// the go tool would find them with ./..., left out those excluded.
+func (s *Scanner) PackageDirs() ([]string, error) {
+	var dirs []string
+	for _, m := range s.modules {
+		m := m
+		top := s.path(m.Dir)
+		err := filepath.Walk(top, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(top, path)
+			if err != nil {
+				return err
+			}
+			dir := filepath.Join(m.Dir, rel)
+			if fi.Mode()&os.ModeSymlink != 0 {
+				// Like the go tool, ./... does not follow symlinks.
+				if st, err := os.Stat(path); err == nil && st.IsDir() {
+					s.warn("symlink", dir, "skipping symlinked directory", nil)
+				}
+				return nil
+			}
+			if !fi.IsDir() {
+				return nil
+			}
+			if dir != m.Dir && s.isModuleRoot(dir) {
+				return filepath.SkipDir
+			}
+			if fi.Name() == "vendor" || dir != m.Dir && s.isIgnoredDir(fi.Name()) || s.pruned(dir) {
+				return filepath.SkipDir
+			}
+			if !s.excluded(dir) && s.hasGoFiles(dir) {
+				dirs = append(dirs, dir)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk module %s: %s", m.Path, err)
+		}
+	}
+	return dirs, nil
+}
+
+// isIgnoredDir reports whether a directory named name is skipped when
+// scanning whole modules, like the go tool does, unless IncludeSpecial
+// says otherwise.
+func (s *Scanner) isIgnoredDir(name string) bool {
+	var kind string
+	switch {
+	case name == "testdata":
+		kind = "testdata"
+	case strings.HasPrefix(name, "."):
+		kind = "hidden"
+	case strings.HasPrefix(name, "_"):
+		kind = "underscore"
+	default:
+		return false
+	}
+	for _, k := range s.opts.IncludeSpecial {
+		if k == kind {
+			return false
+		}
+	}
+	return true
+}
+
+// isModuleRoot reports whether dir has a go.mod of its own.
+func (s *Scanner) isModuleRoot(dir string) bool {
+	_, err := os.Stat(filepath.Join(s.path(dir), "go.mod"))
+	return err == nil
+}
+
+// hasGoFiles reports whether dir directly contains any go file to scan.
+func (s *Scanner) hasGoFiles(dir string) bool {
+	fis, err := ioutil.ReadDir(s.path(dir))
+	if err != nil {
+		return false
+	}
+	for _, fi := range fis {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), ".go") {
+			continue
+		}
+		if s.opts.Tests || !strings.HasSuffix(fi.Name(), "_test.go") {
+			return true
+		}
+	}
+	return false
+}
+
+// excluded reports whether the package in dir is left out of the scan, per
+// Exclude, Only and the .baobabignore files.
+func (s *Scanner) excluded(dir string) bool {
+	if glob.MatchAny(s.opts.Exclude, dir) || s.ignored(dir, true) {
+		return true
+	}
+	return len(s.opts.Only) > 0 && !glob.MatchAny(s.opts.Only, dir)
+}
+
+// pruned reports whether every package in or below dir is excluded, so
+// there is no point walking it.
+func (s *Scanner) pruned(dir string) bool {
+	if s.ignored(dir, true) {
+		return true
+	}
+	for _, p := range s.opts.Exclude {
+		if strings.HasSuffix(p, "/**") && glob.Match(p, dir) {
+			return true
+		}
+	}
+	if len(s.opts.Only) == 0 {
+		return false
+	}
+	for _, p := range s.opts.Only {
+		if glob.MatchPrefix(p, dir) {
+			return false
+		}
+	}
+	return true
+}
+
+// tolerate returns err, file at path failing to parse, or warns about it
+// and returns nil if Lenient.
+func (s *Scanner) tolerate(path string, err error) error {
+	if !s.opts.Lenient {
+		return err
+	}
+	s.warn("parse-error", path, "skipping file", err)
+	return nil
+}
+
+// addFile adds what was found in file of package dir to the graph, and
+// returns the directories of the imported packages in scanned modules.
+func (s *Scanner) addFile(dir, file string, test bool, f *GoFile) []string {
+	var (
+		node   = s.graph.Node(dir)
+		result []string
+	)
+	if f.Canonical != "" {
+		node.Canonical = f.Canonical
+	}
+	if file != "" && !test {
+		node.Files++
+		node.Lines += f.Lines
+	}
+	for _, imp := range f.Imports {
+		imp.File = file
+		imp.Test = test
+		nextDir, m, ok := s.resolveImport(imp.Path)
+		if !ok {
+			imp.Node = s.addOutside(dir, imp)
+			node.Imports = append(node.Imports, imp)
+			continue
+		}
+		imp.Dir = nextDir
+		node.Imports = append(node.Imports, imp)
+		if nextDir == dir || s.excluded(nextDir) {
+			continue
+		}
+		s.graph.AddImport(dir, nextDir, imp)
+		next := s.graph.Node(nextDir)
+		next.Module, next.ImportPath = m.Path, imp.Path
+		result = append(result, nextDir)
+	}
+	return result
+}
+
+// addPackage adds the package in dir to the graph, about to be scanned.
+func (s *Scanner) addPackage(dir string) {
+	node := s.graph.AddNode(dir)
+	if m := s.moduleOfDir(dir); m != nil {
+		node.Module = m.Path
+		if rel, err := filepath.Rel(m.Dir, dir); err == nil {
+			node.ImportPath = path.Join(m.Path, filepath.ToSlash(rel))
+		}
+	}
+}
+
+// Files returns the files considered by the last scan with RecordFiles,
+// sorted.
+func (s *Scanner) Files() []File {
+	var result []File
+	for _, pf := range s.files {
+		result = append(result, File{Dir: pf.dir, Path: pf.path, Skipped: pf.why()})
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result
+}
+
+// File is a go file considered by a scan.
+type File struct {
+	Dir     string // package directory
+	Path    string
+	Skipped string // why the file was left out, empty if it was not
+}
@@ -1,8 +1,9 @@
-package main
+package scan
 
 import (
 	"fmt"
 	"io"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 )
@@ -13,6 +14,8 @@ const eof = -1
 type Token struct {
 	Type Type   // The type of this item.
 	Text string // The text of this item.
+	Line int    // The line number where this item starts, 1-based.
+	Col  int    // The column in bytes where this item starts, 1-based.
 }
 
 // Type identifies the type of lex items.
@@ -26,6 +29,8 @@ const (
 	RightParen      // ')'
 	String          // quoted string (includes quotes)
 	Word            // space-separated word
+	Dot             // '.'
+	Comment         // line comment, only kept in Lexer.Comments
 )
 
 func (i Token) String() string {
@@ -41,9 +46,9 @@ func (i Token) String() string {
 }
 
 // stateFn represents the state of the scanner as a function that returns the next state.
-type stateFn func(*Scanner) stateFn
+type stateFn func(*Lexer) stateFn
 
-type Scanner struct {
+type Lexer struct {
 	r         io.ByteReader
 	done      bool
 	token     Token
@@ -53,12 +58,23 @@ type Scanner struct {
 	start     int    // start position of this item
 	lastRune  rune   // most recent return from next()
 	lastWidth int    // size of that rune
+	line      int    // line number of pos
+	col       int    // column of pos
+	lastLine  int    // line before the most recent next(), for backup()
+	lastCol   int    // column before the most recent next()
+	startLine int    // line number of start
+	startCol  int    // column of start
+	comments  []Token
 }
 
-// NewScanner creates and returns a new scanner.
-func NewScanner(r io.ByteReader) *Scanner {
-	l := &Scanner{
-		r: r,
+// NewLexer creates and returns a new lexer.
+func NewLexer(r io.ByteReader) *Lexer {
+	l := &Lexer{
+		r:         r,
+		line:      1,
+		col:       1,
+		startLine: 1,
+		startCol:  1,
 	}
 	return l
 }
@@ -66,7 +82,7 @@ func NewScanner(r io.ByteReader) *Scanner {
 // loadLine reads the next line of input and stores it in (appends it to) the input.
 // (l.input may have data left over when we are called.)
 // It strips carriage returns to make subsequent processing simpler.
-func (l *Scanner) loadLine() {
+func (l *Lexer) loadLine() {
 	l.buf = l.buf[:0]
 	for {
 		c, err := l.r.ReadByte()
@@ -92,7 +108,7 @@ func (l *Scanner) loadLine() {
 }
 
 // readRune reads the next rune from the input.
-func (l *Scanner) readRune() (rune, int) {
+func (l *Lexer) readRune() (rune, int) {
 	if !l.done && l.pos == len(l.input) {
 		l.loadLine()
 	}
@@ -103,37 +119,51 @@ func (l *Scanner) readRune() (rune, int) {
 }
 
 // next returns the next rune in the input.
-func (l *Scanner) next() rune {
+func (l *Lexer) next() rune {
 	l.lastRune, l.lastWidth = l.readRune()
 	l.pos += l.lastWidth
+	l.lastLine, l.lastCol = l.line, l.col
+	if l.lastRune == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col += l.lastWidth
+	}
 	return l.lastRune
 }
 
 // peek returns but does not consume the next rune in the input.
-func (l *Scanner) peek() rune {
+func (l *Lexer) peek() rune {
 	r, _ := l.readRune()
 	return r
 }
 
 // emit passes an item back to the client.
-func (l *Scanner) emit(t Type) stateFn {
+func (l *Lexer) emit(t Type) stateFn {
 	text := l.input[l.start:l.pos]
-	l.token = Token{t, text}
-	l.start = l.pos
+	l.token = Token{t, text, l.startLine, l.startCol}
+	l.ignore()
 	return nil
 }
 
+// ignore skips over the pending input before this point.
+func (l *Lexer) ignore() {
+	l.start = l.pos
+	l.startLine, l.startCol = l.line, l.col
+}
+
 // errorf returns an error token and empties the input.
-func (l *Scanner) errorf(format string, args ...interface{}) stateFn {
-	l.token = Token{Error, fmt.Sprintf(format, args...)}
+func (l *Lexer) errorf(format string, args ...interface{}) stateFn {
+	l.token = Token{Error, fmt.Sprintf(format, args...), l.startLine, l.startCol}
 	l.start = 0
 	l.pos = 0
+	l.startLine, l.startCol = l.line, l.col
 	l.input = l.input[:0]
 	return nil
 }
 
 // backup steps back one rune. Should only be called once per call of next.
-func (l *Scanner) backup() {
+func (l *Lexer) backup() {
 	if l.lastRune == eof {
 		return
 	}
@@ -142,14 +172,15 @@ func (l *Scanner) backup() {
 	}
 	if l.pos > l.start { // TODO can't happen?
 		l.pos -= l.lastWidth
+		l.line, l.col = l.lastLine, l.lastCol
 	}
 }
 
 // Next returns the next token.
-func (l *Scanner) Next() Token {
+func (l *Lexer) Next() Token {
 	l.lastRune = eof
 	l.lastWidth = 0
-	l.token = Token{EOF, "EOF"}
+	l.token = Token{EOF, "EOF", l.line, l.col}
 	state := lexAny
 	for {
 		state = state(l)
@@ -160,7 +191,7 @@ func (l *Scanner) Next() Token {
 }
 
 // lexAny scans non-space items.
-func lexAny(l *Scanner) stateFn {
+func lexAny(l *Lexer) stateFn {
 	switch r := l.next(); {
 	case r == eof:
 		return nil
@@ -186,6 +217,8 @@ func lexAny(l *Scanner) stateFn {
 		return l.emit(LeftParen)
 	case r == ')':
 		return l.emit(RightParen)
+	case r == '.':
+		return l.emit(Dot)
 	default:
 		return l.errorf("unrecognized character %#U", r)
 	}
@@ -193,17 +226,22 @@ func lexAny(l *Scanner) stateFn {
 
 // lexSpace scans a run of space characters.
 // One space has already been seen.
-func lexSpace(l *Scanner) stateFn {
+func lexSpace(l *Lexer) stateFn {
 	for unicode.IsSpace(l.peek()) {
 		l.next()
 	}
 	// Skips over the pending input.
-	l.start = l.pos
+	l.ignore()
 	return lexAny
 }
 
+// Comments returns the line comments skipped over so far.
+func (l *Lexer) Comments() []Token {
+	return l.comments
+}
+
 // lexComment scans a line comment. The comment marker // has been consumed.
-func lexCommentLine(l *Scanner) stateFn {
+func lexCommentLine(l *Lexer) stateFn {
 	var r rune
 	for {
 		r = l.next()
@@ -211,15 +249,17 @@ func lexCommentLine(l *Scanner) stateFn {
 			break
 		}
 	}
+	text := strings.TrimSuffix(l.input[l.start:l.pos], "\n")
+	l.comments = append(l.comments, Token{Comment, text, l.startLine, l.startCol})
 	if r == eof {
 		return nil
 	}
-	l.start = l.pos
+	l.ignore()
 	return lexAny
 }
 
 // lexComment scans a block comment. The comment marker /* has been consumed.
-func lexCommentBlock(l *Scanner) stateFn {
+func lexCommentBlock(l *Lexer) stateFn {
 	var r rune
 	for {
 		r = l.next()
@@ -234,12 +274,12 @@ func lexCommentBlock(l *Scanner) stateFn {
 	if r == eof {
 		return nil
 	}
-	l.start = l.pos
+	l.ignore()
 	return lexAny
 }
 
 // lexKeyword scans a keyword like package, import...
-func lexKeyword(l *Scanner) stateFn {
+func lexKeyword(l *Lexer) stateFn {
 	for isWordChar(l.peek()) {
 		l.next()
 	}
@@ -252,12 +292,25 @@ func isWordChar(r rune) bool {
 
 // lexQuote scans a quoted string.
 // The next character is the quote.
-func lexQuote(l *Scanner) stateFn {
+// Raw strings, quoted by '`', may span multiple lines.
+func lexQuote(l *Lexer) stateFn {
 	quote := l.next()
 	for {
 		switch l.next() {
-		case eof, '\n':
+		case eof:
 			return l.errorf("unterminated quoted string")
+		case '\n':
+			if quote != '`' {
+				return l.errorf("unterminated quoted string")
+			}
+		case '\\':
+			// Skip the escaped character, so \" does not end the string.
+			// There are no escapes in raw strings.
+			if quote != '`' {
+				if r := l.next(); r == eof || r == '\n' {
+					return l.errorf("unterminated quoted string")
+				}
+			}
 		case quote:
 			return l.emit(String)
 		}
@@ -0,0 +1,123 @@
+package scan
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// lex returns the tokens of input, up to EOF or the first error.
+func lex(input string) []Token {
+	l := NewLexer(strings.NewReader(input))
+	var tokens []Token
+	for {
+		t := l.Next()
+		tokens = append(tokens, t)
+		if t.Type == EOF || t.Type == Error {
+			return tokens
+		}
+	}
+}
+
+func TestLexer(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  []Token
+	}{
+		{"words and punctuation", "import (\n\t. \"fmt\"\n)", []Token{
+			{Word, "import", 1, 1}, {LeftParen, "(", 1, 8},
+			{Dot, ".", 2, 2}, {String, `"fmt"`, 2, 4},
+			{RightParen, ")", 3, 1}, {EOF, "EOF", 3, 2},
+		}},
+		{"escaped quote", `"a\"b" x`, []Token{
+			{String, `"a\"b"`, 1, 1}, {Word, "x", 1, 8}, {EOF, "EOF", 1, 9},
+		}},
+		{"escaped backslash", `"a\\" "b"`, []Token{
+			{String, `"a\\"`, 1, 1}, {String, `"b"`, 1, 7}, {EOF, "EOF", 1, 10},
+		}},
+		{"raw string", "`a\\` x", []Token{
+			{String, "`a\\`", 1, 1}, {Word, "x", 1, 6}, {EOF, "EOF", 1, 7},
+		}},
+		{"multiline raw string", "`a\nb\"\nc` x", []Token{
+			{String, "`a\nb\"\nc`", 1, 1}, {Word, "x", 3, 4}, {EOF, "EOF", 3, 5},
+		}},
+		{"carriage returns", "a\r\nb", []Token{
+			{Word, "a", 1, 1}, {Word, "b", 2, 1}, {EOF, "EOF", 2, 2},
+		}},
+		{"comments", "// c\n/* d\ne */ x", []Token{
+			{Word, "x", 3, 6}, {EOF, "EOF", 3, 7},
+		}},
+		{"multibyte columns", `"é" x`, []Token{
+			{String, `"é"`, 1, 1}, {Word, "x", 1, 6}, {EOF, "EOF", 1, 7},
+		}},
+		{"unterminated string", "\"a\nb\"", []Token{
+			{Error, "unterminated quoted string", 1, 1},
+		}},
+		{"escaped newline", "\"a\\\nb\"", []Token{
+			{Error, "unterminated quoted string", 1, 1},
+		}},
+		{"unterminated raw string", "x `a\nb", []Token{
+			{Word, "x", 1, 1}, {Error, "unterminated quoted string", 1, 3},
+		}},
+		{"unrecognized character", "x\n  #", []Token{
+			{Word, "x", 1, 1}, {Error, "unrecognized character U+0023 '#'", 2, 3},
+		}},
+	} {
+		got := lex(tc.input)
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%s: token %d = %+v, want %+v", tc.name, i, got[i], tc.want[i])
+			}
+		}
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	for _, tc := range []struct {
+		text, want string
+	}{
+		{`"fmt"`, "fmt"},
+		{"`fmt`", "fmt"},
+		{`"a\x2fb"`, "a/b"},
+		{`"a/b"`, "a/b"},
+		{`"a\057b"`, "a/b"},
+		{"`a\\x2fb`", "a\\x2fb"},
+	} {
+		if got := unquote(tc.text); got != tc.want {
+			t.Errorf("unquote(%s) = %q, want %q", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestParseFileErrorPosition(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.go": "package a\n\nimport (\n\t\"fmt\"\n\t`x\ny` #\n)\n",
+	})
+	_, err := parseFile(filepath.Join(dir, "a.go"))
+	if err == nil || !strings.HasSuffix(err.Error(), "a.go:6:4: scan element after 'import (' error: unrecognized character U+0023 '#'") {
+		t.Errorf("got error %v, want one at 6:4", err)
+	}
+}
+
+func TestParseFileEscapedImports(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.go": "package a // import \"example.com/\\x61\"\n\nimport (\n\tx \"net\\x2fhttp\"\n\t`encoding/json`\n)\n\nfunc F() {}\n",
+	})
+	f, err := parseFile(filepath.Join(dir, "a.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Canonical != "example.com/a" {
+		t.Errorf("canonical path %q, want example.com/a", f.Canonical)
+	}
+	if len(f.Imports) != 2 || f.Imports[0].Path != "net/http" || f.Imports[0].Name != "x" || f.Imports[1].Path != "encoding/json" || f.Imports[1].Line != 5 {
+		t.Errorf("imports %+v", f.Imports)
+	}
+}
@@ -1,6 +1,6 @@
 // Code generated by "stringer -type Type"; DO NOT EDIT.
 
-package main
+package scan
 
 import "strconv"
 
@@ -14,11 +14,13 @@ func _() {
 	_ = x[RightParen-3]
 	_ = x[String-4]
 	_ = x[Word-5]
+	_ = x[Dot-6]
+	_ = x[Comment-7]
 }
 
-const _Type_name = "EOFErrorLeftParenRightParenStringWord"
+const _Type_name = "EOFErrorLeftParenRightParenStringWordDotComment"
 
-var _Type_index = [...]uint8{0, 3, 8, 17, 27, 33, 37}
+var _Type_index = [...]uint8{0, 3, 8, 17, 27, 33, 37, 40, 47}
 
 func (i Type) String() string {
 	if i < 0 || i >= Type(len(_Type_index)-1) {
@@ -0,0 +1,64 @@
+package scan
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/sequix/baobab/graph"
+	"golang.org/x/tools/go/packages"
+)
+
+// loadTyped loads every package of the scanned modules with the go tool, in
+// mode, along with their tests if tests, for the graphs needing types.
+func (s *Scanner) loadTyped(ctx context.Context, mode packages.LoadMode, tests bool) ([]*packages.Package, error) {
+	var patterns []string
+	for _, m := range s.modules {
+		patterns = append(patterns, "./"+filepath.ToSlash(m.Dir)+"/...")
+	}
+	env, flags := s.goListEnv()
+	cfg := &packages.Config{
+		Context:    ctx,
+		Mode:       mode,
+		Dir:        s.root,
+		Env:        env,
+		BuildFlags: flags,
+		Tests:      tests,
+	}
+	s.logger.Debug("loading packages", "patterns", patterns)
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages %s: %s", patterns, err)
+	}
+	var loadErr error
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, e := range pkg.Errors {
+			if loadErr == nil {
+				loadErr = s.tolerate(pkg.PkgPath, fmt.Errorf("failed to load package %s: %s", pkg.PkgPath, e))
+			}
+		}
+	})
+	if loadErr != nil {
+		return nil, loadErr
+	}
+	return pkgs, nil
+}
+
+// objectNode adds the node of the object name of the package in dir, of
+// module mod and import path pkgPath, to g, and returns its name: the
+// directory and the name, or the name alone in the root package.
+func (s *Scanner) objectNode(g *graph.Graph, dir string, mod *Module, pkgPath, name string) string {
+	node := name
+	if dir != "." {
+		node = dir + "." + name
+	}
+	if g.Node(node) != nil {
+		return node
+	}
+	n := g.AddNode(node)
+	n.ImportPath = pkgPath + "." + name
+	n.Module = mod.Path
+	n.Group = dir
+	n.Label = name
+	return node
+}
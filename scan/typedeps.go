@@ -0,0 +1,130 @@
+package scan
+
+import (
+	"context"
+	"go/types"
+
+	"github.com/sequix/baobab/graph"
+	"golang.org/x/tools/go/packages"
+)
+
+// TypeGraph returns the graph of the exported types of the scanned modules
+// and the types of theirs they are made of, type checking every package of
+// them. Nodes are types, named like the functions of CallGraph, and an edge
+// goes from a type to another its fields, embedded ones included, the
+// methods of its interface or its exported methods refer to, its Weight the
+// number of those referring to it.
+func (s *Scanner) TypeGraph(ctx context.Context) (*graph.Graph, error) {
+	pkgs, err := s.loadTyped(ctx, packages.NeedName|packages.NeedTypes|packages.NeedDeps|packages.NeedImports, false)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		g     = graph.New()
+		named = map[*types.TypeName]*namedType{}
+		all   = s.namedTypes(pkgs)
+	)
+	for _, t := range all {
+		named[t.obj] = t
+	}
+	node := func(t *namedType) string {
+		if t.node == "" {
+			t.node = s.objectNode(g, t.dir, t.mod, t.obj.Pkg().Path(), t.obj.Name())
+		}
+		return t.node
+	}
+	for _, t := range all {
+		if !t.obj.Exported() {
+			continue
+		}
+		for _, part := range typeParts(t.obj.Type()) {
+			refs := map[*namedType]bool{}
+			walkType(part, func(obj *types.TypeName) {
+				if to := named[obj]; to != nil && to != t && to.obj.Exported() {
+					refs[to] = true
+				}
+			}, map[types.Type]bool{})
+			for to := range refs {
+				g.AddEdge(node(t), node(to)).Weight++
+			}
+		}
+	}
+	return g, nil
+}
+
+// typeParts returns the types of the fields of t, embedded ones included,
+// of the methods of its interface and of its exported methods, or its
+// underlying type if neither a struct nor an interface.
+func typeParts(t types.Type) []types.Type {
+	var parts []types.Type
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			parts = append(parts, u.Field(i).Type())
+		}
+	case *types.Interface:
+		for i := 0; i < u.NumEmbeddeds(); i++ {
+			parts = append(parts, u.EmbeddedType(i))
+		}
+		for i := 0; i < u.NumExplicitMethods(); i++ {
+			parts = append(parts, u.ExplicitMethod(i).Type())
+		}
+	default:
+		parts = append(parts, u)
+	}
+	if n, ok := t.(*types.Named); ok {
+		for i := 0; i < n.NumMethods(); i++ {
+			if m := n.Method(i); m.Exported() {
+				parts = append(parts, m.Type())
+			}
+		}
+	}
+	return parts
+}
+
+// walkType calls refer with the named types t is made of, not looking
+// into them.
+func walkType(t types.Type, refer func(*types.TypeName), seen map[types.Type]bool) {
+	if seen[t] {
+		return
+	}
+	seen[t] = true
+	switch t := t.(type) {
+	case *types.Named:
+		refer(t.Obj())
+		for i := 0; i < t.TypeArgs().Len(); i++ {
+			walkType(t.TypeArgs().At(i), refer, seen)
+		}
+	case *types.Alias:
+		walkType(types.Unalias(t), refer, seen)
+	case *types.Pointer:
+		walkType(t.Elem(), refer, seen)
+	case *types.Slice:
+		walkType(t.Elem(), refer, seen)
+	case *types.Array:
+		walkType(t.Elem(), refer, seen)
+	case *types.Chan:
+		walkType(t.Elem(), refer, seen)
+	case *types.Map:
+		walkType(t.Key(), refer, seen)
+		walkType(t.Elem(), refer, seen)
+	case *types.Signature:
+		walkType(t.Params(), refer, seen)
+		walkType(t.Results(), refer, seen)
+	case *types.Tuple:
+		for i := 0; i < t.Len(); i++ {
+			walkType(t.At(i).Type(), refer, seen)
+		}
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			walkType(t.Field(i).Type(), refer, seen)
+		}
+	case *types.Interface:
+		for i := 0; i < t.NumEmbeddeds(); i++ {
+			walkType(t.EmbeddedType(i), refer, seen)
+		}
+		for i := 0; i < t.NumExplicitMethods(); i++ {
+			walkType(t.ExplicitMethod(i).Type(), refer, seen)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package scan
+
+import (
+	"context"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// UnusedExport is an exported package-level name of a package of the
+// scanned modules no other package of them refers to.
+type UnusedExport struct {
+	Dir  string // package directory
+	Name string
+	Kind string // func, type, var or const
+	File string
+	Line int
+}
+
+// UnusedExports returns the exported package-level names of the packages of
+// the scanned modules, main packages aside, that no other package of them
+// refers to, type checking every package of them, by directory and name.
+// With Options.Tests the references of tests count, those of the external
+// tests of a package to it aside.
+func (s *Scanner) UnusedExports(ctx context.Context) ([]UnusedExport, error) {
+	pkgs, err := s.loadTyped(ctx, packages.NeedName|packages.NeedFiles|packages.NeedTypes|packages.NeedSyntax|packages.NeedTypesInfo, s.opts.Tests)
+	if err != nil {
+		return nil, err
+	}
+	root, err := filepath.Abs(s.root)
+	if err != nil {
+		return nil, err
+	}
+	// Test variants of packages have objects of their own: objects are told
+	// apart by package path and name.
+	key := func(obj types.Object) string { return obj.Pkg().Path() + "." + obj.Name() }
+	used := map[string]bool{}
+	var (
+		result []UnusedExport
+		keys   []string
+	)
+	for _, pkg := range pkgs {
+		if pkg.Types == nil || pkg.TypesInfo == nil {
+			continue
+		}
+		self := strings.TrimSuffix(pkg.PkgPath, "_test")
+		for _, obj := range pkg.TypesInfo.Uses {
+			if obj.Pkg() != nil && obj.Pkg().Path() != self && obj.Parent() == obj.Pkg().Scope() {
+				used[key(obj)] = true
+			}
+		}
+		dir, _, ok := s.resolveImport(pkg.PkgPath)
+		if !ok || s.excluded(dir) || pkg.ID != pkg.PkgPath || pkg.Name == "main" {
+			continue
+		}
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			obj := scope.Lookup(name)
+			if !obj.Exported() {
+				continue
+			}
+			pos := pkg.Fset.Position(obj.Pos())
+			file := pos.Filename
+			if rel, err := filepath.Rel(root, file); err == nil {
+				file = s.path(rel)
+			}
+			result = append(result, UnusedExport{Dir: dir, Name: name, Kind: objectKind(obj), File: file, Line: pos.Line})
+			keys = append(keys, key(obj))
+		}
+	}
+	unused := result[:0]
+	for i, u := range result {
+		if !used[keys[i]] {
+			unused = append(unused, u)
+		}
+	}
+	sort.SliceStable(unused, func(i, j int) bool {
+		if unused[i].Dir != unused[j].Dir {
+			return unused[i].Dir < unused[j].Dir
+		}
+		return unused[i].Name < unused[j].Name
+	})
+	return unused, nil
+}
+
+// objectKind returns func, type, var or const for the kind of obj.
+func objectKind(obj types.Object) string {
+	switch obj.(type) {
+	case *types.Func:
+		return "func"
+	case *types.TypeName:
+		return "type"
+	case *types.Const:
+		return "const"
+	}
+	return "var"
+}
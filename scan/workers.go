@@ -0,0 +1,208 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// parsedFile is a go file of a package being scanned, parsed by a worker.
+type parsedFile struct {
+	dir       string
+	path      string
+	test      bool // a _test.go file
+	skip      bool // left out by build constraints
+	ignored   bool // left out by a .baobabignore file
+	generated bool // has the generated code comment, see SkipGenerated
+	f         *GoFile
+	parseErr  error // the file failed to parse, see Lenient
+	err       error
+}
+
+// why returns why pf was left out of the graph, empty if it was not.
+func (pf *parsedFile) why() string {
+	switch {
+	case pf.ignored:
+		return "ignored by " + ignoreFile
+	case pf.skip:
+		return "build constraints not satisfied"
+	case pf.parseErr != nil:
+		return "failed to parse"
+	case pf.generated:
+		return "generated"
+	}
+	return ""
+}
+
+// parse checks the file is not ignored, matches its build constraints and
+// parses it.
+func (pf *parsedFile) parse(s *Scanner) {
+	if s.ignored(pf.path, false) {
+		s.logger.Debug("skipping file, ignored", "file", pf.path)
+		pf.ignored = true
+		return
+	}
+	ok, err := s.matchFile(pf.dir, filepath.Base(pf.path))
+	if err != nil {
+		pf.err = fmt.Errorf("failed to match build constraints of %s: %s", pf.path, err)
+		return
+	}
+	if !ok {
+		s.logger.Debug("skipping file, build constraints not satisfied", "file", pf.path)
+		pf.skip = true
+		return
+	}
+	s.logger.Debug("parsing file", "file", pf.path)
+	if pf.f, pf.parseErr = s.parse(s.path(pf.path)); pf.parseErr != nil {
+		return
+	}
+	if s.opts.SkipGenerated {
+		pf.generated, pf.err = isGenerated(s.path(pf.path))
+	}
+}
+
+// parseFiles parses files with Workers goroutines.
+func (s *Scanner) parseFiles(files []*parsedFile) {
+	var (
+		wg   sync.WaitGroup
+		jobs = make(chan *parsedFile)
+	)
+	for i := 0; i < s.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pf := range jobs {
+				s.progress.Parsing(pf.path)
+				pf.parse(s)
+				s.progress.Parsed(pf.dir)
+			}
+		}()
+	}
+	for _, pf := range files {
+		jobs <- pf
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// addFiles adds parsed files to the graph, in order, and returns the
+// directories of the packages they import in scanned modules.
+func (s *Scanner) addFiles(files []*parsedFile) ([]string, error) {
+	var next []string
+	if s.opts.RecordFiles {
+		s.files = append(s.files, files...)
+	}
+	for _, pf := range files {
+		switch {
+		case pf.err != nil:
+			return nil, pf.err
+		case pf.skip, pf.ignored:
+			continue
+		case pf.parseErr != nil:
+			if err := s.tolerate(pf.path, pf.parseErr); err != nil {
+				return nil, err
+			}
+			continue
+		case pf.generated:
+			s.skipGenerated(pf.dir, pf.f.Imports)
+			continue
+		}
+		next = append(next, s.addFile(pf.dir, pf.path, pf.test, pf.f)...)
+	}
+	return next, nil
+}
+
+// scanDirs adds the packages in dirs to the graph, then level by level the
+// packages they import, up to Depth. The files of a level are parsed
+// concurrently but only added to the graph, which is not safe for concurrent
+// use, once all are parsed and in directory order, so the graph does not
+// depend on scheduling and every package is reached by its shortest path.
+func (s *Scanner) scanDirs(ctx context.Context, dirs []string) error {
+	for depth := 0; len(dirs) > 0; depth++ {
+		if s.opts.Depth > 0 && depth > s.opts.Depth {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var files []*parsedFile
+		for _, dir := range dirs {
+			dir = filepath.Clean(dir)
+			if _, parsed := s.parsed[dir]; parsed || s.excluded(dir) {
+				continue
+			}
+			s.parsed[dir] = struct{}{}
+			s.logger.Debug("scanning package", "dir", dir, "depth", depth)
+			if _, err := os.Stat(s.path(dir)); os.IsNotExist(err) && s.opts.Lenient {
+				s.warn("missing-dir", dir, "skipping missing directory", nil)
+				continue
+			}
+			fs, tests, err := s.listFiles(dir)
+			if err != nil {
+				return err
+			}
+			s.addPackage(dir)
+			s.graph.Node(dir).TestFiles = tests
+			s.progress.AddDir(dir, len(fs))
+			files = append(files, fs...)
+		}
+		s.parseFiles(files)
+		next, err := s.addFiles(files)
+		if err != nil {
+			return err
+		}
+		dirs = next
+	}
+	return nil
+}
+
+// listFiles returns the go files of dir to scan, and the number of its
+// _test.go files, scanned or not.
+func (s *Scanner) listFiles(dir string) ([]*parsedFile, int, error) {
+	fis, err := ioutil.ReadDir(s.path(dir))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read dir %s: %s", dir, err)
+	}
+	var (
+		result []*parsedFile
+		tests  int
+	)
+	for _, fi := range fis {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), ".go") {
+			continue
+		}
+		test := strings.HasSuffix(fi.Name(), "_test.go")
+		if test {
+			tests++
+		}
+		if test && !s.opts.Tests {
+			continue
+		}
+		result = append(result, &parsedFile{dir: dir, path: filepath.Join(dir, fi.Name()), test: test})
+	}
+	return result, tests, nil
+}
+
+// countLines wraps parse to count the lines of the files it parses too.
+func countLines(parse func(string) (*GoFile, error)) func(string) (*GoFile, error) {
+	return func(file string) (*GoFile, error) {
+		f, err := parse(file)
+		if err != nil {
+			return nil, err
+		}
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file %s: %s", file, err)
+		}
+		f.Lines = bytes.Count(content, []byte("\n"))
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			f.Lines++
+		}
+		return f, nil
+	}
+}
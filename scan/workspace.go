@@ -0,0 +1,280 @@
+package scan
+
+import (
+	"bufio"
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Module is a go module being scanned.
+type Module struct {
+	Path     string   // module path, as in go.mod
+	Dir      string   // root directory, relative to the root of the scan
+	Requires []string // paths of the modules required in go.mod
+}
+
+// FindRoot returns the root of what to scan from dir, as an absolute path:
+// like the go tool, the directory of the closest go.work if workspace, else
+// of the closest go.mod. If there is none, it is the root of the repository
+// dir is in for a GOPATH project, see GOPATHImportPath, or dir itself.
+func FindRoot(dir string, workspace bool) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	root, ok := "", false
+	if workspace {
+		root, ok = FindUp(dir, "go.work")
+	}
+	if !ok {
+		root, ok = FindUp(dir, "go.mod")
+	}
+	if !ok {
+		return gopathRoot(dir), nil
+	}
+	return root, nil
+}
+
+// vcsDirs mark the root of a repository.
+var vcsDirs = []string{".git", ".hg", ".svn", ".bzr"}
+
+// gopathRoot returns the root of the repository dir is in, below a src
+// directory of GOPATH, dir itself if it is not in one.
+func gopathRoot(dir string) string {
+	src, ok := gopathSrc(dir)
+	if !ok {
+		return dir
+	}
+	for d := dir; d != src; d = filepath.Dir(d) {
+		for _, vcs := range vcsDirs {
+			if _, err := os.Stat(filepath.Join(d, vcs)); err == nil {
+				return d
+			}
+		}
+	}
+	return dir
+}
+
+// gopathSrc returns the src directory of GOPATH dir is below, if any.
+func gopathSrc(dir string) (string, bool) {
+	for _, gopath := range filepath.SplitList(build.Default.GOPATH) {
+		src := filepath.Join(gopath, "src")
+		if rel, err := filepath.Rel(src, dir); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			return src, true
+		}
+	}
+	return "", false
+}
+
+// GOPATHImportPath returns the import path of the package in dir, an
+// absolute directory without go.mod, as a GOPATH project: its path below
+// a src directory of GOPATH.
+func GOPATHImportPath(dir string) (string, bool) {
+	src, ok := gopathSrc(dir)
+	if !ok {
+		return "", false
+	}
+	rel, err := filepath.Rel(src, dir)
+	if err != nil {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// FindUp returns the closest directory at or above dir containing file.
+func FindUp(dir, file string) (string, bool) {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, file)); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// resolveEntry returns the directory of an entry, given either as an import
+// path in one of the modules, or a directory relative to base.
+func (s *Scanner) resolveEntry(entry, base string) string {
+	if dir, _, ok := s.resolveImport(entry); ok {
+		return dir
+	}
+	if filepath.IsAbs(entry) {
+		if root, err := filepath.Abs(s.root); err == nil {
+			if rel, err := filepath.Rel(root, entry); err == nil {
+				return rel
+			}
+		}
+		return entry
+	}
+	return filepath.Join(base, entry)
+}
+
+// setupModules finds the modules to scan: all members of the go.work at the
+// root if there is one and gomod is empty, the module in its go.mod
+// otherwise, named gomod if not empty.
+func (s *Scanner) setupModules(gomod string) error {
+	if gomod == "" {
+		dirs, err := parseGoWork(s.path("go.work"))
+		if err == nil {
+			for _, dir := range dirs {
+				m, err := parseGoMod(filepath.Join(s.path(dir), "go.mod"))
+				if err != nil {
+					return err
+				}
+				m.Dir = filepath.Clean(dir)
+				s.modules = append(s.modules, m)
+			}
+			sort.SliceStable(s.modules, func(i, j int) bool {
+				return len(s.modules[i].Path) > len(s.modules[j].Path)
+			})
+			return nil
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+	m, err := parseGoMod(s.path("go.mod"))
+	if os.IsNotExist(err) && gomod == "" {
+		if abs, aerr := filepath.Abs(s.root); aerr == nil {
+			gomod, s.gopath = GOPATHImportPath(abs)
+		}
+	}
+	if os.IsNotExist(err) && gomod == "" {
+		return fmt.Errorf("no go.mod or go.work in %s or above, and no module path given", s.opts.Dir)
+	} else if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if gomod != "" {
+		m.Path = gomod
+	}
+	s.modules = []Module{{m.Path, ".", m.Requires}}
+	return nil
+}
+
+// resolveImport returns the directory and module of an import path, ok is
+// false if the import is not in any scanned module.
+func (s *Scanner) resolveImport(imp string) (dir string, mod *Module, ok bool) {
+	for i := range s.modules {
+		m := &s.modules[i]
+		if imp != m.Path && !strings.HasPrefix(imp, m.Path+"/") {
+			continue
+		}
+		rest := strings.TrimPrefix(strings.TrimPrefix(imp, m.Path), "/")
+		return filepath.Join(m.Dir, filepath.FromSlash(rest)), m, true
+	}
+	return "", nil, false
+}
+
+// moduleOfDir returns the module dir belongs to, nil if none.
+func (s *Scanner) moduleOfDir(dir string) *Module {
+	var (
+		result *Module
+		best   = -1
+	)
+	dir = filepath.Clean(dir)
+	for i := range s.modules {
+		m := &s.modules[i]
+		if m.Dir == "." {
+			if best < 0 {
+				result, best = m, 0
+			}
+			continue
+		}
+		if dir != m.Dir && !strings.HasPrefix(dir, m.Dir+string(os.PathSeparator)) {
+			continue
+		}
+		if len(m.Dir) > best {
+			result, best = m, len(m.Dir)
+		}
+	}
+	return result
+}
+
+// parseGoWork returns the directories listed in use directives of a go.work.
+func parseGoWork(file string) ([]string, error) {
+	var dirs []string
+	err := readDirectives(file, func(verb string, args []string) error {
+		if verb != "use" {
+			return nil
+		}
+		if len(args) != 1 {
+			return fmt.Errorf("usage: use local/dir")
+		}
+		dirs = append(dirs, args[0])
+		return nil
+	})
+	return dirs, err
+}
+
+// parseGoMod returns the module declared in a go.mod, without its Dir.
+func parseGoMod(file string) (Module, error) {
+	var m Module
+	err := readDirectives(file, func(verb string, args []string) error {
+		switch {
+		case verb == "module" && len(args) == 1:
+			m.Path = args[0]
+		case verb == "require" && len(args) >= 1:
+			m.Requires = append(m.Requires, args[0])
+		}
+		return nil
+	})
+	if err != nil {
+		return m, err
+	}
+	if m.Path == "" {
+		return m, fmt.Errorf("no module directive in %s", file)
+	}
+	return m, nil
+}
+
+// readDirectives calls fn with the verb and arguments of every directive in
+// a go.mod or go.work file, expanding blocks like `use ( a b )`.
+func readDirectives(file string, fn func(verb string, args []string) error) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var (
+		block string
+		scan  = bufio.NewScanner(f)
+	)
+	for line := 1; scan.Scan(); line++ {
+		text := scan.Text()
+		if i := strings.Index(text, "//"); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		for i, f := range fields {
+			if strings.HasPrefix(f, `"`) || strings.HasPrefix(f, "`") {
+				if uq, err := strconv.Unquote(f); err == nil {
+					fields[i] = uq
+				}
+			}
+		}
+		switch {
+		case block != "" && fields[0] == ")":
+			block = ""
+		case block != "":
+			err = fn(block, fields)
+		case len(fields) == 2 && fields[1] == "(":
+			block = fields[0]
+		default:
+			err = fn(fields[0], fields[1:])
+		}
+		if err != nil {
+			return fmt.Errorf("%s:%d: %s", file, line, err)
+		}
+	}
+	return scan.Err()
+}
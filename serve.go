@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sequix/baobab/internal/websocket"
+)
+
+var (
+	flagAddr string
+	flagLive bool
+)
+
+// serveFlags defines the flags of the serve command.
+func serveFlags(fs *flag.FlagSet) {
+	fs.StringVar(&flagAddr, "addr", "localhost:8080", "`ADDRESS` to listen on, like :8080 for every interface")
+	fs.BoolVar(&flagLive, "watch", false, "scan again whenever go files change, pushing the changes to the live view")
+}
+
+//go:embed serve.html
+var serveHTML []byte
+
+// server answers the requests of baobab serve about the graph of its last
+// scan.
+type server struct {
+	mu     sync.RWMutex
+	view   *Graph // the graph output, as -filter-node and the like make it
+	health health // of the graph scanned, for /metrics
+	output func(*Graph) *Graph
+	scanMu sync.Mutex // held by the running scan
+
+	clientsMu sync.Mutex
+	clients   map[*websocket.Conn]chan []byte // of the live view, sent the changes of each scan
+}
+
+func runServe(fs *flag.FlagSet, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments %q", args)
+	}
+	if flagRepo != "" {
+		return fmt.Errorf("cannot serve a -repo")
+	}
+	output, err := outputGraph()
+	if err != nil {
+		return err
+	}
+	if err := loadTheme(); err != nil {
+		return err
+	}
+	if err := loadImportRules(); err != nil {
+		return err
+	}
+	start := time.Now()
+	if err := scanGraph(fs, ""); err != nil {
+		return err
+	}
+	s := &server{view: output(graph), health: newHealth(graph, time.Since(start)), output: output}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handleUI)
+	mux.HandleFunc("GET /live", s.handleLiveUI)
+	mux.HandleFunc("GET /api/graph", s.handleGraph)
+	mux.HandleFunc("GET /api/live", s.handleLive)
+	mux.HandleFunc("GET /api/nodes", s.handleNodes)
+	mux.HandleFunc("GET /api/nodes/{name...}", s.handleNode)
+	mux.HandleFunc("GET /api/edges", s.handleEdges)
+	mux.HandleFunc("GET /api/query", s.handleQuery)
+	mux.HandleFunc("POST /api/scan", s.handleScan)
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+	mux.HandleFunc("GET /graphql", s.handleGraphQL)
+	mux.HandleFunc("POST /graphql", s.handleGraphQL)
+	srv := &http.Server{Addr: flagAddr, Handler: mux}
+	if flagLive {
+		go s.watch(context.Background())
+	}
+	logger.Info("serving", "addr", flagAddr)
+	return srv.ListenAndServe()
+}
+
+// rescan scans again, then replaces the graph served. Requests keep being
+// answered from the previous graph meanwhile.
+func (s *server) rescan(ctx context.Context) error {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+	start := time.Now()
+	g, err := scanner.Scan(ctx)
+	if err != nil {
+		return err
+	}
+	if err := annotate(g); err != nil {
+		return err
+	}
+	view, h := s.output(g), newHealth(g, time.Since(start))
+	s.mu.Lock()
+	old := s.view
+	graph, s.view, s.health = g, view, h
+	s.mu.Unlock()
+	s.broadcast(graphDelta(old, view))
+	return nil
+}
+
+func (s *server) handleUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(themeHTML(serveHTML))
+}
+
+func (s *server) handleGraph(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, s.view)
+}
+
+func (s *server) handleNodes(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	nodes := []jsonNode{}
+	for _, name := range s.view.Nodes() {
+		nodes = append(nodes, newJSONNode(s.view.Node(name)))
+	}
+	serveJSON(w, nodes)
+}
+
+func (s *server) handleNode(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	name := r.PathValue("name")
+	n := s.view.Node(name)
+	if n == nil {
+		http.Error(w, fmt.Sprintf("no package %s in the graph", name), http.StatusNotFound)
+		return
+	}
+	serveJSON(w, struct {
+		jsonNode
+		Imports   []string `json:"imports"`
+		Importers []string `json:"importers"`
+	}{newJSONNode(n), s.view.Succ(name), s.view.Pred(name)})
+}
+
+func (s *server) handleEdges(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	edges := []jsonEdge{}
+	for _, e := range s.view.Edges() {
+		edges = append(edges, newJSONEdge(s.view, e))
+	}
+	serveJSON(w, edges)
+}
+
+// handleQuery answers a query of baobab repl, given as q, in plain text,
+// colored if color is 1.
+func (s *server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	fields := strings.Fields(r.URL.Query().Get("q"))
+	if len(fields) == 0 {
+		http.Error(w, "missing query q, see help", http.StatusBadRequest)
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out bytes.Buffer
+	if err := query(&out, painter(r.URL.Query().Get("color") == "1"), fields[0], fields[1:]); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(out.Bytes())
+}
+
+func (s *server) handleScan(w http.ResponseWriter, r *http.Request) {
+	if err := s.rescan(r.Context()); err != nil {
+		warn("serve", "", "failed to scan", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	serveJSON(w, struct {
+		Packages int       `json:"packages"`
+		Edges    int       `json:"edges"`
+		Scanned  time.Time `json:"scanned"`
+	}{len(s.view.Nodes()), len(s.view.Edges()), s.health.scanned})
+}
+
+// handleMetrics serves gauges of the health of the graph to Prometheus.
+func (s *server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetrics(w, s.health)
+}
+
+// serveJSON writes v as the JSON response.
+func serveJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		logger.Error("failed to write response", "err", err)
+	}
+}
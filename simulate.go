@@ -0,0 +1,172 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	graphs "github.com/sequix/baobab/graph"
+)
+
+var flagRemove listFlag
+
+// simulateFlags defines the flags of the simulate command.
+func simulateFlags(fs *flag.FlagSet) {
+	fs.Var(&flagRemove, "remove", "`PACKAGE` to simulate deleting, by directory or import path, repeatable")
+}
+
+// removal is what deleting packages breaks.
+type removal struct {
+	removed  []string
+	importer map[string][]string // removed packages each package imports
+	broken   [][]string          // shortest chain of imports from each package left importing a removed one
+	before   int                 // parts of the graph of the scanned modules
+	after    int                 // parts of it left after
+	split    [][]string          // new parts after, the largest aside
+}
+
+func runSimulate(fs *flag.FlagSet, args []string) error {
+	source, err := sourceArg(args)
+	if err != nil {
+		return err
+	}
+	if len(flagRemove) == 0 {
+		return fmt.Errorf("want a package to -remove")
+	}
+	p, err := newPainter()
+	if err != nil {
+		return err
+	}
+	if err := scanGraph(fs, source); err != nil {
+		return err
+	}
+	var removed []string
+	for _, arg := range flagRemove {
+		name := graphNode(arg)
+		if name == "" {
+			return fmt.Errorf("no package %s in the graph", arg)
+		}
+		removed = append(removed, name)
+	}
+	r := simulateRemoval(graph, removed)
+	if err := writeOutput(func(w io.Writer) error { return printRemoval(w, p, r) }); err != nil {
+		return err
+	}
+	if len(r.broken) > 0 {
+		return errViolations
+	}
+	return nil
+}
+
+// simulateRemoval returns what deleting the removed packages from g breaks.
+func simulateRemoval(g *Graph, removed []string) removal {
+	r := removal{removed: removed, importer: map[string][]string{}}
+	gone := map[string]bool{}
+	for _, name := range removed {
+		gone[name] = true
+	}
+	for _, name := range removed {
+		for _, from := range g.Pred(name) {
+			if !gone[from] {
+				r.importer[from] = append(r.importer[from], name)
+			}
+		}
+	}
+	// Every package reaching a removed one breaks, along its shortest chain.
+	for _, name := range g.Nodes() {
+		if gone[name] {
+			continue
+		}
+		var best []string
+		for _, to := range removed {
+			if path := graphs.ShortestPath(g, name, to); path != nil && (best == nil || len(path) < len(best)) {
+				best = path
+			}
+		}
+		if best != nil {
+			r.broken = append(r.broken, best)
+		}
+	}
+	sort.SliceStable(r.broken, func(i, j int) bool { return len(r.broken[i]) < len(r.broken[j]) })
+
+	internal := func(n *Node) bool { return !n.External && !n.Std && !n.Marker && !n.Proto }
+	before := weakComponents(g.Filter(internal, func(*Edge) bool { return true }))
+	after := weakComponents(g.Filter(func(n *Node) bool { return internal(n) && !gone[n.Name] }, func(*Edge) bool { return true }))
+	r.before, r.after = len(before), len(after)
+	if r.after > r.before {
+		known := map[string]bool{}
+		for _, part := range before {
+			known[strings.Join(part, "\x00")] = true
+		}
+		sort.SliceStable(after, func(i, j int) bool { return len(after[i]) > len(after[j]) })
+		for _, part := range after[1:] {
+			if !known[strings.Join(part, "\x00")] {
+				r.split = append(r.split, part)
+			}
+		}
+	}
+	return r
+}
+
+// weakComponents returns the sets of packages of g connected by imports,
+// whichever way they go, each sorted.
+func weakComponents(g *Graph) [][]string {
+	var result [][]string
+	seen := map[string]bool{}
+	for _, name := range g.Nodes() {
+		if seen[name] {
+			continue
+		}
+		var part []string
+		seen[name] = true
+		queue := []string{name}
+		for len(queue) > 0 {
+			n := queue[0]
+			queue = queue[1:]
+			part = append(part, n)
+			for _, next := range append(g.Succ(n), g.Pred(n)...) {
+				if !seen[next] {
+					seen[next] = true
+					queue = append(queue, next)
+				}
+			}
+		}
+		sort.Strings(part)
+		result = append(result, part)
+	}
+	return result
+}
+
+// printRemoval writes the packages losing an import, those no longer
+// building, each with its chain of imports, and the parts the graph falls
+// into.
+func printRemoval(w io.Writer, p painter, r removal) error {
+	fmt.Fprintf(w, "removing %s\n", p.paint(strings.Join(r.removed, ", "), bold))
+	importers := make([]string, 0, len(r.importer))
+	for name := range r.importer {
+		importers = append(importers, name)
+	}
+	sort.Strings(importers)
+	fmt.Fprintf(w, "\n%d packages lose an import:\n", len(importers))
+	for _, name := range importers {
+		for _, to := range r.importer[name] {
+			file, _ := importPos(graph, graph.Edge(name, to))
+			fmt.Fprintf(w, "  %s -> %s %s\n", p.paint(name, red), to, p.paint(file, dim))
+		}
+	}
+	fmt.Fprintf(w, "\n%d packages no longer build:\n", len(r.broken))
+	for _, path := range r.broken {
+		fmt.Fprintf(w, "  %s\n", p.paint(strings.Join(path, " -> "), red))
+	}
+	if len(r.split) == 0 {
+		_, err := fmt.Fprintf(w, "\nthe graph stays in %d parts\n", r.after)
+		return err
+	}
+	fmt.Fprintf(w, "\nthe graph splits from %d into %d parts, cut off:\n", r.before, r.after)
+	for _, part := range r.split {
+		fmt.Fprintf(w, "  %s\n", p.paint(strings.Join(part, ", "), yellow))
+	}
+	return nil
+}
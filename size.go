@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+var flagSizeBy string
+
+// sizeMetrics are the metrics of -size-by, by name.
+var sizeMetrics = map[string]func(g *Graph, n *Node) float64{
+	"loc":   func(g *Graph, n *Node) float64 { return float64(n.Lines) },
+	"files": func(g *Graph, n *Node) float64 { return float64(n.Files) },
+	"churn": func(g *Graph, n *Node) float64 { return float64(n.Commits) },
+	"fanin": func(g *Graph, n *Node) float64 { return float64(len(g.Pred(n.Name))) },
+	"coverage": func(g *Graph, n *Node) float64 {
+		c, _ := coverage(n)
+		return c
+	},
+}
+
+// checkSizeBy checks -size-by names a metric, and that the flag measuring
+// it is given.
+func checkSizeBy() error {
+	switch {
+	case flagSizeBy == "":
+	case sizeMetrics[flagSizeBy] == nil:
+		return fmt.Errorf("unknown -size-by %q, want loc, files, churn, fanin or coverage", flagSizeBy)
+	case flagSizeBy == "churn" && flagChurn == "":
+		return fmt.Errorf("-size-by churn takes -churn")
+	case flagSizeBy == "coverage" && flagCoverage == "":
+		return fmt.Errorf("-size-by coverage takes -coverage")
+	}
+	return nil
+}
+
+// nodeSizes returns the DOT attributes sizing the nodes of g per -size-by,
+// their area growing with the metric, up to four times the default width
+// for the largest.
+func nodeSizes(g *Graph) map[string]string {
+	metric := sizeMetrics[flagSizeBy]
+	if metric == nil {
+		return nil
+	}
+	var (
+		values = map[string]float64{}
+		top    float64
+	)
+	for _, name := range g.Nodes() {
+		values[name] = metric(g, g.Node(name))
+		top = math.Max(top, values[name])
+	}
+	if top == 0 {
+		return nil
+	}
+	result := map[string]string{}
+	for name, v := range values {
+		scale := 1 + 3*math.Sqrt(v/top)
+		result[name] = fmt.Sprintf("width=%.2f, height=%.2f, fontsize=%.0f", 0.75*scale, 0.5*scale, 14*math.Sqrt(scale))
+	}
+	return result
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var flagMinGroupLines int
+
+// splitFlags defines the flags of the split command.
+func splitFlags(fs *flag.FlagSet) {
+	fs.IntVar(&flagMinGroupLines, "min-lines", 50, "only suggest splits into groups of files of at least `N` lines each")
+}
+
+// fileRefs is what a go file declares at package level and refers to.
+type fileRefs struct {
+	name    string
+	lines   int
+	decls   map[string]bool // package-level names, methods as Type.Method
+	uses    map[string]bool // identifiers used, selectors aside
+	imports map[string]bool
+}
+
+// fileGroup is a set of files of a package referring to each other but to
+// no other file of it.
+type fileGroup struct {
+	files   []string
+	lines   int
+	imports []string // imports no other group of the package has
+}
+
+func runSplit(fs *flag.FlagSet, args []string) error {
+	source, err := sourceArg(args)
+	if err != nil {
+		return err
+	}
+	p, err := newPainter()
+	if err != nil {
+		return err
+	}
+	if flagGoList != "" {
+		return fmt.Errorf("cannot read the files of go list output")
+	}
+	recordFiles = true
+	cleanup, err := prepare(fs, source)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if err := scanAll(); err != nil {
+		return err
+	}
+	files, err := readFileRefs()
+	if err != nil {
+		return err
+	}
+	dirs := make([]string, 0, len(files))
+	for dir := range files {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	splits := map[string][]fileGroup{}
+	for _, dir := range dirs {
+		if groups := fileGroups(files[dir]); splittable(groups) {
+			splits[dir] = groups
+		}
+	}
+	return writeOutput(func(w io.Writer) error { return printSplits(w, p, dirs, splits) })
+}
+
+// readFileRefs parses the files of the packages scanned, tests and
+// generated files aside, by package directory.
+func readFileRefs() (map[string][]*fileRefs, error) {
+	result := map[string][]*fileRefs{}
+	fset := token.NewFileSet()
+	for _, file := range scanner.Files() {
+		if file.Skipped != "" || strings.HasSuffix(file.Path, "_test.go") || graph.Node(file.Dir) == nil {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file.Path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %s", file.Path, err)
+		}
+		refs := &fileRefs{
+			name:    filepath.Base(file.Path),
+			lines:   fset.File(f.Pos()).LineCount(),
+			decls:   map[string]bool{},
+			uses:    map[string]bool{},
+			imports: map[string]bool{},
+		}
+		for _, imp := range f.Imports {
+			if path, err := strconv.Unquote(imp.Path.Value); err == nil {
+				refs.imports[path] = true
+			}
+		}
+		declared := map[*ast.Ident]bool{}
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				declared[decl.Name] = true
+				if decl.Recv == nil {
+					refs.decls[decl.Name.Name] = true
+				} else if recv := receiverType(decl.Recv.List[0].Type); recv != "" {
+					// A method belongs with its type.
+					refs.decls[recv+"."+decl.Name.Name] = true
+					refs.uses[recv] = true
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						declared[spec.Name] = true
+						refs.decls[spec.Name.Name] = true
+					case *ast.ValueSpec:
+						for _, name := range spec.Names {
+							declared[name] = true
+							refs.decls[name.Name] = true
+						}
+					}
+				}
+			}
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.SelectorExpr:
+				// Fields and methods are not package-level names.
+				ast.Inspect(n.X, func(n ast.Node) bool {
+					if id, ok := n.(*ast.Ident); ok && !declared[id] {
+						refs.uses[id.Name] = true
+					}
+					return true
+				})
+				return false
+			case *ast.Ident:
+				if !declared[n] {
+					refs.uses[n.Name] = true
+				}
+			}
+			return true
+		})
+		result[file.Dir] = append(result[file.Dir], refs)
+	}
+	return result, nil
+}
+
+// receiverType returns the name of the type of a method receiver.
+func receiverType(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return receiverType(expr.X)
+	case *ast.IndexExpr:
+		return receiverType(expr.X)
+	case *ast.IndexListExpr:
+		return receiverType(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	}
+	return ""
+}
+
+// fileGroups returns the groups of files of a package referring to each
+// other, the largest first. Files declaring nothing, like doc.go, are left
+// out.
+func fileGroups(files []*fileRefs) []fileGroup {
+	owner := map[string]int{} // declaring file of each name
+	for i, f := range files {
+		for name := range f.decls {
+			owner[name] = i
+		}
+	}
+	parent := make([]int, len(files))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i, f := range files {
+		for name := range f.uses {
+			if j, ok := owner[name]; ok {
+				parent[find(i)] = find(j)
+			}
+		}
+	}
+	byRoot := map[int][]*fileRefs{}
+	for i, f := range files {
+		if len(f.decls) > 0 {
+			byRoot[find(i)] = append(byRoot[find(i)], f)
+		}
+	}
+	var groups []fileGroup
+	var imports []map[string]bool
+	for _, members := range byRoot {
+		var g fileGroup
+		uses := map[string]bool{}
+		for _, f := range members {
+			g.files = append(g.files, f.name)
+			g.lines += f.lines
+			for path := range f.imports {
+				uses[path] = true
+			}
+		}
+		sort.Strings(g.files)
+		groups = append(groups, g)
+		imports = append(imports, uses)
+	}
+	for i := range groups {
+		for path := range imports[i] {
+			shared := false
+			for j := range groups {
+				shared = shared || j != i && imports[j][path]
+			}
+			if !shared {
+				groups[i].imports = append(groups[i].imports, path)
+			}
+		}
+		sort.Strings(groups[i].imports)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].lines != groups[j].lines {
+			return groups[i].lines > groups[j].lines
+		}
+		return groups[i].files[0] < groups[j].files[0]
+	})
+	return groups
+}
+
+// splittable reports whether groups are worth splitting a package along:
+// several, of -min-lines lines each, with imports of their own.
+func splittable(groups []fileGroup) bool {
+	if len(groups) < 2 {
+		return false
+	}
+	distinct := 0
+	for _, g := range groups {
+		if g.lines < flagMinGroupLines {
+			return false
+		}
+		if len(g.imports) > 0 {
+			distinct++
+		}
+	}
+	return distinct > 0
+}
+
+// printSplits writes the packages to split and the groups of files to split
+// them into, with the imports only each group has.
+func printSplits(w io.Writer, p painter, dirs []string, splits map[string][]fileGroup) error {
+	for _, dir := range dirs {
+		groups := splits[dir]
+		if groups == nil {
+			continue
+		}
+		fmt.Fprintf(w, "%s: %d groups of files referring to no other\n", p.paint(dir, cyan), len(groups))
+		for i, g := range groups {
+			fmt.Fprintf(w, "  %d. %s %s\n", i+1, strings.Join(g.files, " "), p.paint(fmt.Sprintf("(%d lines)", g.lines), dim))
+			if len(g.imports) > 0 {
+				fmt.Fprintf(w, "     only imports %s\n", strings.Join(g.imports, ", "))
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d packages to split\n", len(splits))
+	return err
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"io"
+	"time"
+
+	"github.com/sequix/baobab/internal/sqlite"
+)
+
+// sqliteSchema is the schema of the database -format sqlite writes, as the
+// README documents it.
+var sqliteSchema = []struct{ table, sql string }{
+	{"meta", `CREATE TABLE meta (
+  id INTEGER PRIMARY KEY,
+  key TEXT NOT NULL,
+  value TEXT
+)`},
+	{"packages", `CREATE TABLE packages (
+  id INTEGER PRIMARY KEY,
+  name TEXT NOT NULL,
+  import_path TEXT,
+  module TEXT,
+  external INTEGER NOT NULL,
+  std INTEGER NOT NULL,
+  cgo INTEGER NOT NULL,
+  marker INTEGER NOT NULL,
+  proto INTEGER NOT NULL,
+  canonical TEXT,
+  label TEXT,
+  packages INTEGER,
+  group_name TEXT,
+  files INTEGER NOT NULL,
+  test_files INTEGER NOT NULL,
+  lines INTEGER NOT NULL,
+  imports INTEGER NOT NULL,
+  importers INTEGER NOT NULL,
+  page_rank REAL NOT NULL,
+  betweenness REAL NOT NULL
+)`},
+	{"imports", `CREATE TABLE imports (
+  id INTEGER PRIMARY KEY,
+  importer TEXT NOT NULL,
+  imported TEXT NOT NULL,
+  kind TEXT NOT NULL,
+  weight INTEGER
+)`},
+	{"evidence", `CREATE TABLE evidence (
+  id INTEGER PRIMARY KEY,
+  package TEXT NOT NULL,
+  file TEXT NOT NULL,
+  line INTEGER,
+  path TEXT NOT NULL,
+  alias TEXT,
+  test INTEGER NOT NULL,
+  imported TEXT
+)`},
+}
+
+// writeSQLite writes g as a SQLite database: its packages with their sizes
+// and rank scores, its imports and the import declarations making them.
+func writeSQLite(w io.Writer, g *Graph) error {
+	info := readBuildInfo()
+	rows := map[string][][]any{
+		"meta": {
+			{"generator", "baobab " + info.Version},
+			{"revision", null(info.Revision)},
+			{"go", info.Go},
+			{"created", time.Now().UTC().Format(time.RFC3339)},
+		},
+	}
+	pr, bc := PageRank(g), Betweenness(g)
+	for _, name := range g.Nodes() {
+		n := g.Node(name)
+		var packages any
+		if n.Packages > 0 {
+			packages = n.Packages
+		}
+		rows["packages"] = append(rows["packages"], []any{
+			name, null(n.ImportPath), null(n.Module), n.External, n.Std, n.Cgo, n.Marker, n.Proto,
+			null(n.Canonical), null(n.Label), packages, null(n.Group), n.Files, n.TestFiles, n.Lines,
+			len(g.Succ(name)), len(g.Pred(name)), pr[name], bc[name],
+		})
+		for _, imp := range n.Imports {
+			var line any
+			if imp.Line > 0 {
+				line = imp.Line
+			}
+			var imported any
+			switch {
+			case imp.Dir != "" && g.Edge(name, imp.Dir) != nil:
+				imported = imp.Dir
+			case imp.Node != "" && g.Edge(name, imp.Node) != nil:
+				imported = imp.Node
+			}
+			rows["evidence"] = append(rows["evidence"], []any{
+				name, imp.File, line, imp.Path, null(imp.Name), imp.Test, imported,
+			})
+		}
+	}
+	for _, e := range g.Edges() {
+		var weight any
+		if e.Weight > 0 {
+			weight = e.Weight
+		}
+		rows["imports"] = append(rows["imports"], []any{e.From, e.To, e.Kind(), weight})
+	}
+	db := sqlite.New()
+	for _, t := range sqliteSchema {
+		if err := db.AddTable(t.table, t.sql, rows[t.table]); err != nil {
+			return err
+		}
+	}
+	_, err := db.WriteTo(w)
+	return err
+}
+
+// null returns s, or nil for NULL if empty.
+func null(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// stagedDirs returns the absolute directories of the go files staged in the
+// git repository of the current directory, added, copied, modified or
+// renamed, sorted.
+func stagedDirs() ([]string, error) {
+	top, err := git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	files, err := git("-C", top, "diff", "--cached", "--name-only", "--diff-filter=ACMR", "--", "*.go")
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var dirs []string
+	for _, file := range strings.Split(files, "\n") {
+		if file == "" {
+			continue
+		}
+		dir := filepath.Join(top, filepath.Dir(filepath.FromSlash(file)))
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// git runs git with args and returns its output, trimmed.
+func git(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	logger.Debug("running git", "cmd", cmd.Args)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to run %s: %s: %s", strings.Join(cmd.Args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// involving returns the violations involving one of the packages in dirs.
+func involving(violations []Violation, dirs []string) []Violation {
+	affected := map[string]bool{}
+	for _, dir := range dirs {
+		affected[scanner.Resolve(dir)] = true
+	}
+	var result []Violation
+	for _, v := range violations {
+		for _, pkg := range v.Packages {
+			if affected[pkg] {
+				result = append(result, v)
+				break
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+var flagTheme string
+
+// themeFlags defines the flag picking the colors of the drawings.
+func themeFlags(fs *flag.FlagSet) {
+	fs.StringVar(&flagTheme, "theme", "", "colors of DOT and HTML output: light, dark, or a YAML `FILE` of colors by role, starting from the theme its base names")
+}
+
+// theme is the colors of the drawings, by role, in any form both graphviz
+// and CSS take, like #1e1e1e.
+type theme struct {
+	background  string
+	foreground  string // text, and lines of nodes
+	muted       string // secondary text, standard and external packages
+	border      string // of panels
+	node        string // fill
+	edge        string
+	accent      string // highlights and selections
+	good        string // added
+	bad         string // removed, vulnerable or wrong
+	warn        string // changed
+	crossModule string // imports of other modules of the workspace
+	proto       string
+	iface       string
+	marker      string // fill of cgo and other marker nodes
+
+	// The colors and graphviz styles of imports by kind, empty to leave
+	// them be, that of external ones muted. The edge of an external import
+	// takes both its kind's and the external ones.
+	normalEdge, testEdge, blankEdge, dotEdge, externalEdge      string
+	normalStyle, testStyle, blankStyle, dotStyle, externalStyle string
+}
+
+// themes are the presets of -theme.
+var themes = map[string]theme{
+	"light": {
+		background: "#ffffff", foreground: "#222222", muted: "#888888", border: "#cccccc",
+		node: "#f4f4ff", edge: "#999999", accent: "#4682b4",
+		good: "#2ca02c", bad: "#d62728", warn: "#ff7f0e",
+		crossModule: "#1f77b4", proto: "#2e8b57", iface: "#9467bd", marker: "#fff3b0",
+		testStyle: "dashed",
+	},
+	"dark": {
+		background: "#1e1e1e", foreground: "#d4d4d4", muted: "#808080", border: "#3c3c3c",
+		node: "#2d2d3a", edge: "#6a6a6a", accent: "#569cd6",
+		good: "#6a9955", bad: "#f44747", warn: "#ce9178",
+		crossModule: "#4fc1ff", proto: "#4ec9b0", iface: "#c586c0", marker: "#4d4420",
+		testStyle: "dashed",
+	},
+}
+
+// defaultTheme is the colors of DOT output without -theme, leaving the
+// background, text, nodes and edges to graphviz.
+var defaultTheme = theme{
+	muted: "gray", good: "green3", bad: "red", warn: "orange",
+	crossModule: "blue", proto: "darkgreen", iface: "purple", marker: "lightyellow",
+	testStyle: "dashed",
+}
+
+// palette is the theme of -theme, read by loadTheme, and whether one was
+// given.
+var (
+	palette = defaultTheme
+	themed  bool
+)
+
+// loadTheme reads the theme of -theme, a preset or a file.
+func loadTheme() error {
+	palette, themed = defaultTheme, flagTheme != ""
+	if !themed {
+		return nil
+	}
+	if t, ok := themes[flagTheme]; ok {
+		palette = t
+		return nil
+	}
+	values, err := parseConfig(flagTheme)
+	if err != nil {
+		return fmt.Errorf("failed to read theme: %s", err)
+	}
+	palette = themes["light"]
+	for _, v := range values {
+		if v.list || len(v.values) != 1 {
+			return fmt.Errorf("%s:%d: want a color for %s", flagTheme, v.line, v.key)
+		}
+		value := v.values[0]
+		if style := palette.edgeStyle(v.key); style != nil {
+			if !edgeStyles[value] {
+				return fmt.Errorf("%s:%d: unknown edge style %q, want solid, dashed, dotted or bold", flagTheme, v.line, value)
+			}
+			*style = value
+			continue
+		}
+		if v.key == "base" {
+			t, ok := themes[value]
+			if !ok {
+				return fmt.Errorf("%s:%d: unknown base theme %q, want light or dark", flagTheme, v.line, value)
+			}
+			palette = t
+			continue
+		}
+		field := palette.role(v.key)
+		if field == nil {
+			return fmt.Errorf("%s:%d: unknown color role %q", flagTheme, v.line, v.key)
+		}
+		*field = value
+	}
+	return nil
+}
+
+// role returns the color of t for the role name of theme files, nil if
+// none.
+func (t *theme) role(name string) *string {
+	return map[string]*string{
+		"background":    &t.background,
+		"foreground":    &t.foreground,
+		"muted":         &t.muted,
+		"border":        &t.border,
+		"node":          &t.node,
+		"edge":          &t.edge,
+		"accent":        &t.accent,
+		"good":          &t.good,
+		"bad":           &t.bad,
+		"warn":          &t.warn,
+		"cross-module":  &t.crossModule,
+		"proto":         &t.proto,
+		"interface":     &t.iface,
+		"marker":        &t.marker,
+		"normal-edge":   &t.normalEdge,
+		"test-edge":     &t.testEdge,
+		"blank-edge":    &t.blankEdge,
+		"dot-edge":      &t.dotEdge,
+		"external-edge": &t.externalEdge,
+	}[name]
+}
+
+// edgeStyles are the graphviz styles theme files may give edges.
+var edgeStyles = map[string]bool{"solid": true, "dashed": true, "dotted": true, "bold": true}
+
+// edgeStyle returns the style of t for the key of theme files, like
+// test-edge-style, nil if none.
+func (t *theme) edgeStyle(name string) *string {
+	return map[string]*string{
+		"normal-edge-style":   &t.normalStyle,
+		"test-edge-style":     &t.testStyle,
+		"blank-edge-style":    &t.blankStyle,
+		"dot-edge-style":      &t.dotStyle,
+		"external-edge-style": &t.externalStyle,
+	}[name]
+}
+
+// edgeKind returns the color and style of t for imports of kind, normal,
+// test, blank, dot or external.
+func (t *theme) edgeKind(kind string) (color, style string) {
+	switch kind {
+	case "test":
+		return t.testEdge, t.testStyle
+	case "blank":
+		return t.blankEdge, t.blankStyle
+	case "dot":
+		return t.dotEdge, t.dotStyle
+	case "external":
+		if t.externalEdge == "" {
+			return t.muted, t.externalStyle
+		}
+		return t.externalEdge, t.externalStyle
+	}
+	return t.normalEdge, t.normalStyle
+}
+
+// dotThemeAttrs returns the graphviz statements setting the background and
+// the default colors of nodes and edges per -theme, empty without.
+func dotThemeAttrs() string {
+	if !themed {
+		return ""
+	}
+	t := palette
+	return fmt.Sprintf("graph [bgcolor=%q, fontcolor=%q]\nnode [color=%q, fontcolor=%q, style=filled, fillcolor=%q]\nedge [color=%q, fontcolor=%q]\n",
+		t.background, t.foreground, t.foreground, t.foreground, t.node, t.edge, t.foreground)
+}
+
+// themeHTML returns page with the CSS variables of its colors set per
+// -theme, the page as is without.
+func themeHTML(page []byte) []byte {
+	if !themed {
+		return page
+	}
+	var css strings.Builder
+	css.WriteString("<style>:root {")
+	for _, name := range []string{"background", "foreground", "muted", "border", "node", "edge", "accent", "good", "bad", "warn"} {
+		fmt.Fprintf(&css, " --%s: %s;", name, *palette.role(name))
+	}
+	css.WriteString(" }</style>\n</head>")
+	return bytes.Replace(page, []byte("</head>"), []byte(css.String()), 1)
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"io"
+)
+
+//go:embed treemap.html
+var treemapHTML []byte
+
+// writeTreemap writes g as a standalone HTML page drawing the packages as
+// the rectangles of a treemap, nested by directory, each as large as its
+// lines of go code and as dark as the number of packages importing it.
+func writeTreemap(w io.Writer, g *Graph) error {
+	doc, err := json.Marshal(newJSONGraph(g))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(themeHTML(bytes.Replace(treemapHTML, []byte("/*GRAPH*/null"), doc, 1)))
+	return err
+}
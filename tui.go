@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// explorer is the state of baobab tui: a list of packages, all of them or
+// the imports or importers of the focused one, with a cursor.
+type explorer struct {
+	g         *Graph
+	p         painter
+	focus     string   // package whose imports or importers are listed, empty for all
+	importers bool     // list the importers of focus rather than its imports
+	history   []string // packages focused before, to go back to
+	search    string   // only list packages containing it
+	typing    bool     // search being typed
+	list      []string
+	cursor    int
+	offset    int // index of the first row shown
+	rows      int // height of the terminal
+	cols      int // width of the terminal
+}
+
+func runTUI(fs *flag.FlagSet, args []string) error {
+	source, err := sourceArg(args)
+	if err != nil {
+		return err
+	}
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return fmt.Errorf("tui needs a terminal")
+	}
+	if err := scanGraph(fs, source); err != nil {
+		return err
+	}
+	restore, err := rawTerminal()
+	if err != nil {
+		return err
+	}
+	defer restore()
+	e := &explorer{g: graph, p: painter(os.Getenv("NO_COLOR") == "")}
+	e.update()
+	return e.run()
+}
+
+// rawTerminal puts the terminal in raw mode, for keys to be read as typed,
+// and returns the function restoring it.
+func rawTerminal() (func(), error) {
+	saved, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set terminal mode: %s", err)
+	}
+	// Use the alternate screen, without cursor, like full screen programs.
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	return func() {
+		fmt.Print("\x1b[?25h\x1b[?1049l")
+		term.Restore(int(os.Stdin.Fd()), saved)
+	}, nil
+}
+
+// terminalSize returns the number of rows and columns of the terminal, 24
+// by 80 if unknown.
+func terminalSize() (rows, cols int) {
+	cols, rows, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || rows < 4 || cols < 20 {
+		return 24, 80
+	}
+	return rows, cols
+}
+
+// run draws the explorer and handles keys until asked to quit, drawing
+// again when the terminal is resized.
+func (e *explorer) run() error {
+	resized := make(chan os.Signal, 1)
+	if len(resizeSignals) > 0 {
+		signal.Notify(resized, resizeSignals...)
+		defer signal.Stop(resized)
+	}
+	type keyOrErr struct {
+		key string
+		err error
+	}
+	keys := make(chan keyOrErr)
+	go func() {
+		in := bufio.NewReader(os.Stdin)
+		for {
+			key, err := readKey(in)
+			keys <- keyOrErr{key, err}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	e.rows, e.cols = terminalSize()
+	for {
+		e.draw()
+		select {
+		case <-resized:
+			e.rows, e.cols = terminalSize()
+		case k := <-keys:
+			if k.err != nil {
+				return k.err
+			}
+			if !e.handle(k.key) {
+				return nil
+			}
+		}
+	}
+}
+
+// readKey reads a key press: a character, or a name like "up" for keys
+// sending escape sequences and control characters.
+func readKey(in *bufio.Reader) (string, error) {
+	r, _, err := in.ReadRune()
+	if err != nil {
+		return "", err
+	}
+	switch r {
+	case '\r', '\n':
+		return "enter", nil
+	case '\t':
+		return "tab", nil
+	case 127, 8:
+		return "backspace", nil
+	case 3:
+		return "ctrl-c", nil
+	case 27:
+	default:
+		return string(r), nil
+	}
+	if in.Buffered() == 0 {
+		return "esc", nil
+	}
+	seq := ""
+	for in.Buffered() > 0 {
+		b, _ := in.ReadByte()
+		seq += string(b)
+		if b >= 'A' && b <= 'Z' || b == '~' {
+			break
+		}
+	}
+	switch seq {
+	case "[A", "OA":
+		return "up", nil
+	case "[B", "OB":
+		return "down", nil
+	case "[C", "OC":
+		return "right", nil
+	case "[D", "OD":
+		return "left", nil
+	case "[5~":
+		return "pgup", nil
+	case "[6~":
+		return "pgdn", nil
+	case "[H", "[1~":
+		return "home", nil
+	case "[F", "[4~":
+		return "end", nil
+	}
+	return "esc", nil
+}
+
+// handle changes the state after key, returning false to quit.
+func (e *explorer) handle(key string) bool {
+	if e.typing {
+		switch key {
+		case "enter":
+			e.typing = false
+		case "esc", "ctrl-c":
+			e.typing, e.search = false, ""
+		case "backspace":
+			if e.search != "" {
+				e.search = e.search[:len(e.search)-1]
+			}
+		default:
+			if len(key) == 1 && key[0] >= ' ' {
+				e.search += key
+			}
+		}
+		e.update()
+		return true
+	}
+	page := max(e.rows-4, 1)
+	switch key {
+	case "q", "ctrl-c":
+		return false
+	case "up", "k":
+		e.cursor--
+	case "down", "j":
+		e.cursor++
+	case "pgup":
+		e.cursor -= page
+	case "pgdn", " ":
+		e.cursor += page
+	case "home", "g":
+		e.cursor = 0
+	case "end", "G":
+		e.cursor = len(e.list) - 1
+	case "enter", "right", "l":
+		if e.cursor < len(e.list) {
+			if e.focus != "" {
+				e.history = append(e.history, e.focus)
+			}
+			e.focus, e.search = e.list[e.cursor], ""
+			e.update()
+		}
+	case "backspace", "left", "h":
+		if len(e.history) > 0 {
+			e.focus = e.history[len(e.history)-1]
+			e.history = e.history[:len(e.history)-1]
+		} else {
+			e.focus = ""
+		}
+		e.search = ""
+		e.update()
+	case "tab":
+		e.importers = !e.importers
+		e.update()
+	case "a":
+		e.focus, e.history, e.search = "", nil, ""
+		e.update()
+	case "/":
+		e.typing, e.search = true, ""
+		e.update()
+	case "esc":
+		e.search = ""
+		e.update()
+	}
+	e.cursor = max(min(e.cursor, len(e.list)-1), 0)
+	return true
+}
+
+// update lists the packages to show, keeping the cursor in range.
+func (e *explorer) update() {
+	var names []string
+	switch {
+	case e.focus == "":
+		names = e.g.Nodes()
+	case e.importers:
+		names = e.g.Pred(e.focus)
+	default:
+		names = e.g.Succ(e.focus)
+	}
+	e.list = e.list[:0]
+	for _, name := range names {
+		if strings.Contains(name, e.search) {
+			e.list = append(e.list, name)
+		}
+	}
+	e.cursor = max(min(e.cursor, len(e.list)-1), 0)
+}
+
+// draw writes the whole screen.
+func (e *explorer) draw() {
+	var (
+		b      strings.Builder
+		height = e.rows - 3
+	)
+	if e.cursor < e.offset {
+		e.offset = e.cursor
+	} else if e.cursor >= e.offset+height {
+		e.offset = e.cursor - height + 1
+	}
+	b.WriteString("\x1b[H\x1b[2J")
+	title := fmt.Sprintf("all packages (%d)", len(e.list))
+	if e.focus != "" {
+		what := "imports"
+		if e.importers {
+			what = "importers"
+		}
+		title = fmt.Sprintf("%s of %s (%d)", what, e.focus, len(e.list))
+	}
+	if e.search != "" || e.typing {
+		title += fmt.Sprintf(", matching %q", e.search)
+	}
+	b.WriteString(e.p.paint(e.fit(title), bold) + "\r\n")
+	for i := e.offset; i < len(e.list) && i < e.offset+height; i++ {
+		name := e.list[i]
+		marker := "  "
+		if i == e.cursor {
+			marker = "> "
+		}
+		row := e.fit(fmt.Sprintf("%s%-*s  %3d imports  %3d importers", marker, min(e.width(), e.cols-32), name,
+			len(e.g.Succ(name)), len(e.g.Pred(name))))
+		if i == e.cursor {
+			row = e.p.paint(row, reverse)
+		}
+		b.WriteString(row + "\r\n")
+	}
+	b.WriteString(fmt.Sprintf("\x1b[%d;1H", e.rows))
+	help := "enter: open  backspace: back  tab: imports/importers  /: search  a: all  q: quit"
+	if e.typing {
+		help = "/" + e.search + "  (enter: done, esc: cancel)"
+	}
+	b.WriteString(e.p.paint(e.fit(help), dim))
+	fmt.Print(b.String())
+}
+
+// width returns the width of the longest package name listed.
+func (e *explorer) width() int {
+	w := 0
+	for _, name := range e.list {
+		w = max(w, len(name))
+	}
+	return w
+}
+
+// fit cuts s to the width of the terminal.
+func (e *explorer) fit(s string) string {
+	if len(s) > e.cols {
+		return s[:e.cols]
+	}
+	return s
+}
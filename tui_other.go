@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// resizeSignals are the signals sent when the terminal is resized, none
+// here, so the size is only read at start.
+var resizeSignals []os.Signal
@@ -0,0 +1,11 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// resizeSignals are the signals sent when the terminal is resized.
+var resizeSignals = []os.Signal{syscall.SIGWINCH}
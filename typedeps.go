@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+)
+
+func runTypes(fs *flag.FlagSet, args []string) error {
+	source, err := sourceArg(args)
+	if err != nil {
+		return err
+	}
+	if err := typeGraphFormat("types"); err != nil {
+		return err
+	}
+	if flagGoList != "" {
+		return fmt.Errorf("cannot type check go list output")
+	}
+	write, err := graphWriter()
+	if err != nil {
+		return err
+	}
+	cleanup, err := prepare(fs, source)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	g, err := scanner.TypeGraph(context.Background())
+	if err != nil {
+		return err
+	}
+	if !flagSamePackage {
+		g = crossPackage(g)
+	}
+	return writeOutput(func(w io.Writer) error { return write(w, g) })
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/sequix/baobab/scan"
+)
+
+func runUnused(fs *flag.FlagSet, args []string) error {
+	source, err := sourceArg(args)
+	if err != nil {
+		return err
+	}
+	p, err := newPainter()
+	if err != nil {
+		return err
+	}
+	if flagGoList != "" {
+		return fmt.Errorf("cannot type check go list output")
+	}
+	cleanup, err := prepare(fs, source)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	unused, err := scanner.UnusedExports(context.Background())
+	if err != nil {
+		return err
+	}
+	return writeOutput(func(w io.Writer) error { return printUnused(w, p, unused) })
+}
+
+// printUnused writes the exported names no other package uses, under their
+// package, with where they are declared.
+func printUnused(w io.Writer, p painter, unused []scan.UnusedExport) error {
+	pkgs := 0
+	for i, u := range unused {
+		if i == 0 || u.Dir != unused[i-1].Dir {
+			n := 1
+			for n < len(unused)-i && unused[i+n].Dir == u.Dir {
+				n++
+			}
+			fmt.Fprintf(w, "%s: %d unused\n", p.paint(u.Dir, cyan), n)
+			pkgs++
+		}
+		fmt.Fprintf(w, "  %-5s %s %s\n", u.Kind, p.paint(u.Name, red), p.paint(fmt.Sprintf("%s:%d", u.File, u.Line), dim))
+	}
+	_, err := fmt.Fprintf(w, "%d exported names unused by other packages, in %d packages\n", len(unused), pkgs)
+	return err
+}
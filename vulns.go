@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"sort"
+	"strings"
+)
+
+var (
+	flagVulns       string
+	flagGovulncheck bool
+
+	stdinVulns []byte // -vulns read from stdin, for scans after the first
+)
+
+// vulnFinding is a finding of govulncheck -json: the frames of a call path
+// from the vulnerable symbol, first, up to the code scanned, last.
+type vulnFinding struct {
+	OSV   string      `json:"osv"`
+	Trace []vulnFrame `json:"trace"`
+}
+
+type vulnFrame struct {
+	Module   string `json:"module"`
+	Package  string `json:"package"`
+	Function string `json:"function"`
+}
+
+// applyVulns marks the packages and imports of g on the call paths to
+// vulnerable functions govulncheck found, read from -vulns or, with
+// -govulncheck, running it on the root scanned.
+func applyVulns(g *Graph) error {
+	var (
+		data []byte
+		err  error
+	)
+	switch {
+	case flagVulns == "-":
+		if stdinVulns == nil {
+			stdinVulns, err = io.ReadAll(os.Stdin)
+		}
+		data = stdinVulns
+	case flagVulns != "":
+		data, err = os.ReadFile(flagVulns)
+	case flagGovulncheck:
+		data, err = runGovulncheck()
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read govulncheck output: %s", err)
+	}
+	findings, err := parseVulnFindings(data)
+	if err != nil {
+		return err
+	}
+	nodeOf := packageNodes(g)
+	for _, f := range findings {
+		// Only call paths, not the imports and requirements of vulnerable
+		// code govulncheck also reports.
+		if len(f.Trace) == 0 || f.Trace[0].Function == "" {
+			continue
+		}
+		prev := ""
+		for _, frame := range f.Trace {
+			name := nodeOf(frame.Package)
+			if name == "" && frame.Module != "stdlib" {
+				name = nodeOf(frame.Module)
+			}
+			if name == "" {
+				continue
+			}
+			n := g.Node(name)
+			if !slices.Contains(n.Vulns, f.OSV) {
+				n.Vulns = append(n.Vulns, f.OSV)
+				sort.Strings(n.Vulns)
+			}
+			// Frames go from callee to caller.
+			if prev != "" && g.Edge(name, prev) != nil {
+				g.Edge(name, prev).Vulnerable = true
+			}
+			prev = name
+		}
+	}
+	return nil
+}
+
+// runGovulncheck runs govulncheck -json on the packages of the current
+// directory for the build constraints scanned, and returns its output.
+func runGovulncheck() ([]byte, error) {
+	args := []string{"-json"}
+	if flagTags != "" {
+		args = append(args, "-tags", flagTags)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("govulncheck", append(args, "./...")...)
+	cmd.Env = os.Environ()
+	if flagGOOS != "" {
+		cmd.Env = append(cmd.Env, "GOOS="+flagGOOS)
+	}
+	if flagGOARCH != "" {
+		cmd.Env = append(cmd.Env, "GOARCH="+flagGOARCH)
+	}
+	logger.Debug("running govulncheck", "cmd", cmd.Args)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// parseVulnFindings returns the findings of the stream of messages of
+// govulncheck -json in data.
+func parseVulnFindings(data []byte) ([]vulnFinding, error) {
+	var result []vulnFinding
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var msg struct {
+			Finding *vulnFinding `json:"finding"`
+		}
+		if err := dec.Decode(&msg); err == io.EOF {
+			return result, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to decode govulncheck output: %s", err)
+		}
+		if msg.Finding != nil {
+			result = append(result, *msg.Finding)
+		}
+	}
+}
+
+// packageNodes returns the function naming the node of g a package, by
+// import path, belongs to, empty if none: the node of the package itself,
+// or else the external or standard one its import path is below, the
+// longest if several.
+func packageNodes(g *Graph) func(string) string {
+	byPath := map[string]string{}
+	var trees []string
+	for _, name := range g.Nodes() {
+		n := g.Node(name)
+		switch {
+		case n.ImportPath != "":
+			byPath[n.ImportPath] = name
+		case n.External || n.Std:
+			trees = append(trees, name)
+			byPath[nodePath(name)] = name
+		}
+	}
+	sort.Slice(trees, func(i, j int) bool { return len(nodePath(trees[i])) > len(nodePath(trees[j])) })
+	return func(pkg string) string {
+		if name, ok := byPath[pkg]; ok {
+			return name
+		}
+		for _, tree := range trees {
+			if strings.HasPrefix(pkg, nodePath(tree)+"/") {
+				return tree
+			}
+		}
+		return ""
+	}
+}
+
+// dotVulnAttrs returns the DOT attributes marking n if on a call path to a
+// vulnerable function, empty if not.
+func dotVulnAttrs(n *Node) string {
+	if len(n.Vulns) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("color=%q, fontcolor=%q, peripheries=2, tooltip=%q", palette.bad, palette.bad, strings.Join(n.Vulns, " "))
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watch waits for go files to change, updates the graph for the packages
+// they are in and writes the output again. Only the changed packages are
+// scanned again, except with -backend packages or -depth, whose results
+// depend on the whole scan, or after an update failed, when scan starts
+// over.
+func watch(write func(io.Writer) error) error {
+	if flagSkipGen {
+		printGeneratedReport(os.Stderr)
+	}
+	if err := writeOutput(write); err != nil {
+		return err
+	}
+	w, err := newWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.close()
+	var (
+		full  = flagBackend == "packages" || flagDepth > 0
+		dirty = full
+	)
+	for {
+		changed, err := w.next(context.Background())
+		if err != nil {
+			return err
+		}
+		logger.Info("files changed", "dirs", changed)
+		if dirty {
+			err = scanAll()
+		} else {
+			err = scanner.Rescan(context.Background(), changed)
+		}
+		if err != nil {
+			warn("watch", "", "failed to update the graph", err)
+			dirty = true
+			continue
+		}
+		dirty = full
+		if flagSkipGen {
+			printGeneratedReport(os.Stderr)
+		}
+		if err := writeOutput(write); err != nil {
+			return err
+		}
+	}
+}
+
+// watcher follows the file system events in the directories of the modules
+// scanned.
+type watcher struct {
+	fs    *fsnotify.Watcher
+	known map[string]bool // package directories, relative to the root
+}
+
+// newWatcher watches every directory of the modules scanned.
+func newWatcher() (*watcher, error) {
+	fs, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch files: %s", err)
+	}
+	w := &watcher{fs: fs}
+	for _, m := range scanner.Modules() {
+		if err := w.add(filepath.Join(scanner.Root(), m.Dir), nil); err != nil {
+			fs.Close()
+			return nil, err
+		}
+	}
+	if w.known, err = w.packageDirs(); err != nil {
+		fs.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// close stops watching.
+func (w *watcher) close() {
+	w.fs.Close()
+}
+
+// add watches top and the directories below it, but vendor and hidden ones,
+// marking them in touched if not nil.
+func (w *watcher) add(top string, touched map[string]bool) error {
+	return filepath.WalkDir(top, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if path != top && os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != top && (d.Name() == "vendor" || strings.HasPrefix(d.Name(), ".")) {
+			return filepath.SkipDir
+		}
+		if err := w.fs.Add(path); err != nil {
+			return fmt.Errorf("failed to watch %s: %s", path, err)
+		}
+		if touched != nil {
+			touched[w.rel(path)] = true
+		}
+		return nil
+	})
+}
+
+// rel returns path relative to the root, as packages are named.
+func (w *watcher) rel(path string) string {
+	if rel, err := filepath.Rel(scanner.Root(), path); err == nil {
+		return rel
+	}
+	return path
+}
+
+// packageDirs returns the package directories as a set.
+func (w *watcher) packageDirs() (map[string]bool, error) {
+	dirs, err := scanner.PackageDirs()
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		result[dir] = true
+	}
+	return result, nil
+}
+
+// next waits for go files to change and returns the package directories
+// they are in, sorted, once no event came for -interval, so that a checkout
+// or a save of many files is scanned once.
+func (w *watcher) next(ctx context.Context) ([]string, error) {
+	touched := map[string]bool{}
+	var settle <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case ev, ok := <-w.fs.Events:
+			if !ok {
+				return nil, fmt.Errorf("stopped watching files")
+			}
+			if w.touch(ev, touched) {
+				settle = time.After(flagInterval)
+			}
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return nil, fmt.Errorf("stopped watching files")
+			}
+			warn("watch", "", "failed to watch files", err)
+		case <-settle:
+			cur, err := w.packageDirs()
+			if err != nil {
+				return nil, err
+			}
+			var changed []string
+			for dir := range touched {
+				// Packages gone are known but no longer current.
+				if cur[dir] || w.known[dir] {
+					changed = append(changed, dir)
+				}
+			}
+			w.known = cur
+			touched, settle = map[string]bool{}, nil
+			if len(changed) > 0 {
+				sort.Strings(changed)
+				return changed, nil
+			}
+		}
+	}
+}
+
+// touch marks in touched the directories ev may change a package in, and
+// reports whether there was any.
+func (w *watcher) touch(ev fsnotify.Event, touched map[string]bool) bool {
+	if ev.Op == fsnotify.Chmod {
+		return false
+	}
+	if ev.Has(fsnotify.Create) {
+		if fi, err := os.Lstat(ev.Name); err == nil && fi.IsDir() {
+			if err := w.add(ev.Name, touched); err != nil {
+				warn("watch", ev.Name, "failed to watch new directory", err)
+			}
+			return true
+		}
+	}
+	if ev.Has(fsnotify.Remove) || ev.Has(fsnotify.Rename) {
+		// Maybe a directory, the packages in which are gone.
+		gone := w.rel(ev.Name)
+		touched[gone] = true
+		for dir := range w.known {
+			if strings.HasPrefix(dir, gone+string(filepath.Separator)) {
+				touched[dir] = true
+			}
+		}
+	}
+	if !strings.HasSuffix(ev.Name, ".go") {
+		return ev.Has(fsnotify.Remove) || ev.Has(fsnotify.Rename)
+	}
+	touched[w.rel(filepath.Dir(ev.Name))] = true
+	return true
+}